@@ -0,0 +1,176 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package escrow implements an escrow channel app with a third-party arbiter.
+//
+// The buyer escrows an amount for the seller. The escrowed amount is released
+// to the seller or refunded to the buyer if buyer and seller agree. If they
+// disagree, the arbiter decides by signing the decision, which is then
+// embedded into the channel state by the favored party.
+package escrow // import "perun.network/go-perun/apps/escrow"
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+// App is an escrow app.
+type App struct {
+	Addr wallet.Address
+}
+
+var _ channel.StateApp = (*App)(nil)
+
+// Def returns the address of this escrow app.
+func (a *App) Def() wallet.Address {
+	return a.Addr
+}
+
+// DecodeData decodes escrow Data from the reader.
+func (a *App) DecodeData(r io.Reader) (channel.Data, error) {
+	data := new(Data)
+	return data, data.Decode(r)
+}
+
+// ValidInit checks that the initial state contains valid, pending escrow Data
+// and that the buyer can cover the escrowed amount.
+func (a *App) ValidInit(p *channel.Params, s *channel.State) error {
+	data, err := asData(s.Data)
+	if err != nil {
+		return err
+	}
+	numParts := channel.Index(len(p.Parts))
+	switch {
+	case data.Buyer >= numParts || data.Seller >= numParts:
+		return errors.Errorf("participant index out of range [0, %d)", numParts)
+	case data.Buyer == data.Seller:
+		return errors.New("buyer and seller must differ")
+	case int(data.Asset) >= len(s.Assets):
+		return errors.Errorf("asset index %d out of range [0, %d)", data.Asset, len(s.Assets))
+	case data.Amount == nil || data.Amount.Sign() < 0:
+		return errors.New("amount must be non-negative")
+	case s.Balances[data.Asset][data.Buyer].Cmp(data.Amount) < 0:
+		return errors.New("buyer cannot cover escrowed amount")
+	case data.Arbiter == nil:
+		return errors.New("arbiter must be set")
+	case data.IsDecided() || data.ArbiterSig != nil:
+		return errors.New("escrow must be pending initially")
+	}
+	for i, part := range p.Parts {
+		if part.Equals(data.Arbiter) {
+			return errors.Errorf("arbiter must not be a participant, is participant %d", i)
+		}
+	}
+	return nil
+}
+
+// ValidTransition checks that a pending escrow is decided correctly and that a
+// decided escrow does not change anymore.
+//
+// Without an arbiter signature, the escrowed amount can only be released by
+// the buyer and refunded by the seller, i.e., the party that loses the amount
+// must propose the decision, and the other party agrees by signing the state.
+// With a valid arbiter signature on the decision, either party may propose it.
+// If the escrow is released, the amount is transferred from the buyer to the
+// seller. A refund does not change the balances.
+func (a *App) ValidTransition(_ *channel.Params, from, to *channel.State, actor channel.Index) error {
+	fromData, err := asData(from.Data)
+	if err != nil {
+		return err
+	}
+	toData, err := asData(to.Data)
+	if err != nil {
+		return err
+	}
+	if err := assertSameEscrow(fromData, toData); err != nil {
+		return channel.NewStateTransitionError(from.ID, err.Error())
+	}
+	if actor != fromData.Buyer && actor != fromData.Seller {
+		return channel.NewStateTransitionError(from.ID, "actor is neither buyer nor seller")
+	}
+
+	if err := a.validDecision(from, to, fromData, toData, actor); err != nil {
+		return channel.NewStateTransitionError(from.ID, err.Error())
+	}
+	return nil
+}
+
+func (a *App) validDecision(from, to *channel.State, fromData, toData *Data, actor channel.Index) error {
+	if fromData.IsDecided() || !toData.IsDecided() {
+		if toData.Decision != fromData.Decision || !bytes.Equal(toData.ArbiterSig, fromData.ArbiterSig) {
+			return errors.New("decision must not change")
+		}
+		return errors.WithMessage(from.Balances.AssertEqual(to.Balances), "balances changed")
+	}
+
+	if toData.ArbiterSig != nil {
+		ok, err := wallet.VerifySignature(DecisionMsg(from.ID, toData.Decision), toData.ArbiterSig, toData.Arbiter)
+		if err != nil {
+			return errors.WithMessage(err, "verifying arbiter signature")
+		} else if !ok {
+			return errors.New("invalid arbiter signature")
+		}
+	}
+
+	expected := from.Balances.Clone()
+	switch toData.Decision {
+	case Release:
+		if toData.ArbiterSig == nil && actor != fromData.Buyer {
+			return errors.New("only the buyer can release without arbiter")
+		}
+		transfer(expected[fromData.Asset], fromData.Buyer, fromData.Seller, fromData.Amount)
+	case Refund:
+		if toData.ArbiterSig == nil && actor != fromData.Seller {
+			return errors.New("only the seller can refund without arbiter")
+		}
+	default:
+		return errors.Errorf("invalid decision %d", toData.Decision)
+	}
+	return errors.WithMessagef(expected.AssertEqual(to.Balances), "%v allocation", toData.Decision)
+}
+
+// transfer moves amount from bals[from] to bals[to].
+func transfer(bals []channel.Bal, from, to channel.Index, amount *big.Int) {
+	bals[from].Sub(bals[from], amount)
+	bals[to].Add(bals[to], amount)
+}
+
+// assertSameEscrow checks that the immutable escrow parameters are equal.
+func assertSameEscrow(a, b *Data) error {
+	switch {
+	case a.Buyer != b.Buyer || a.Seller != b.Seller:
+		return errors.New("participants changed")
+	case a.Asset != b.Asset:
+		return errors.New("asset changed")
+	case a.Amount.Cmp(b.Amount) != 0:
+		return errors.New("amount changed")
+	case !a.Arbiter.Equals(b.Arbiter):
+		return errors.New("arbiter changed")
+	}
+	return nil
+}
+
+func asData(data channel.Data) (*Data, error) {
+	d, ok := data.(*Data)
+	if !ok {
+		return nil, errors.Errorf("escrow app data must be *escrow.Data, is %T", data)
+	}
+	return d, nil
+}
@@ -0,0 +1,213 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package escrow
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "perun.network/go-perun/backend/sim" // backend init
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+	"perun.network/go-perun/wallet"
+	wallettest "perun.network/go-perun/wallet/test"
+)
+
+const (
+	buyer  = channel.Index(0)
+	seller = channel.Index(1)
+)
+
+func newTestState(rng *rand.Rand, app *App, arbiter wallet.Address) (*channel.Params, *channel.State) {
+	return test.NewRandomParamsAndState(rng,
+		test.WithApp(app),
+		test.WithAppData(NewData(buyer, seller, 0, big.NewInt(30), arbiter)),
+		test.WithNumLocked(0),
+		test.WithBalances([]channel.Bal{big.NewInt(100), big.NewInt(0)}),
+	)
+}
+
+func TestApp_Generic(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	app := &App{wallettest.NewRandomAddress(rng)}
+	arbiter := wallettest.NewRandomAccount(rng)
+
+	test.GenericAppTest(t, rng, &test.AppSetup{
+		App: app,
+		NewState: func(rng *rand.Rand) (*channel.Params, *channel.State) {
+			params, state := newTestState(rng, app, arbiter.Address())
+			state.IsFinal = false
+			return params, state
+		},
+		NewTransition: func(rng *rand.Rand, _ *channel.Params, from *channel.State) (*channel.State, channel.Index) {
+			to := from.Clone()
+			to.Version++
+			data := to.Data.(*Data)
+			actor := channel.Index(rng.Intn(2))
+			if data.IsDecided() || rng.Intn(2) == 0 {
+				return to, actor // no decision
+			}
+
+			data.Decision = Release
+			if rng.Intn(2) == 0 {
+				data.Decision = Refund
+			}
+			if rng.Intn(2) == 0 {
+				sig, err := SignDecision(arbiter, to.ID, data.Decision)
+				require.NoError(t, err)
+				data.ArbiterSig = sig
+			} else if data.Decision == Release {
+				actor = data.Buyer
+			} else {
+				actor = data.Seller
+			}
+			if data.Decision == Release {
+				transfer(to.Balances[data.Asset], data.Buyer, data.Seller, data.Amount)
+			}
+			return to, actor
+		},
+		NewInvalidTransition: func(rng *rand.Rand, _ *channel.Params, from *channel.State) (*channel.State, channel.Index) {
+			to := from.Clone()
+			to.Version++
+			data := to.Data.(*Data)
+			if data.IsDecided() {
+				// Decided escrows must not change.
+				data.Decision = Release + Refund - data.Decision
+				return to, data.Buyer
+			}
+			// Only the buyer can release without the arbiter.
+			data.Decision = Release
+			transfer(to.Balances[data.Asset], data.Buyer, data.Seller, data.Amount)
+			return to, data.Seller
+		},
+		ShallowFields: []string{"Arbiter"},
+	})
+}
+
+func TestApp_ValidInit(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	app := &App{wallettest.NewRandomAddress(rng)}
+	params, state := newTestState(rng, app, wallettest.NewRandomAddress(rng))
+	assert.NoError(t, app.ValidInit(params, state))
+
+	invalid := []struct {
+		desc   string
+		modify func(*Data)
+	}{
+		{"same buyer and seller", func(d *Data) { d.Seller = d.Buyer }},
+		{"seller out of range", func(d *Data) { d.Seller = 2 }},
+		{"asset out of range", func(d *Data) { d.Asset = 1 }},
+		{"negative amount", func(d *Data) { d.Amount.SetInt64(-1) }},
+		{"amount exceeds balance", func(d *Data) { d.Amount.SetInt64(101) }},
+		{"decided", func(d *Data) { d.Decision = Release }},
+		{"arbiter is participant", func(d *Data) { d.Arbiter = params.Parts[seller] }},
+	}
+	for _, tt := range invalid {
+		s := state.Clone()
+		tt.modify(s.Data.(*Data))
+		assert.Error(t, app.ValidInit(params, s), tt.desc)
+	}
+}
+
+func TestApp_ValidTransition(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	app := &App{wallettest.NewRandomAddress(rng)}
+	arbiter := wallettest.NewRandomAccount(rng)
+	params, from := newTestState(rng, app, arbiter.Address())
+
+	decide := func(d Decision, sig wallet.Sig) *channel.State {
+		to := from.Clone()
+		to.Version++
+		data := to.Data.(*Data)
+		data.Decision, data.ArbiterSig = d, sig
+		if d == Release {
+			to.Balances[0][buyer].SetInt64(70)
+			to.Balances[0][seller].SetInt64(30)
+		}
+		return to
+	}
+	sign := func(d Decision) wallet.Sig {
+		sig, err := SignDecision(arbiter, from.ID, d)
+		require.NoError(t, err)
+		return sig
+	}
+	assertTransitionErr := func(err error, msg string) {
+		t.Helper()
+		assert.True(t, channel.IsStateTransitionError(err), msg)
+	}
+
+	t.Run("agreement", func(t *testing.T) {
+		assert.NoError(t, app.ValidTransition(params, from, decide(Release, nil), buyer))
+		assert.NoError(t, app.ValidTransition(params, from, decide(Refund, nil), seller))
+		assertTransitionErr(app.ValidTransition(params, from, decide(Release, nil), seller), "release by seller")
+		assertTransitionErr(app.ValidTransition(params, from, decide(Refund, nil), buyer), "refund by buyer")
+		assertTransitionErr(app.ValidTransition(params, from, decide(Decision(3), nil), buyer), "invalid decision")
+	})
+
+	t.Run("arbiter", func(t *testing.T) {
+		assert.NoError(t, app.ValidTransition(params, from, decide(Release, sign(Release)), seller))
+		assert.NoError(t, app.ValidTransition(params, from, decide(Refund, sign(Refund)), buyer))
+		assertTransitionErr(app.ValidTransition(params, from, decide(Release, sign(Refund)), seller), "wrong decision signed")
+
+		other, err := SignDecision(wallettest.NewRandomAccount(rng), from.ID, Release)
+		require.NoError(t, err)
+		assertTransitionErr(app.ValidTransition(params, from, decide(Release, other), seller), "wrong signer")
+	})
+
+	t.Run("allocation", func(t *testing.T) {
+		to := decide(Release, nil)
+		to.Balances[0][seller].SetInt64(20)
+		assertTransitionErr(app.ValidTransition(params, from, to, buyer), "wrong release allocation")
+
+		to = decide(Refund, nil)
+		to.Balances[0][buyer].SetInt64(70)
+		to.Balances[0][seller].SetInt64(30)
+		assertTransitionErr(app.ValidTransition(params, from, to, seller), "refund changes allocation")
+
+		to = from.Clone()
+		to.Balances[0][buyer].SetInt64(70)
+		to.Balances[0][seller].SetInt64(30)
+		assertTransitionErr(app.ValidTransition(params, from, to, buyer), "pending changes allocation")
+	})
+
+	t.Run("decided", func(t *testing.T) {
+		decided := decide(Release, nil)
+		final := decided.Clone()
+		final.IsFinal = true
+		assert.NoError(t, app.ValidTransition(params, decided, final, seller))
+
+		to := decided.Clone()
+		to.Data.(*Data).Decision = Refund
+		assertTransitionErr(app.ValidTransition(params, decided, to, seller), "decision changed")
+	})
+
+	t.Run("immutable", func(t *testing.T) {
+		for _, modify := range []func(*Data){
+			func(d *Data) { d.Amount.SetInt64(0) },
+			func(d *Data) { d.Arbiter = wallettest.NewRandomAddress(rng) },
+			func(d *Data) { d.Buyer, d.Seller = d.Seller, d.Buyer },
+		} {
+			to := from.Clone()
+			modify(to.Data.(*Data))
+			assertTransitionErr(app.ValidTransition(params, from, to, buyer), "immutable field changed")
+		}
+		assertTransitionErr(app.ValidTransition(params, from, from, 2), "third actor")
+	})
+}
@@ -0,0 +1,164 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package escrow
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	perunio "perun.network/go-perun/pkg/io"
+	"perun.network/go-perun/wallet"
+)
+
+// Decision is the outcome of an escrow.
+type Decision uint8
+
+const (
+	// Pending means that the escrow was not decided yet.
+	Pending Decision = iota
+	// Release means that the escrowed amount is released to the seller.
+	Release
+	// Refund means that the escrowed amount is refunded to the buyer.
+	Refund
+)
+
+// String returns the name of the decision.
+func (d Decision) String() string {
+	switch d {
+	case Pending:
+		return "Pending"
+	case Release:
+		return "Release"
+	case Refund:
+		return "Refund"
+	}
+	return "Invalid"
+}
+
+// Valid returns whether d is one of Pending, Release or Refund.
+func (d Decision) Valid() bool {
+	return d <= Refund
+}
+
+// Data is the app data of an escrow channel.
+//
+// The buyer escrows Amount of asset Asset from their balance. The escrow is
+// decided by setting Decision, either by mutual agreement of buyer and seller
+// or by the signature ArbiterSig of the Arbiter on the decision.
+type Data struct {
+	// Buyer is the index of the buying participant.
+	Buyer channel.Index
+	// Seller is the index of the selling participant.
+	Seller channel.Index
+	// Asset is the index of the escrowed asset.
+	Asset channel.Index
+	// Amount is the escrowed amount.
+	Amount *big.Int
+	// Arbiter is the address of the third party that decides disputes. It is
+	// not a channel participant.
+	Arbiter wallet.Address
+	// Decision is the outcome of the escrow.
+	Decision Decision
+	// ArbiterSig is the arbiter's signature on the decision, see SignDecision.
+	// It is nil if the decision was not made by the arbiter.
+	ArbiterSig wallet.Sig
+}
+
+var _ channel.Data = (*Data)(nil)
+
+// NewData returns new pending escrow data.
+func NewData(buyer, seller, asset channel.Index, amount *big.Int, arbiter wallet.Address) *Data {
+	return &Data{
+		Buyer:   buyer,
+		Seller:  seller,
+		Asset:   asset,
+		Amount:  new(big.Int).Set(amount),
+		Arbiter: arbiter,
+	}
+}
+
+// IsDecided returns whether the escrow was decided.
+func (d *Data) IsDecided() bool {
+	return d.Decision != Pending
+}
+
+// Encode encodes the escrow data into an io.Writer.
+func (d *Data) Encode(w io.Writer) error {
+	hasSig := d.ArbiterSig != nil
+	err := perunio.Encode(w, d.Buyer, d.Seller, d.Asset, d.Amount, d.Arbiter, uint8(d.Decision), hasSig)
+	if err == nil && hasSig {
+		err = perunio.Encode(w, d.ArbiterSig)
+	}
+	return errors.WithMessage(err, "encoding escrow data")
+}
+
+// Decode decodes escrow data from an io.Reader.
+func (d *Data) Decode(r io.Reader) (err error) {
+	var (
+		decision uint8
+		hasSig   bool
+	)
+	if err = perunio.Decode(r, &d.Buyer, &d.Seller, &d.Asset, &d.Amount); err != nil {
+		return errors.WithMessage(err, "decoding escrow data")
+	}
+	if d.Arbiter, err = wallet.DecodeAddress(r); err != nil {
+		return errors.WithMessage(err, "decoding arbiter address")
+	}
+	if err = perunio.Decode(r, &decision, &hasSig); err != nil {
+		return errors.WithMessage(err, "decoding decision")
+	}
+	if d.Decision = Decision(decision); !d.Decision.Valid() {
+		return errors.Errorf("invalid decision %d", decision)
+	}
+	d.ArbiterSig = nil
+	if hasSig {
+		d.ArbiterSig, err = wallet.DecodeSig(r)
+	}
+	return errors.WithMessage(err, "decoding arbiter signature")
+}
+
+// Clone returns a deep copy of the escrow data. The arbiter address is
+// immutable and therefore not copied.
+func (d *Data) Clone() channel.Data {
+	if d == nil {
+		return nil
+	}
+	clone := *d
+	clone.Amount = new(big.Int).Set(d.Amount)
+	if d.ArbiterSig != nil {
+		clone.ArbiterSig = append(wallet.Sig(nil), d.ArbiterSig...)
+	}
+	return &clone
+}
+
+// DecisionMsg returns the message that the arbiter signs to decide the escrow
+// of the given channel.
+func DecisionMsg(id channel.ID, d Decision) []byte {
+	var buf bytes.Buffer
+	// Writing to a bytes.Buffer never fails.
+	_ = perunio.Encode(&buf, "PerunEscrowDecision", id, uint8(d))
+	return buf.Bytes()
+}
+
+// SignDecision signs the decision for the escrow of the given channel with
+// the arbiter's account.
+func SignDecision(arbiter wallet.Account, id channel.ID, d Decision) (wallet.Sig, error) {
+	sig, err := arbiter.SignData(DecisionMsg(id, d))
+	return sig, errors.WithMessage(err, "signing decision")
+}
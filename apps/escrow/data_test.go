@@ -0,0 +1,76 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package escrow
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "perun.network/go-perun/backend/sim" // backend init
+	"perun.network/go-perun/channel/test"
+	iotest "perun.network/go-perun/pkg/io/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+	wallettest "perun.network/go-perun/wallet/test"
+)
+
+func TestData_Serializer(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	arbiter := wallettest.NewRandomAccount(rng)
+
+	data := NewData(0, 1, 2, big.NewInt(rng.Int63()), arbiter.Address())
+	iotest.GenericSerializerTest(t, data)
+
+	sig, err := SignDecision(arbiter, test.NewRandomChannelID(rng), Refund)
+	require.NoError(t, err)
+	data.Decision, data.ArbiterSig = Refund, sig
+	iotest.GenericSerializerTest(t, data)
+
+	t.Run("invalid decision", func(t *testing.T) {
+		data := NewData(0, 1, 2, big.NewInt(10), arbiter.Address())
+		data.Decision = Refund + 1
+		var buf bytes.Buffer
+		require.NoError(t, data.Encode(&buf))
+		err := new(Data).Decode(&buf)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid decision")
+	})
+}
+
+func TestData_Clone(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	data := NewData(0, 1, 0, big.NewInt(10), wallettest.NewRandomAddress(rng))
+	data.ArbiterSig = []byte{1, 2, 3}
+	clone := data.Clone().(*Data)
+	assert.Equal(t, data, clone)
+
+	clone.Amount.SetInt64(20)
+	clone.ArbiterSig[0] = 0
+	assert.Equal(t, int64(10), data.Amount.Int64())
+	assert.Equal(t, byte(1), data.ArbiterSig[0])
+}
+
+func TestDecisionMsg(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	id := test.NewRandomChannelID(rng)
+	assert.NotEqual(t, DecisionMsg(id, Release), DecisionMsg(id, Refund))
+	assert.NotEqual(t, DecisionMsg(id, Release), DecisionMsg(test.NewRandomChannelID(rng), Release))
+	assert.Equal(t, "Release", Release.String())
+	assert.True(t, Refund.Valid())
+	assert.False(t, (Refund + 1).Valid())
+}
@@ -0,0 +1,42 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package escrow
+
+import (
+	"math/big"
+	"math/rand"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/test"
+	wtest "perun.network/go-perun/wallet/test"
+)
+
+// Randomizer implements channel/test.AppRandomizer for the escrow app.
+type Randomizer struct{}
+
+var _ test.AppRandomizer = (*Randomizer)(nil)
+
+// NewRandomApp returns a new escrow app with a random definition.
+func (*Randomizer) NewRandomApp(rng *rand.Rand) channel.App {
+	return &App{wtest.NewRandomAddress(rng)}
+}
+
+// NewRandomData returns new random pending escrow data between participants 0
+// and 1 for asset 0 with a zero amount, so that it is valid for any
+// allocation.
+func (*Randomizer) NewRandomData(rng *rand.Rand) channel.Data {
+	buyer := channel.Index(rng.Intn(2))
+	return NewData(buyer, buyer^1, 0, new(big.Int), wtest.NewRandomAddress(rng))
+}
@@ -0,0 +1,40 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package escrow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "perun.network/go-perun/backend/sim" // backend init
+	"perun.network/go-perun/channel"
+	pkgtest "perun.network/go-perun/pkg/test"
+	"perun.network/go-perun/wallet/test"
+)
+
+func TestResolver(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	assert, require := assert.New(t), require.New(t)
+
+	def := test.NewRandomAddress(rng)
+	channel.RegisterAppResolver(def.Equals, &Resolver{})
+
+	app, err := channel.Resolve(def)
+	require.NoError(err)
+	require.IsType(&App{}, app)
+	assert.True(def.Equals(app.Def()))
+}
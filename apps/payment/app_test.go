@@ -16,6 +16,7 @@ package payment
 
 import (
 	"math/big"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -128,6 +129,36 @@ func TestApp_ValidTransition(t *testing.T) {
 	// to pass valid input.
 }
 
+func TestApp_Generic(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	app := &App{wallettest.NewRandomAddress(rng)}
+
+	test.GenericAppTest(t, rng, &test.AppSetup{
+		App: app,
+		NewState: func(rng *rand.Rand) (*channel.Params, *channel.State) {
+			return test.NewRandomParamsAndState(rng,
+				test.WithApp(app),
+				test.WithAppData(Data()),
+				test.WithNumLocked(0),
+				test.WithIsFinal(false),
+			)
+		},
+		NewTransition: func(rng *rand.Rand, _ *channel.Params, from *channel.State) (*channel.State, channel.Index) {
+			to := from.Clone()
+			to.Version++
+			numParts := len(to.Balances[0])
+			actor := channel.Index(rng.Intn(numParts))
+			for _, bals := range to.Balances {
+				receiver := (int(actor) + 1 + rng.Intn(numParts-1)) % numParts
+				amount := new(big.Int).Rand(rng, new(big.Int).Add(bals[actor], big.NewInt(1)))
+				bals[actor].Sub(bals[actor], amount)
+				bals[receiver].Add(bals[receiver], amount)
+			}
+			return to, actor
+		},
+	})
+}
+
 func asBalances(rawBals ...[]int64) channel.Balances {
 	ret := make(channel.Balances, len(rawBals))
 	for i, rawBal := range rawBals {
@@ -0,0 +1,202 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stream implements a streaming payment channel app.
+//
+// In a stream, funds accrue continuously from the payer to the payee at a fixed
+// rate per second. The payee claims accrued funds with channel updates and the
+// payer can stop the stream at any time.
+package stream // import "perun.network/go-perun/apps/stream"
+
+import (
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+// DefaultMaxSkew is the default tolerance for clock differences between the
+// channel participants.
+const DefaultMaxSkew = 10 * time.Second
+
+// App is a streaming payment app.
+type App struct {
+	Addr wallet.Address
+	// Clock is used to determine the elapsed time of a stream. If nil, the
+	// SystemClock is used.
+	Clock Clock
+	// MaxSkew is the tolerated clock difference between the participants.
+	MaxSkew time.Duration
+}
+
+var _ channel.StateApp = (*App)(nil)
+
+// NewApp returns a new stream app with the given definition, using the
+// SystemClock and DefaultMaxSkew.
+func NewApp(addr wallet.Address) *App {
+	return &App{Addr: addr, Clock: SystemClock{}, MaxSkew: DefaultMaxSkew}
+}
+
+// Def returns the address of this stream app.
+func (a *App) Def() wallet.Address {
+	return a.Addr
+}
+
+// DecodeData decodes stream Data from the reader.
+func (a *App) DecodeData(r io.Reader) (channel.Data, error) {
+	data := new(Data)
+	return data, data.Decode(r)
+}
+
+// Now returns the current time of the app's clock.
+func (a *App) Now() time.Time {
+	if a.Clock == nil {
+		return time.Now()
+	}
+	return a.Clock.Now()
+}
+
+// ValidInit checks that the initial state contains valid stream Data. Nothing
+// must have been claimed and the stream must not be stopped yet.
+func (a *App) ValidInit(p *channel.Params, s *channel.State) error {
+	data, err := asData(s.Data)
+	if err != nil {
+		return err
+	}
+	numParts := channel.Index(len(p.Parts))
+	switch {
+	case data.Payer >= numParts || data.Payee >= numParts:
+		return errors.Errorf("participant index out of range [0, %d)", numParts)
+	case data.Payer == data.Payee:
+		return errors.New("payer and payee must differ")
+	case int(data.Asset) >= len(s.Assets):
+		return errors.Errorf("asset index %d out of range [0, %d)", data.Asset, len(s.Assets))
+	case data.Rate == nil || data.Rate.Sign() < 0:
+		return errors.New("rate must be non-negative")
+	case data.Claimed == nil || data.Claimed.Sign() != 0:
+		return errors.New("initial claimed amount must be zero")
+	case data.IsStopped():
+		return errors.New("stream must not be stopped initially")
+	}
+	return nil
+}
+
+// ValidTransition checks that the transition is either a claim by the payee
+// or a stop by the payer.
+//
+// The payee may claim up to rate × elapsed time in total, transferring the
+// claimed amount of the streamed asset from the payer to themselves. The
+// payer may stop the stream at the current time, but must not change the
+// allocation. Both may sign a final state that does not change the balances.
+func (a *App) ValidTransition(_ *channel.Params, from, to *channel.State, actor channel.Index) error {
+	fromData, err := asData(from.Data)
+	if err != nil {
+		return err
+	}
+	toData, err := asData(to.Data)
+	if err != nil {
+		return err
+	}
+	if err := assertSameStream(fromData, toData); err != nil {
+		return channel.NewStateTransitionError(from.ID, err.Error())
+	}
+	if fromData.IsStopped() && toData.Stop != fromData.Stop {
+		return channel.NewStateTransitionError(from.ID, "stream already stopped")
+	}
+
+	switch actor {
+	case fromData.Payee:
+		err = a.validClaim(from, to, fromData, toData)
+	case fromData.Payer:
+		err = a.validStop(from, to, fromData, toData)
+	default:
+		err = errors.Errorf("actor %d is neither payer nor payee", actor)
+	}
+	if err != nil {
+		return channel.NewStateTransitionError(from.ID, err.Error())
+	}
+	return nil
+}
+
+// validClaim checks that the payee claims at most the accrued amount and that
+// exactly the claimed difference is transferred from the payer to the payee.
+func (a *App) validClaim(from, to *channel.State, fromData, toData *Data) error {
+	if toData.Stop != fromData.Stop {
+		return errors.New("payee must not stop the stream")
+	}
+	claim := new(big.Int).Sub(toData.Claimed, fromData.Claimed)
+	if claim.Sign() < 0 {
+		return errors.New("claimed amount must not decrease")
+	}
+	if accrued := toData.Accrued(a.Now().Add(a.MaxSkew)); toData.Claimed.Cmp(accrued) > 0 {
+		return errors.Errorf("claimed %v exceeds accrued %v", toData.Claimed, accrued)
+	}
+
+	expected := from.Balances.Clone()
+	asset := expected[fromData.Asset]
+	asset[fromData.Payer].Sub(asset[fromData.Payer], claim)
+	asset[fromData.Payee].Add(asset[fromData.Payee], claim)
+	return errors.WithMessage(expected.AssertEqual(to.Balances), "claim transfer")
+}
+
+// validStop checks that the payer does not change the claimed amount or the
+// balances and, if the stream is stopped, that it is stopped now.
+func (a *App) validStop(from, to *channel.State, fromData, toData *Data) error {
+	if toData.Claimed.Cmp(fromData.Claimed) != 0 {
+		return errors.New("payer must not change the claimed amount")
+	}
+	if err := from.Balances.AssertEqual(to.Balances); err != nil {
+		return errors.WithMessage(err, "payer must not change balances")
+	}
+	if toData.Stop == fromData.Stop {
+		return nil
+	}
+
+	now := a.Now()
+	stop := time.Unix(int64(toData.Stop), 0)
+	if stop.Before(now.Add(-a.MaxSkew)) || stop.After(now.Add(a.MaxSkew)) {
+		return errors.Errorf("stop time %v too far from current time %v", stop, now)
+	}
+	if toData.Stop < toData.Start {
+		return errors.New("stop time before start time")
+	}
+	return nil
+}
+
+// assertSameStream checks that the immutable stream parameters are equal.
+func assertSameStream(a, b *Data) error {
+	switch {
+	case a.Payer != b.Payer || a.Payee != b.Payee:
+		return errors.New("participants changed")
+	case a.Asset != b.Asset:
+		return errors.New("asset changed")
+	case a.Rate.Cmp(b.Rate) != 0:
+		return errors.New("rate changed")
+	case a.Start != b.Start:
+		return errors.New("start time changed")
+	}
+	return nil
+}
+
+func asData(data channel.Data) (*Data, error) {
+	d, ok := data.(*Data)
+	if !ok {
+		return nil, errors.Errorf("stream app data must be *stream.Data, is %T", data)
+	}
+	return d, nil
+}
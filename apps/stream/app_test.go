@@ -0,0 +1,217 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "perun.network/go-perun/backend/sim" // backend init
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+	wallettest "perun.network/go-perun/wallet/test"
+)
+
+type mockClock struct{ now time.Time }
+
+func (c *mockClock) Now() time.Time { return c.now }
+
+const (
+	payer = channel.Index(0)
+	payee = channel.Index(1)
+)
+
+func newTestApp(t *testing.T, now int64) (*App, *mockClock) {
+	rng := pkgtest.Prng(t)
+	clock := &mockClock{now: time.Unix(now, 0)}
+	return &App{Addr: wallettest.NewRandomAddress(rng), Clock: clock, MaxSkew: time.Second}, clock
+}
+
+func newTestState(t *testing.T, app *App, data *Data) (*channel.Params, *channel.State) {
+	rng := pkgtest.Prng(t)
+	return test.NewRandomParamsAndState(rng,
+		test.WithApp(app),
+		test.WithAppData(data),
+		test.WithNumLocked(0),
+		test.WithBalances([]channel.Bal{big.NewInt(1000), big.NewInt(0)}),
+	)
+}
+
+func TestApp_Generic(t *testing.T) {
+	app, clock := newTestApp(t, 100)
+
+	test.GenericAppTest(t, pkgtest.Prng(t), &test.AppSetup{
+		App: app,
+		NewState: func(rng *rand.Rand) (*channel.Params, *channel.State) {
+			rate := big.NewInt(rng.Int63n(10) + 1)
+			return test.NewRandomParamsAndState(rng,
+				test.WithApp(app),
+				test.WithAppData(NewData(payer, payee, 0, rate, clock.now)),
+				test.WithNumLocked(0),
+				test.WithBalances([]channel.Bal{big.NewInt(1000), big.NewInt(0)}),
+				test.WithIsFinal(false),
+			)
+		},
+		NewTransition: func(rng *rand.Rand, _ *channel.Params, from *channel.State) (*channel.State, channel.Index) {
+			clock.now = clock.now.Add(time.Duration(rng.Intn(20)) * time.Second)
+			to := from.Clone()
+			to.Version++
+			data := to.Data.(*Data)
+			if !data.IsStopped() && rng.Intn(4) == 0 {
+				data.Stop = uint64(clock.now.Unix())
+				return to, payer
+			}
+
+			bals := to.Balances[data.Asset]
+			claim := data.Claimable(clock.now)
+			if claim.Cmp(bals[payer]) > 0 {
+				claim.Set(bals[payer])
+			}
+			data.Claimed.Add(data.Claimed, claim)
+			bals[payer].Sub(bals[payer], claim)
+			bals[payee].Add(bals[payee], claim)
+			return to, payee
+		},
+	})
+}
+
+func TestApp_Def(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	def := wallettest.NewRandomAddress(rng)
+	app := NewApp(def)
+	assert.True(t, def.Equals(app.Def()))
+	assert.IsType(t, SystemClock{}, app.Clock)
+}
+
+func TestApp_ValidInit(t *testing.T) {
+	app, _ := newTestApp(t, 100)
+	params, state := newTestState(t, app, NewData(payer, payee, 0, big.NewInt(10), time.Unix(100, 0)))
+	assert.NoError(t, app.ValidInit(params, state))
+
+	invalid := []struct {
+		desc   string
+		modify func(*Data)
+	}{
+		{"same payer and payee", func(d *Data) { d.Payee = d.Payer }},
+		{"payee out of range", func(d *Data) { d.Payee = 2 }},
+		{"asset out of range", func(d *Data) { d.Asset = 1 }},
+		{"negative rate", func(d *Data) { d.Rate.SetInt64(-1) }},
+		{"claimed", func(d *Data) { d.Claimed.SetInt64(1) }},
+		{"stopped", func(d *Data) { d.Stop = 100 }},
+	}
+	for _, tt := range invalid {
+		s := state.Clone()
+		tt.modify(s.Data.(*Data))
+		assert.Error(t, app.ValidInit(params, s), tt.desc)
+	}
+
+	s := state.Clone()
+	s.Data = channel.NoData()
+	assert.Error(t, app.ValidInit(params, s), "wrong data type")
+}
+
+func TestApp_ValidTransition(t *testing.T) {
+	app, clock := newTestApp(t, 105)
+	params, from := newTestState(t, app, NewData(payer, payee, 0, big.NewInt(10), time.Unix(100, 0)))
+
+	claim := func(amount int64) *channel.State {
+		to := from.Clone()
+		to.Version++
+		to.Data.(*Data).Claimed.SetInt64(amount)
+		to.Balances[0][payer].SetInt64(1000 - amount)
+		to.Balances[0][payee].SetInt64(amount)
+		return to
+	}
+
+	t.Run("claim", func(t *testing.T) {
+		assert := assert.New(t)
+		assert.NoError(app.ValidTransition(params, from, claim(50), payee))
+		assert.NoError(app.ValidTransition(params, from, claim(60), payee), "within skew")
+		assert.NoError(app.ValidTransition(params, from, from, payee), "empty claim")
+		err := app.ValidTransition(params, from, claim(70), payee)
+		assert.True(channel.IsStateTransitionError(err), "exceeds accrued")
+		assert.Error(app.ValidTransition(params, from, claim(50), payer), "claim by payer")
+
+		to := claim(50)
+		to.Balances[0][payer].SetInt64(1000)
+		assert.Error(app.ValidTransition(params, from, to, payee), "unfunded claim")
+
+		clock.now = time.Unix(200, 0)
+		defer func() { clock.now = time.Unix(105, 0) }()
+		assert.NoError(app.ValidTransition(params, from, claim(1000), payee))
+	})
+
+	t.Run("decreasing claim", func(t *testing.T) {
+		prev := claim(50)
+		to := prev.Clone()
+		to.Data.(*Data).Claimed.SetInt64(40)
+		to.Balances[0][payer].SetInt64(960)
+		to.Balances[0][payee].SetInt64(40)
+		assert.Error(t, app.ValidTransition(params, prev, to, payee))
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		assert := assert.New(t)
+		stop := func(at uint64) *channel.State {
+			to := from.Clone()
+			to.Data.(*Data).Stop = at
+			return to
+		}
+		assert.NoError(app.ValidTransition(params, from, stop(105), payer))
+		assert.NoError(app.ValidTransition(params, from, stop(106), payer), "within skew")
+		assert.Error(app.ValidTransition(params, from, stop(102), payer), "stop in past")
+		assert.Error(app.ValidTransition(params, from, stop(105), payee), "stop by payee")
+
+		stopped := stop(105)
+		assert.Error(app.ValidTransition(params, stopped, stop(0), payer), "restart")
+
+		to := stop(105)
+		to.Balances[0][payer].SetInt64(900)
+		to.Balances[0][payee].SetInt64(100)
+		assert.Error(app.ValidTransition(params, from, to, payer), "payer changes balances")
+
+		clock.now = time.Unix(200, 0)
+		defer func() { clock.now = time.Unix(105, 0) }()
+		s := stopped.Clone()
+		s.Data.(*Data).Claimed.SetInt64(50)
+		s.Balances[0][payer].SetInt64(950)
+		s.Balances[0][payee].SetInt64(50)
+		assert.NoError(app.ValidTransition(params, stopped, s, payee), "claim after stop")
+		s.Data.(*Data).Claimed.SetInt64(60)
+		s.Balances[0][payer].SetInt64(940)
+		s.Balances[0][payee].SetInt64(60)
+		assert.Error(app.ValidTransition(params, stopped, s, payee), "claim beyond stop")
+	})
+
+	t.Run("immutable", func(t *testing.T) {
+		for _, modify := range []func(*Data){
+			func(d *Data) { d.Rate.SetInt64(11) },
+			func(d *Data) { d.Start = 99 },
+			func(d *Data) { d.Payer, d.Payee = d.Payee, d.Payer },
+		} {
+			to := from.Clone()
+			modify(to.Data.(*Data))
+			err := app.ValidTransition(params, from, to, payee)
+			require.Error(t, err)
+			assert.True(t, channel.IsStateTransitionError(err))
+		}
+	})
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/client"
+)
+
+// Claim claims all funds that accrued in the stream of the given channel
+// until now. It must be called by the payee. If nothing can be claimed, no
+// update is sent.
+func Claim(ctx context.Context, ch *client.Channel) error {
+	app, data, err := streamOf(ch.State())
+	if err != nil {
+		return err
+	}
+	if ch.Idx() != data.Payee {
+		return errors.New("only the payee can claim")
+	}
+	if data.Claimable(app.Now()).Sign() == 0 {
+		return nil
+	}
+
+	return ch.UpdateBy(ctx, func(s *channel.State) error {
+		data := s.Data.(*Data) // safe, checked above and immutable
+		claim := data.Claimable(app.Now())
+		bals := s.Balances[data.Asset]
+		bals[data.Payer].Sub(bals[data.Payer], claim)
+		bals[data.Payee].Add(bals[data.Payee], claim)
+		data.Claimed.Add(data.Claimed, claim)
+		return nil
+	})
+}
+
+// Stop stops the stream of the given channel at the current time. It must be
+// called by the payer. The payee can still claim the funds that accrued until
+// the stream was stopped.
+func Stop(ctx context.Context, ch *client.Channel) error {
+	app, data, err := streamOf(ch.State())
+	if err != nil {
+		return err
+	}
+	if ch.Idx() != data.Payer {
+		return errors.New("only the payer can stop the stream")
+	}
+	if data.IsStopped() {
+		return errors.New("stream already stopped")
+	}
+
+	return ch.UpdateBy(ctx, func(s *channel.State) error {
+		s.Data.(*Data).Stop = uint64(app.Now().Unix())
+		return nil
+	})
+}
+
+// ClaimPeriodically claims the accrued funds of the stream in the given
+// channel every interval. It returns nil after the stream was stopped and all
+// accrued funds were claimed, or the context's error if it is done first.
+func ClaimPeriodically(ctx context.Context, ch *client.Channel, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if err := Claim(ctx, ch); err != nil {
+			return errors.WithMessage(err, "claiming")
+		}
+		app, data, err := streamOf(ch.State())
+		if err != nil {
+			return err
+		}
+		if data.IsStopped() && data.Claimable(app.Now()).Sign() == 0 {
+			return nil
+		}
+	}
+}
+
+func streamOf(s *channel.State) (*App, *Data, error) {
+	app, ok := s.App.(*App)
+	if !ok {
+		return nil, nil, errors.Errorf("channel app must be *stream.App, is %T", s.App)
+	}
+	data, err := asData(s.Data)
+	return app, data, err
+}
@@ -0,0 +1,221 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"context"
+	"math/big"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/test"
+	"perun.network/go-perun/client"
+	pkgtest "perun.network/go-perun/pkg/test"
+	wallettest "perun.network/go-perun/wallet/test"
+	"perun.network/go-perun/wire"
+)
+
+const clientTestTimeout = 10 * time.Second
+
+type (
+	// syncClock is a Clock that can be advanced concurrently to its use.
+	syncClock struct {
+		mu  sync.Mutex
+		now time.Time
+	}
+
+	// nopFunder and nopAdjudicator are a Funder and an Adjudicator that do
+	// nothing.
+	nopFunder      struct{}
+	nopAdjudicator struct{}
+
+	// acceptingHandler accepts all channel proposals and updates.
+	acceptingHandler struct {
+		ctx    context.Context
+		rng    *rand.Rand
+		wallet wallettest.Wallet
+		chs    chan *client.Channel
+	}
+)
+
+func (c *syncClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *syncClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (nopFunder) Fund(context.Context, channel.FundingReq) error { return nil }
+
+func (nopAdjudicator) Register(context.Context, channel.AdjudicatorReq) error { return nil }
+
+func (nopAdjudicator) Progress(context.Context, channel.ProgressReq) error { return nil }
+
+func (nopAdjudicator) Withdraw(context.Context, channel.AdjudicatorReq, channel.StateMap) error {
+	return nil
+}
+
+func (nopAdjudicator) Subscribe(context.Context, *channel.Params) (channel.AdjudicatorSubscription, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (h *acceptingHandler) HandleProposal(prop client.ChannelProposal, res *client.ProposalResponder) {
+	part := h.wallet.NewRandomAccount(h.rng).Address()
+	ch, err := res.Accept(h.ctx, prop.(*client.LedgerChannelProposal).Accept(part, client.WithNonceFrom(h.rng)))
+	if err != nil {
+		close(h.chs)
+		return
+	}
+	h.chs <- ch
+}
+
+func (h *acceptingHandler) HandleUpdate(_ client.ChannelUpdate, res *client.UpdateResponder) {
+	res.Accept(h.ctx) // nolint:errcheck,gosec
+}
+
+// newStreamChannels opens a stream channel with a rate of 10 per second
+// between a payer and a payee client. The payer deposits 1000.
+func newStreamChannels(ctx context.Context, t *testing.T, clock Clock) (payerCh, payeeCh *client.Channel) {
+	rng := pkgtest.Prng(t)
+	app := &App{Addr: wallettest.NewRandomAddress(rng), Clock: clock, MaxSkew: time.Second}
+	reg := channel.NewAppRegistry("stream")
+	reg.RegisterApp(app)
+
+	bus := wire.NewLocalBus()
+	wallets := [2]wallettest.Wallet{wallettest.NewWallet(), wallettest.NewWallet()}
+	addrs := [2]wire.Address{wallettest.NewRandomAddress(rng), wallettest.NewRandomAddress(rng)}
+	var clients [2]*client.Client
+	for i := range clients {
+		var err error
+		clients[i], err = client.New(addrs[i], bus, nopFunder{}, nopAdjudicator{}, wallets[i], client.WithAppRegistry(reg))
+		require.NoError(t, err)
+		t.Cleanup(func(c *client.Client) func() {
+			return func() { c.Close() } // nolint:errcheck,gosec
+		}(clients[i]))
+	}
+	h := &acceptingHandler{
+		ctx:    ctx,
+		rng:    rand.New(rand.NewSource(rng.Int63())),
+		wallet: wallets[payee],
+		chs:    make(chan *client.Channel, 1),
+	}
+	for _, c := range clients {
+		go c.Handle(h, h)
+	}
+
+	prop, err := client.NewLedgerChannelProposal(
+		60,
+		wallets[payer].NewRandomAccount(rng).Address(),
+		&channel.Allocation{
+			Assets:   []channel.Asset{test.NewRandomAsset(rng)},
+			Balances: channel.Balances{{big.NewInt(1000), big.NewInt(0)}},
+		},
+		addrs[:],
+		client.WithNonceFrom(rng),
+		client.WithApp(app, NewData(payer, payee, 0, big.NewInt(10), clock.Now())),
+	)
+	require.NoError(t, err)
+	payerCh, err = clients[payer].ProposeChannel(ctx, prop)
+	require.NoError(t, err)
+	payeeCh, ok := <-h.chs
+	require.True(t, ok, "payee should accept the channel")
+	return payerCh, payeeCh
+}
+
+func requireBals(t *testing.T, ch *client.Channel, payerBal, payeeBal int64) {
+	t.Helper()
+	bals := ch.State().Balances[0]
+	require.Zerof(t, bals[payer].Cmp(big.NewInt(payerBal)), "payer balance: %v", bals[payer])
+	require.Zerof(t, bals[payee].Cmp(big.NewInt(payeeBal)), "payee balance: %v", bals[payee])
+}
+
+func TestClaimAndStop(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), clientTestTimeout)
+	defer cancel()
+	clock := &syncClock{now: time.Unix(1600000000, 0)}
+	payerCh, payeeCh := newStreamChannels(ctx, t, clock)
+
+	// Nothing accrued yet, so no update is sent.
+	require.NoError(t, Claim(ctx, payeeCh))
+	assert.Zero(t, payeeCh.State().Version)
+
+	clock.Advance(10 * time.Second)
+	assert.Error(t, Claim(ctx, payerCh), "payer must not claim")
+	require.NoError(t, Claim(ctx, payeeCh))
+	requireBals(t, payeeCh, 900, 100)
+	requireBals(t, payerCh, 900, 100)
+
+	clock.Advance(5 * time.Second)
+	assert.Error(t, Stop(ctx, payeeCh), "payee must not stop")
+	require.NoError(t, Stop(ctx, payerCh))
+	assert.Error(t, Stop(ctx, payerCh), "stream already stopped")
+
+	// Funds accrue only until the stream was stopped.
+	clock.Advance(time.Minute)
+	require.NoError(t, Claim(ctx, payeeCh))
+	requireBals(t, payerCh, 850, 150)
+	version := payeeCh.State().Version
+	require.NoError(t, Claim(ctx, payeeCh))
+	assert.Equal(t, version, payeeCh.State().Version, "nothing left to claim")
+}
+
+func TestClaimPeriodically(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), clientTestTimeout)
+	defer cancel()
+	clock := &syncClock{now: time.Unix(1600000000, 0)}
+	payerCh, payeeCh := newStreamChannels(ctx, t, clock)
+
+	t.Run("context done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		assert.True(t, errors.Is(ClaimPeriodically(ctx, payeeCh, time.Millisecond), context.Canceled))
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- ClaimPeriodically(ctx, payeeCh, 10*time.Millisecond) }()
+
+	clock.Advance(10 * time.Second)
+	require.Eventually(t, func() bool {
+		return payerCh.State().Balances[0][payee].Cmp(big.NewInt(100)) == 0
+	}, clientTestTimeout, 10*time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("ClaimPeriodically returned while the stream runs: %v", err)
+	default:
+	}
+
+	// The clock stands still, so the payee sends no claim while the payer
+	// stops the stream.
+	require.NoError(t, Stop(ctx, payerCh))
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-ctx.Done():
+		t.Fatal("ClaimPeriodically should return after the stopped stream was claimed")
+	}
+	requireBals(t, payeeCh, 900, 100)
+}
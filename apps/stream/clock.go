@@ -0,0 +1,33 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import "time"
+
+type (
+	// A Clock tells the current time. It is used by the stream app to determine
+	// the elapsed time of a stream.
+	Clock interface {
+		Now() time.Time
+	}
+
+	// SystemClock is a Clock that returns the local system time.
+	SystemClock struct{}
+)
+
+// Now returns the current local time.
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	perunio "perun.network/go-perun/pkg/io"
+)
+
+// Data is the app data of a streaming payment channel.
+//
+// The payee may claim Rate units of asset Asset per elapsed second since Start
+// from the payer. Claimed records the total amount that was claimed so far.
+// The payer can stop the stream by setting Stop, after which no more funds
+// accrue.
+type Data struct {
+	// Payer is the index of the paying participant.
+	Payer channel.Index
+	// Payee is the index of the receiving participant.
+	Payee channel.Index
+	// Asset is the index of the streamed asset.
+	Asset channel.Index
+	// Rate is the amount that accrues per second.
+	Rate *big.Int
+	// Start is the unix time in seconds at which the stream started.
+	Start uint64
+	// Stop is the unix time in seconds at which the stream was stopped, or 0
+	// if it is still running.
+	Stop uint64
+	// Claimed is the total amount that was claimed by the payee so far.
+	Claimed *big.Int
+}
+
+var _ channel.Data = (*Data)(nil)
+
+// NewData returns new stream data for a stream starting at start.
+func NewData(payer, payee, asset channel.Index, rate *big.Int, start time.Time) *Data {
+	return &Data{
+		Payer:   payer,
+		Payee:   payee,
+		Asset:   asset,
+		Rate:    new(big.Int).Set(rate),
+		Start:   uint64(start.Unix()),
+		Claimed: new(big.Int),
+	}
+}
+
+// IsStopped returns whether the payer stopped the stream.
+func (d *Data) IsStopped() bool {
+	return d.Stop != 0
+}
+
+// Accrued returns the total amount that accrued until the given time. If the
+// stream was stopped before t, the amount that accrued until the stop is
+// returned.
+func (d *Data) Accrued(t time.Time) *big.Int {
+	var end uint64
+	if unix := t.Unix(); unix > 0 {
+		end = uint64(unix)
+	}
+	if d.IsStopped() && d.Stop < end {
+		end = d.Stop
+	}
+	if end <= d.Start {
+		return new(big.Int)
+	}
+	elapsed := new(big.Int).SetUint64(end - d.Start)
+	return elapsed.Mul(elapsed, d.Rate)
+}
+
+// Claimable returns the amount that the payee can claim at the given time.
+func (d *Data) Claimable(t time.Time) *big.Int {
+	claimable := d.Accrued(t)
+	claimable.Sub(claimable, d.Claimed)
+	if claimable.Sign() < 0 {
+		return claimable.SetInt64(0)
+	}
+	return claimable
+}
+
+// Encode encodes the stream data into an io.Writer.
+func (d *Data) Encode(w io.Writer) error {
+	return errors.WithMessage(
+		perunio.Encode(w, d.Payer, d.Payee, d.Asset, d.Rate, d.Start, d.Stop, d.Claimed),
+		"encoding stream data")
+}
+
+// Decode decodes stream data from an io.Reader.
+func (d *Data) Decode(r io.Reader) error {
+	return errors.WithMessage(
+		perunio.Decode(r, &d.Payer, &d.Payee, &d.Asset, &d.Rate, &d.Start, &d.Stop, &d.Claimed),
+		"decoding stream data")
+}
+
+// Clone returns a deep copy of the stream data.
+func (d *Data) Clone() channel.Data {
+	if d == nil {
+		return nil
+	}
+	clone := *d
+	clone.Rate = new(big.Int).Set(d.Rate)
+	clone.Claimed = new(big.Int).Set(d.Claimed)
+	return &clone
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	iotest "perun.network/go-perun/pkg/io/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+func TestData_Serializer(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	r := new(Randomizer)
+	for i := 0; i < 10; i++ {
+		data := r.NewRandomData(rng).(*Data)
+		data.Claimed.SetInt64(rng.Int63())
+		data.Stop = data.Start + uint64(rng.Int31())
+		iotest.GenericSerializerTest(t, data)
+	}
+}
+
+func TestData_Clone(t *testing.T) {
+	data := NewData(0, 1, 0, big.NewInt(10), time.Unix(100, 0))
+	clone := data.Clone().(*Data)
+	assert.Equal(t, data, clone)
+
+	clone.Rate.SetInt64(20)
+	clone.Claimed.SetInt64(5)
+	assert.Equal(t, int64(10), data.Rate.Int64())
+	assert.Zero(t, data.Claimed.Sign())
+}
+
+func TestData_Accrued(t *testing.T) {
+	assert := assert.New(t)
+	data := NewData(0, 1, 0, big.NewInt(10), time.Unix(100, 0))
+
+	assert.Zero(data.Accrued(time.Unix(50, 0)).Sign(), "before start")
+	assert.Zero(data.Accrued(time.Unix(100, 0)).Sign(), "at start")
+	assert.Equal(int64(50), data.Accrued(time.Unix(105, 0)).Int64())
+
+	data.Claimed.SetInt64(30)
+	assert.Equal(int64(20), data.Claimable(time.Unix(105, 0)).Int64())
+	assert.Zero(data.Claimable(time.Unix(101, 0)).Sign(), "claimed more than accrued")
+
+	data.Stop = 110
+	assert.True(data.IsStopped())
+	assert.Equal(int64(100), data.Accrued(time.Unix(200, 0)).Int64(), "after stop")
+	assert.Equal(int64(70), data.Claimable(time.Unix(200, 0)).Int64())
+}
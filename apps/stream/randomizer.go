@@ -0,0 +1,44 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"math/big"
+	"math/rand"
+	"time"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/test"
+	wtest "perun.network/go-perun/wallet/test"
+)
+
+// Randomizer implements channel/test.AppRandomizer for the stream app.
+type Randomizer struct{}
+
+var _ test.AppRandomizer = (*Randomizer)(nil)
+
+// NewRandomApp returns a new stream app with a random definition.
+func (*Randomizer) NewRandomApp(rng *rand.Rand) channel.App {
+	return NewApp(wtest.NewRandomAddress(rng))
+}
+
+// NewRandomData returns new random stream data, streaming asset 0 between
+// participants 0 and 1 in a random direction.
+func (*Randomizer) NewRandomData(rng *rand.Rand) channel.Data {
+	payer := channel.Index(rng.Intn(2))
+	rate := big.NewInt(rng.Int63n(1000) + 1)
+	start := time.Unix(rng.Int63n(1<<32), 0)
+	return NewData(payer, payer^1, 0, rate, start)
+}
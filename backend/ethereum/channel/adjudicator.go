@@ -38,7 +38,8 @@ var _ channel.Adjudicator = (*Adjudicator)(nil)
 type Adjudicator struct {
 	ContractBackend
 	contract *adjudicator.Adjudicator
-	// The address to which we send all funds.
+	// The address to which we send all funds of requests without a
+	// Receiver.
 	Receiver common.Address
 	// Structured logger
 	log log.Logger
@@ -49,7 +50,8 @@ type Adjudicator struct {
 }
 
 // NewAdjudicator creates a new ethereum adjudicator. The receiver is the
-// on-chain address that receives withdrawals.
+// on-chain address that receives withdrawals if the AdjudicatorReq does not
+// specify a Receiver.
 func NewAdjudicator(backend ContractBackend, contract common.Address, receiver common.Address, txSender accounts.Account) *Adjudicator {
 	contr, err := adjudicator.NewAdjudicator(contract, backend)
 	if err != nil {
@@ -31,9 +31,13 @@ const secondaryWaitBlocks = 2
 // final states, resp.) is called on the adjudicator.
 // - a subscription on Concluded events is established
 // - it searches for a past concluded event
-//   - if found, channel is already concluded and success is returned
+//   - if found, channel is already concluded and success is returned once the
+//     event is confirmed
 //   - if none found, conclude/concludeFinal is called on the adjudicator
 // - it waits for a Concluded event from the blockchain.
+//
+// Concluded events are only accepted once they are confirmed, see
+// ContractBackend.ConfirmationDepth.
 func (a *Adjudicator) ensureConcluded(ctx context.Context, req channel.AdjudicatorReq, subStates channel.StateMap) error {
 	// Listen for Concluded event.
 	watchOpts, err := a.NewWatchOpts(ctx)
@@ -47,21 +51,27 @@ func (a *Adjudicator) ensureConcluded(ctx context.Context, req channel.Adjudicat
 	}
 	defer sub.Unsubscribe()
 
-	if found, err := a.filterConcluded(ctx, req.Params.ID()); err != nil {
+	conf, err := newEventConfirmer(ctx, &a.ContractBackend)
+	if err != nil {
+		return errors.WithMessage(err, "creating event confirmer")
+	}
+	defer conf.Close()
+
+	if past, err := a.filterConcluded(ctx, req.Params.ID()); err != nil {
 		return errors.WithMessage(err, "filtering old Concluded events")
-	} else if found {
-		return nil
+	} else if past != nil {
+		return waitConcluded(ctx, conf, sub, events, past)
 	}
 
 	// In final Register calls, as the non-initiator, we optimistically wait for
 	// the other party to send the transaction first for secondaryWaitBlocks many
 	// blocks.
 	if req.Tx.IsFinal && req.Secondary {
-		isConcluded, err := waitConcludedForNBlocks(ctx, a, sub, events, secondaryWaitBlocks)
+		concluded, err := waitConcludedForNBlocks(ctx, a.headReader(), sub, events, secondaryWaitBlocks)
 		if err != nil {
 			return err
-		} else if isConcluded {
-			return nil
+		} else if concluded != nil {
+			return waitConcluded(ctx, conf, sub, events, concluded)
 		}
 	}
 
@@ -77,70 +87,102 @@ func (a *Adjudicator) ensureConcluded(ctx context.Context, req channel.Adjudicat
 		return err
 	}
 
-	select {
-	case <-events:
-		return nil
-	case <-ctx.Done():
-		return errors.Wrap(ctx.Err(), "context cancelled")
-	case err = <-sub.Err():
-		return errors.Wrap(err, "subscription error")
+	return waitConcluded(ctx, conf, sub, events, nil)
+}
+
+// waitConcluded waits until a Concluded event is confirmed by conf. If seen is
+// not nil, it is a Concluded event that was already received and is passed to
+// conf first.
+func waitConcluded(ctx context.Context,
+	conf *eventConfirmer,
+	sub ethereum.Subscription,
+	events chan *adjudicator.AdjudicatorChannelUpdate,
+	seen *adjudicator.AdjudicatorChannelUpdate,
+) (err error) {
+	var confirmed []confirmedEvent
+	if seen != nil {
+		confirmed, err = conf.Add(ctx, seen, seen.Raw)
+	}
+	for {
+		if err != nil {
+			return errors.WithMessage(err, "confirming Concluded event")
+		}
+		for _, e := range confirmed {
+			if !e.Removed && e.Event.(*adjudicator.AdjudicatorChannelUpdate).Phase == phaseConcluded {
+				return nil
+			}
+		}
+
+		select {
+		case e := <-events:
+			confirmed, err = conf.Add(ctx, e, e.Raw)
+		case head := <-conf.Heads():
+			confirmed, err = conf.NewHead(ctx, head)
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "context cancelled")
+		case err = <-sub.Err():
+			return errors.Wrap(err, "subscription error")
+		case err = <-conf.Err():
+			return errors.Wrap(err, "header subscription error")
+		}
 	}
 }
 
 // waitConcludedForNBlocks waits for up to numBlocks blocks for a Concluded
-// event on the concluded channel. If an event is emitted, true is returned.
-// Otherwise, if numBlocks blocks have passed, false is returned.
+// event on the concluded channel. If an event is emitted, it is returned.
+// Otherwise, if numBlocks blocks have passed, nil is returned. The returned
+// event might not be confirmed yet.
 //
-// cr is the ChainReader used for setting up a block header subscription. sub is
-// the Concluded event subscription instance.
+// cr is the ChainReader used for setting up a block header subscription, see
+// ContractBackend.HeadTracker. sub is the Concluded event subscription
+// instance.
 func waitConcludedForNBlocks(ctx context.Context,
 	cr ethereum.ChainReader,
 	sub ethereum.Subscription,
 	concluded chan *adjudicator.AdjudicatorChannelUpdate,
 	numBlocks int,
-) (bool, error) {
+) (*adjudicator.AdjudicatorChannelUpdate, error) {
 	h := make(chan *types.Header)
 	hsub, err := cr.SubscribeNewHead(ctx, h)
 	if err != nil {
-		return false, errors.Wrap(err, "subscribing to new blocks")
+		return nil, errors.Wrap(err, "subscribing to new blocks")
 	}
 	defer hsub.Unsubscribe()
-	for i := 0; i < numBlocks; i++ {
+	start, err := cr.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving latest block")
+	}
+	end := start.Number.Uint64() + uint64(numBlocks)
+	for {
 		select {
-		case <-h: // do nothing, wait another block
+		case head := <-h:
+			if head.Number.Uint64() >= end {
+				return nil, nil
+			}
 		case e := <-concluded: // other participant performed transaction
 			if e.Phase == phaseConcluded {
-				return true, nil
+				return e, nil
 			}
 		case <-ctx.Done():
-			return false, errors.Wrap(ctx.Err(), "context cancelled")
+			return nil, errors.Wrap(ctx.Err(), "context cancelled")
 		case err = <-hsub.Err():
-			return false, errors.Wrap(err, "header subscription error")
+			return nil, errors.Wrap(err, "header subscription error")
 		case err = <-sub.Err():
-			return false, errors.Wrap(err, "concluded subscription error")
+			return nil, errors.Wrap(err, "concluded subscription error")
 		}
 	}
-	return false, nil
 }
 
-// filterConcluded returns whether there has been a Concluded event in the past.
-func (a *Adjudicator) filterConcluded(ctx context.Context, channelID channel.ID) (bool, error) {
-	filterOpts, err := a.NewFilterOpts(ctx)
-	if err != nil {
-		return false, err
-	}
-	iter, err := a.contract.FilterChannelUpdate(filterOpts, [][32]byte{channelID})
+// filterConcluded returns the Concluded event in the past, if there is one.
+func (a *Adjudicator) filterConcluded(ctx context.Context, channelID channel.ID) (*adjudicator.AdjudicatorChannelUpdate, error) {
+	events, err := a.filterChannelUpdates(ctx, channelID)
 	if err != nil {
-		return false, errors.Wrap(err, "creating iterator")
+		return nil, err
 	}
-
-	found := false
-	for iter.Next() {
-		if iter.Event.Phase == phaseConcluded {
-			found = true
-			break
+	for _, e := range events {
+		if e.Phase == phaseConcluded {
+			return e, nil
 		}
 	}
-
-	return found, nil
+	return nil, nil
 }
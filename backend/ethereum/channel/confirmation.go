@@ -0,0 +1,214 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+// reorgWindow is the number of blocks after which confirmed events are assumed
+// to be final. Confirmed events that are buried deeper are forgotten, so
+// their removal by an even deeper reorg is not detected anymore.
+const reorgWindow = 128
+
+type (
+	// eventConfirmer holds back chain events until they are buried deep enough
+	// in the canonical chain, see ContractBackend.ConfirmationDepth.
+	//
+	// Events are added with Add and new chain heads are passed to NewHead, both
+	// of which return the events that became confirmed. If a confirmed event is
+	// removed from the chain by a reorg deeper than the confirmation depth, it
+	// is returned again as a retraction, unless it is older than reorgWindow.
+	eventConfirmer struct {
+		cr    ethereum.ChainReader
+		depth uint64
+		head  uint64
+
+		heads chan *types.Header
+		sub   ethereum.Subscription // nil if every mined event is confirmed
+
+		pending   map[logID]pendingEvent
+		confirmed map[logID]pendingEvent
+	}
+
+	// logID identifies a log in a specific block.
+	logID struct {
+		block common.Hash
+		index uint
+	}
+
+	// pendingEvent is an event with its raw log.
+	pendingEvent struct {
+		event interface{}
+		log   types.Log
+	}
+
+	// confirmedEvent is an event that was confirmed or, if Removed is set, a
+	// previously confirmed event that was removed from the chain.
+	confirmedEvent struct {
+		Event   interface{}
+		Removed bool
+	}
+)
+
+// newEventConfirmer creates a new eventConfirmer using the confirmation depth
+// of the given ContractBackend. If the depth is larger than one, it subscribes
+// to new chain heads, which have to be read from Heads and passed to NewHead.
+// The eventConfirmer must be closed after use.
+func newEventConfirmer(ctx context.Context, cb *ContractBackend) (*eventConfirmer, error) {
+	c := &eventConfirmer{
+		cr:        cb,
+		depth:     cb.ConfirmationDepth(),
+		pending:   make(map[logID]pendingEvent),
+		confirmed: make(map[logID]pendingEvent),
+	}
+	if c.depth <= 1 {
+		return c, nil
+	}
+
+	c.heads = make(chan *types.Header)
+	sub, err := cb.SubscribeNewHead(ctx, c.heads)
+	if err != nil {
+		return nil, errors.Wrap(err, "subscribing to new blocks")
+	}
+	head, err := cb.HeaderByNumber(ctx, nil)
+	if err != nil {
+		sub.Unsubscribe()
+		return nil, errors.Wrap(err, "retrieving latest block")
+	}
+	c.sub = sub
+	c.head = head.Number.Uint64()
+	return c, nil
+}
+
+// Heads returns the channel of new chain heads. It is nil if the
+// eventConfirmer does not need to track the chain head.
+func (c *eventConfirmer) Heads() <-chan *types.Header {
+	return c.heads
+}
+
+// Err returns the error channel of the head subscription. It is nil if the
+// eventConfirmer does not need to track the chain head.
+func (c *eventConfirmer) Err() <-chan error {
+	if c.sub == nil {
+		return nil
+	}
+	return c.sub.Err()
+}
+
+// Close unsubscribes from new chain heads.
+func (c *eventConfirmer) Close() {
+	if c.sub != nil {
+		c.sub.Unsubscribe()
+	}
+}
+
+// Add adds an event with its raw log and returns all events that are
+// confirmed or retracted now. Duplicate events are ignored.
+func (c *eventConfirmer) Add(ctx context.Context, event interface{}, l types.Log) ([]confirmedEvent, error) {
+	id := logID{block: l.BlockHash, index: l.Index}
+	if l.Removed {
+		if _, ok := c.pending[id]; ok {
+			delete(c.pending, id)
+		} else if p, ok := c.confirmed[id]; ok {
+			delete(c.confirmed, id)
+			return []confirmedEvent{{Event: p.event, Removed: true}}, nil
+		}
+		return nil, nil
+	}
+	if l.BlockNumber > c.head {
+		// The log's block is mined, so the head is at least that high.
+		c.head = l.BlockNumber
+	}
+
+	_, isPending := c.pending[id]
+	_, isConfirmed := c.confirmed[id]
+	if isPending || isConfirmed {
+		return nil, nil
+	}
+	c.pending[id] = pendingEvent{event: event, log: l}
+	return c.release(ctx)
+}
+
+// NewHead sets the current chain head and returns all events that are
+// confirmed now.
+func (c *eventConfirmer) NewHead(ctx context.Context, head *types.Header) ([]confirmedEvent, error) {
+	c.head = head.Number.Uint64()
+	return c.release(ctx)
+}
+
+// release returns and marks all pending events as confirmed that are buried
+// deep enough in the chain. Pending events whose block is not part of the
+// canonical chain anymore are dropped, as are confirmed events that are older
+// than reorgWindow.
+func (c *eventConfirmer) release(ctx context.Context) ([]confirmedEvent, error) {
+	c.prune()
+
+	var ready []pendingEvent
+	for _, p := range c.pending {
+		if c.depth <= 1 || p.log.BlockNumber+c.depth-1 <= c.head {
+			ready = append(ready, p)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool {
+		a, b := ready[i].log, ready[j].log
+		return a.BlockNumber < b.BlockNumber || a.BlockNumber == b.BlockNumber && a.Index < b.Index
+	})
+
+	var events []confirmedEvent
+	for _, p := range ready {
+		id := logID{block: p.log.BlockHash, index: p.log.Index}
+		if c.depth > 1 {
+			canonical, err := c.isCanonical(ctx, p.log)
+			if err != nil {
+				return events, err
+			}
+			if !canonical {
+				delete(c.pending, id)
+				continue
+			}
+		}
+		delete(c.pending, id)
+		c.confirmed[id] = p
+		events = append(events, confirmedEvent{Event: p.event})
+	}
+	return events, nil
+}
+
+// prune forgets all confirmed events that are older than reorgWindow.
+func (c *eventConfirmer) prune() {
+	for id, p := range c.confirmed {
+		if p.log.BlockNumber+reorgWindow < c.head {
+			delete(c.confirmed, id)
+		}
+	}
+}
+
+// isCanonical returns whether the block of the given log is part of the
+// canonical chain.
+func (c *eventConfirmer) isCanonical(ctx context.Context, l types.Log) (bool, error) {
+	h, err := c.cr.HeaderByNumber(ctx, new(big.Int).SetUint64(l.BlockNumber))
+	if err != nil {
+		return false, errors.Wrapf(err, "retrieving block %d", l.BlockNumber)
+	}
+	return h.Hash() == l.BlockHash, nil
+}
@@ -0,0 +1,205 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockChain is a ChainReader that only implements HeaderByNumber on a
+// canonical chain of headers.
+type mockChain struct {
+	ethereum.ChainReader
+	headers []*types.Header
+}
+
+func newMockChain(n int) *mockChain {
+	c := new(mockChain)
+	c.extend(n)
+	return c
+}
+
+// extend appends n new headers to the chain.
+func (c *mockChain) extend(n int) {
+	for i := 0; i < n; i++ {
+		c.headers = append(c.headers, &types.Header{
+			Number: big.NewInt(int64(len(c.headers))),
+			Extra:  []byte{byte(len(c.headers)), byte(i), 1}, // make hashes unique per fork
+		})
+	}
+}
+
+// reorg replaces the newest depth headers by depth+1 new headers.
+func (c *mockChain) reorg(depth int) {
+	c.headers = c.headers[:len(c.headers)-depth]
+	for i := 0; i <= depth; i++ {
+		c.headers = append(c.headers, &types.Header{
+			Number: big.NewInt(int64(len(c.headers))),
+			Extra:  []byte{byte(len(c.headers)), byte(i), 2},
+		})
+	}
+}
+
+func (c *mockChain) head() *types.Header {
+	return c.headers[len(c.headers)-1]
+}
+
+func (c *mockChain) HeaderByNumber(_ context.Context, n *big.Int) (*types.Header, error) {
+	if n == nil {
+		return c.head(), nil
+	}
+	return c.headers[n.Uint64()], nil
+}
+
+// logAt returns a log in the current block of the given number.
+func (c *mockChain) logAt(number uint64, index uint) types.Log {
+	return types.Log{BlockNumber: number, BlockHash: c.headers[number].Hash(), Index: index}
+}
+
+func TestEventConfirmer(t *testing.T) {
+	ctx := context.Background()
+	chain := newMockChain(10)
+	conf := &eventConfirmer{
+		cr:        chain,
+		depth:     3,
+		head:      chain.head().Number.Uint64(),
+		pending:   make(map[logID]pendingEvent),
+		confirmed: make(map[logID]pendingEvent),
+	}
+
+	newHead := func() []confirmedEvent {
+		evs, err := conf.NewHead(ctx, chain.head())
+		require.NoError(t, err)
+		return evs
+	}
+
+	// An old enough event is confirmed immediately.
+	old := chain.logAt(7, 0)
+	evs, err := conf.Add(ctx, "old", old)
+	require.NoError(t, err)
+	assert.Equal(t, []confirmedEvent{{Event: "old"}}, evs)
+
+	// Duplicates are ignored.
+	evs, err = conf.Add(ctx, "old", old)
+	require.NoError(t, err)
+	assert.Empty(t, evs)
+
+	// A new event needs two more blocks.
+	l := chain.logAt(9, 1)
+	evs, err = conf.Add(ctx, "new", l)
+	require.NoError(t, err)
+	assert.Empty(t, evs)
+	chain.extend(1)
+	assert.Empty(t, newHead())
+
+	// A reorg removes the pending event, it is dropped.
+	chain.reorg(2)
+	removed := l
+	removed.Removed = true
+	evs, err = conf.Add(ctx, "new", removed)
+	require.NoError(t, err)
+	assert.Empty(t, evs)
+	chain.extend(3)
+	assert.Empty(t, newHead())
+
+	// An event of a block that is not canonical anymore is dropped, even
+	// without removal.
+	stale := chain.logAt(chain.head().Number.Uint64(), 0)
+	evs, err = conf.Add(ctx, "stale", stale)
+	require.NoError(t, err)
+	assert.Empty(t, evs)
+	chain.reorg(3)
+	chain.extend(2)
+	assert.Empty(t, newHead())
+	assert.Empty(t, conf.pending)
+
+	// A deep reorg retracts a confirmed event.
+	removed = old
+	removed.Removed = true
+	evs, err = conf.Add(ctx, "old", removed)
+	require.NoError(t, err)
+	assert.Equal(t, []confirmedEvent{{Event: "old", Removed: true}}, evs)
+
+	// Confirmed events are returned in chain order.
+	head := chain.head().Number.Uint64()
+	for i, ev := range []string{"b", "a"} {
+		_, err := conf.Add(ctx, ev, chain.logAt(head-uint64(i), 0))
+		require.NoError(t, err)
+	}
+	chain.extend(2)
+	assert.Equal(t, []confirmedEvent{{Event: "a"}, {Event: "b"}}, newHead())
+}
+
+func TestEventConfirmer_NoDepth(t *testing.T) {
+	ctx := context.Background()
+	conf, err := newEventConfirmer(ctx, &ContractBackend{})
+	require.NoError(t, err)
+	defer conf.Close()
+	assert.Nil(t, conf.Heads())
+	assert.Nil(t, conf.Err())
+
+	l := types.Log{BlockNumber: 1, BlockHash: common.Hash{1}}
+	evs, err := conf.Add(ctx, "ev", l)
+	require.NoError(t, err)
+	assert.Equal(t, []confirmedEvent{{Event: "ev"}}, evs)
+}
+
+func TestEventConfirmer_Prune(t *testing.T) {
+	ctx := context.Background()
+	chain := newMockChain(10)
+	conf := &eventConfirmer{
+		cr:        chain,
+		depth:     3,
+		head:      chain.head().Number.Uint64(),
+		pending:   make(map[logID]pendingEvent),
+		confirmed: make(map[logID]pendingEvent),
+	}
+
+	evs, err := conf.Add(ctx, "ev", chain.logAt(5, 0))
+	require.NoError(t, err)
+	require.Len(t, evs, 1)
+
+	chain.extend(reorgWindow - 4)
+	_, err = conf.NewHead(ctx, chain.head())
+	require.NoError(t, err)
+	assert.Len(t, conf.confirmed, 1, "event within the reorg window should be kept")
+
+	chain.extend(1)
+	_, err = conf.NewHead(ctx, chain.head())
+	require.NoError(t, err)
+	assert.Empty(t, conf.confirmed, "event older than the reorg window should be pruned")
+
+	t.Run("no depth", func(t *testing.T) {
+		conf, err := newEventConfirmer(ctx, &ContractBackend{})
+		require.NoError(t, err)
+		defer conf.Close()
+
+		_, err = conf.Add(ctx, "old", types.Log{BlockNumber: 1, BlockHash: common.Hash{1}})
+		require.NoError(t, err)
+		_, err = conf.Add(ctx, "new", types.Log{BlockNumber: 2 + reorgWindow, BlockHash: common.Hash{2}})
+		require.NoError(t, err)
+		require.Len(t, conf.confirmed, 1)
+		_, ok := conf.confirmed[logID{block: common.Hash{2}}]
+		assert.True(t, ok, "newest event should be kept")
+	})
+}
@@ -23,18 +23,30 @@ import (
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/pkg/errors"
 
+	"perun.network/go-perun/channel"
 	"perun.network/go-perun/log"
 )
 
-// How many blocks we query into the past for events.
+// How many blocks we query into the past for events if no event cursor is
+// known, see CursorStore.
 const startBlockOffset = 100
 
+// filterChunkSize is the maximal number of blocks that are scanned for events
+// with a single filter query.
+const filterChunkSize = 5000
+
 // GasLimit is the max amount of gas we want to send per transaction.
 const GasLimit = 500000
 
+// DefaultConfirmationDepth is the default number of blocks that a transaction
+// or event has to be included in. A depth of one means that a mined
+// transaction or event is treated as final.
+const DefaultConfirmationDepth = 1
+
 // ContractInterface provides all functions needed by an ethereum backend.
 // Both test.SimulatedBackend and ethclient.Client implement this interface.
 type ContractInterface interface {
@@ -52,15 +64,111 @@ type Transactor interface {
 // This is needed to send on-chain transaction to interact with the smart contracts.
 type ContractBackend struct {
 	ContractInterface
-	tr Transactor
+	tr            Transactor
+	confirmations uint64
+	cursors       CursorStore
+	gas           GasStrategy
+	nonces        *NonceManager
+	heads         *ChainHeadTracker
 }
 
-// NewContractBackend creates a new ContractBackend with the given parameters.
+// NewContractBackend creates a new ContractBackend with the given parameters
+// and the DefaultConfirmationDepth. Event cursors are kept in memory, see
+// SetCursorStore. Nonces are handed out by a new NonceManager, see
+// SetNonceManager. All subscriptions to new chain heads share a single
+// subscription of a ChainHeadTracker.
 func NewContractBackend(cf ContractInterface, tr Transactor) ContractBackend {
+	return NewContractBackendWithConfirmations(cf, tr, DefaultConfirmationDepth)
+}
+
+// NewContractBackendWithConfirmations creates a new ContractBackend that only
+// treats transactions and events as final once they are included in the block
+// at depth confirmations of the canonical chain, i.e., once confirmations-1
+// blocks were mined on top of their block. Events that are removed from the
+// chain by a reorganization before are dropped.
+func NewContractBackendWithConfirmations(cf ContractInterface, tr Transactor, confirmations uint64) ContractBackend {
 	return ContractBackend{
 		ContractInterface: cf,
 		tr:                tr,
+		confirmations:     confirmations,
+		cursors:           NewMemCursorStore(),
+		nonces:            NewNonceManager(cf),
+		heads:             NewChainHeadTracker(cf),
+	}
+}
+
+// SetCursorStore sets the store of the event cursors of channels. Use a
+// persistent store, like a KVCursorStore, so that restored channels find all
+// past adjudicator events. It has to be set before the ContractBackend is
+// passed to NewFunder or NewAdjudicator.
+func (c *ContractBackend) SetCursorStore(cursors CursorStore) {
+	c.cursors = cursors
+}
+
+// CursorStore returns the store of the event cursors of channels. It is nil
+// for a ContractBackend that was not created with a constructor.
+func (c *ContractBackend) CursorStore() CursorStore {
+	return c.cursors
+}
+
+// SetGasStrategy sets the strategy that determines the gas limit and price of
+// transactions and whether stuck transactions are re-sent. If it is nil, the
+// gas limits passed to NewTransactor are used and the gas price is left to
+// go-ethereum. It has to be set before the ContractBackend is passed to
+// NewFunder or NewAdjudicator.
+func (c *ContractBackend) SetGasStrategy(gs GasStrategy) {
+	c.gas = gs
+}
+
+// GasStrategy returns the gas strategy of the ContractBackend, see
+// SetGasStrategy.
+func (c *ContractBackend) GasStrategy() GasStrategy {
+	return c.gas
+}
+
+// SetNonceManager sets the NonceManager that hands out the nonces of
+// transactions created with NewTransactor. Share one NonceManager between all
+// ContractBackends that send transactions from the same accounts. If it is
+// nil, the nonces are set by go-ethereum. It has to be set before the
+// ContractBackend is passed to NewFunder or NewAdjudicator.
+func (c *ContractBackend) SetNonceManager(nm *NonceManager) {
+	c.nonces = nm
+}
+
+// NonceManager returns the NonceManager of the ContractBackend, see
+// SetNonceManager.
+func (c *ContractBackend) NonceManager() *NonceManager {
+	return c.nonces
+}
+
+// HeadTracker returns the ChainHeadTracker of the ContractBackend. It is nil
+// for a ContractBackend that was not created with a constructor.
+func (c *ContractBackend) HeadTracker() *ChainHeadTracker {
+	return c.heads
+}
+
+// SubscribeNewHead subscribes to new chain heads using the shared subscription
+// of the ChainHeadTracker, see HeadTracker.
+func (c ContractBackend) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return c.headReader().SubscribeNewHead(ctx, ch)
+}
+
+// headReader returns the ChainHeadTracker or, if there is none, the
+// ContractInterface.
+func (c *ContractBackend) headReader() ethereum.ChainReader {
+	if c.heads == nil {
+		return c.ContractInterface
 	}
+	return c.heads
+}
+
+// ConfirmationDepth returns the number of blocks that a transaction or event
+// has to be included in before it is treated as final. It is at least one.
+func (c *ContractBackend) ConfirmationDepth() uint64 {
+	if c.confirmations == 0 {
+		return 1
+	}
+	return c.confirmations
 }
 
 // NewWatchOpts returns bind.WatchOpts with the field Start set to the current
@@ -92,6 +200,57 @@ func (c *ContractBackend) NewFilterOpts(ctx context.Context) (*bind.FilterOpts,
 	}, nil
 }
 
+// startBlockNum returns the block from which on events of the given channel
+// are scanned. It is the channel's cursor, if set, and startBlockOffset blocks
+// before the latest block otherwise.
+func (c *ContractBackend) startBlockNum(ctx context.Context, id channel.ID) (uint64, error) {
+	if c.cursors != nil {
+		block, ok, err := c.cursors.Cursor(id)
+		if err != nil {
+			return 0, errors.WithMessage(err, "reading event cursor")
+		} else if ok {
+			return block, nil
+		}
+	}
+	return c.pastOffsetBlockNum(ctx)
+}
+
+// initCursor sets the event cursor of the given channel to the latest block
+// if it is not set yet.
+func (c *ContractBackend) initCursor(ctx context.Context, id channel.ID) error {
+	if c.cursors == nil {
+		return nil
+	}
+	if _, ok, err := c.cursors.Cursor(id); err != nil {
+		return errors.WithMessage(err, "reading event cursor")
+	} else if ok {
+		return nil
+	}
+	h, err := c.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "retrieving latest block")
+	}
+	return errors.WithMessage(c.cursors.SetCursor(id, h.Number.Uint64()), "setting event cursor")
+}
+
+// forEachChunk calls fn with consecutive block ranges [start, end] of at most
+// size blocks that cover the blocks from to to, including both.
+func forEachChunk(from, to, size uint64, fn func(start, end uint64) error) error {
+	for start := from; start <= to; start += size {
+		end := start + size - 1
+		if end > to || end < start { // end < start on overflow
+			end = to
+		}
+		if err := fn(start, end); err != nil {
+			return err
+		}
+		if end == to {
+			break
+		}
+	}
+	return nil
+}
+
 func (c *ContractBackend) pastOffsetBlockNum(ctx context.Context) (uint64, error) {
 	h, err := c.HeaderByNumber(ctx, nil)
 	if err != nil {
@@ -108,9 +267,17 @@ func (c *ContractBackend) pastOffsetBlockNum(ctx context.Context) (uint64, error
 // NewTransactor returns bind.TransactOpts with the context, gas limit and
 // account set as specified, using the ContractBackend's Transactor.
 //
-// The gas price and nonce are not set and will be set by go-ethereum
+// If the ContractBackend has a GasStrategy, the gas limit is ignored. Instead,
+// the gas usage of each transaction is estimated by go-ethereum and the gas
+// limit is set by the GasStrategy, as well as the gas price.
+//
+// Otherwise, the gas price is not set and will be set by go-ethereum
 // automatically when not manually specified by the caller. The caller must also
 // set the value manually afterwards if it should be different from 0.
+//
+// If the ContractBackend has a NonceManager, the nonce is set by it when the
+// transaction is signed. The transaction must then be sent with the
+// SendTransaction method of the ContractBackend.
 func (c *ContractBackend) NewTransactor(ctx context.Context, gasLimit uint64,
 	acc accounts.Account) (*bind.TransactOpts, error) {
 	auth, err := c.tr.NewTransactor(acc)
@@ -121,15 +288,50 @@ func (c *ContractBackend) NewTransactor(ctx context.Context, gasLimit uint64,
 	auth.GasLimit = gasLimit
 	auth.Context = ctx
 
+	if c.gas != nil {
+		if auth.GasPrice, err = c.gas.GasPrice(ctx); err != nil {
+			return nil, errors.WithMessage(err, "determining gas price")
+		}
+		// go-ethereum estimates the gas if no limit is set. The signer is
+		// called with the estimate, so we replace it there.
+		auth.GasLimit = 0
+		signer := auth.Signer
+		auth.Signer = func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			return signer(addr, copyTx(tx, tx.Nonce(), c.gas.GasLimit(tx.Gas()), tx.GasPrice()))
+		}
+	}
+	if c.nonces != nil {
+		auth.Signer = c.nonces.signer(ctx, auth.Signer)
+	}
+
 	return auth, nil
 }
 
+// SendTransaction sends the transaction and informs the NonceManager, if any,
+// whether its nonce was used.
+func (c ContractBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	err := c.ContractInterface.SendTransaction(ctx, tx)
+	if c.nonces != nil {
+		c.nonces.sent(tx.Hash())
+	}
+	return err
+}
+
 // ConfirmTransaction returns whether a transaction was mined successfully or not
-// and the receipt if it could be retrieved.
+// and the receipt if it could be retrieved. It waits until the transaction is
+// buried under ConfirmationDepth-1 blocks. If the transaction's block is
+// reorganized out of the chain in the meantime, it waits for the transaction
+// to be mined again.
+//
+// If the GasStrategy of the ContractBackend re-sends stuck transactions, the
+// returned receipt might be of a re-sent transaction with the same nonce.
 func (c *ContractBackend) ConfirmTransaction(ctx context.Context, tx *types.Transaction, acc accounts.Account) (*types.Receipt, error) {
-	receipt, err := bind.WaitMined(ctx, c, tx)
+	tx, receipt, err := c.waitMined(ctx, tx, acc)
 	if err != nil {
-		return nil, errors.Wrap(err, "sending transaction")
+		return nil, errors.WithMessage(err, "sending transaction")
+	}
+	if receipt, err = c.waitConfirmed(ctx, tx, receipt); err != nil {
+		return nil, errors.WithMessage(err, "waiting for confirmations")
 	}
 	if receipt.Status == types.ReceiptStatusFailed {
 		reason, err := errorReason(ctx, c, tx, receipt.BlockNumber, acc)
@@ -149,6 +351,55 @@ func (c *ContractBackend) ConfirmTransaction(ctx context.Context, tx *types.Tran
 	return receipt, nil
 }
 
+// waitConfirmed waits until the mined transaction with the given receipt is
+// buried under ConfirmationDepth-1 blocks and returns its final receipt.
+func (c *ContractBackend) waitConfirmed(ctx context.Context, tx *types.Transaction, receipt *types.Receipt) (*types.Receipt, error) {
+	depth := c.ConfirmationDepth()
+	if depth <= 1 {
+		return receipt, nil
+	}
+
+	heads := make(chan *types.Header)
+	sub, err := c.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return nil, errors.Wrap(err, "subscribing to new blocks")
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		head, err := c.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "retrieving latest block")
+		}
+		if receipt.BlockNumber.Uint64()+depth-1 <= head.Number.Uint64() {
+			// Check that the transaction was not reorganized in the meantime.
+			current, err := c.TransactionReceipt(ctx, tx.Hash())
+			if err != nil && !stderrors.Is(err, ethereum.NotFound) {
+				return nil, errors.Wrap(err, "retrieving receipt")
+			}
+			if current == nil {
+				log.WithField("tx", tx.Hash()).Warn("TX was reorganized out of the chain, waiting for it to be mined again")
+				if current, err = bind.WaitMined(ctx, c, tx); err != nil {
+					return nil, errors.Wrap(err, "waiting for TX to be mined again")
+				}
+			}
+			if current.BlockHash == receipt.BlockHash {
+				return receipt, nil
+			}
+			receipt = current
+			continue
+		}
+
+		select {
+		case <-heads:
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "context cancelled")
+		case err := <-sub.Err():
+			return nil, errors.Wrap(err, "header subscription")
+		}
+	}
+}
+
 // ErrTxFailed signals a failed, i.e., reverted, transaction.
 var ErrTxFailed = stderrors.New("transaction failed")
 
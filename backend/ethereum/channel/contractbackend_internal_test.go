@@ -0,0 +1,53 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_forEachChunk(t *testing.T) {
+	tests := []struct {
+		from, to, size uint64
+		chunks         [][2]uint64
+	}{
+		{1, 1, 10, [][2]uint64{{1, 1}}},
+		{1, 10, 10, [][2]uint64{{1, 10}}},
+		{1, 11, 10, [][2]uint64{{1, 10}, {11, 11}}},
+		{5, 30, 10, [][2]uint64{{5, 14}, {15, 24}, {25, 30}}},
+		{11, 10, 10, nil},
+		{math.MaxUint64 - 1, math.MaxUint64, 10, [][2]uint64{{math.MaxUint64 - 1, math.MaxUint64}}},
+	}
+	for _, tt := range tests {
+		var chunks [][2]uint64
+		assert.NoError(t, forEachChunk(tt.from, tt.to, tt.size, func(start, end uint64) error {
+			chunks = append(chunks, [2]uint64{start, end})
+			return nil
+		}))
+		assert.Equal(t, tt.chunks, chunks, "from %d to %d in chunks of %d", tt.from, tt.to, tt.size)
+	}
+
+	err := errors.New("chunk error")
+	calls := 0
+	assert.Same(t, err, forEachChunk(1, 100, 10, func(uint64, uint64) error {
+		calls++
+		return err
+	}))
+	assert.Equal(t, 1, calls)
+}
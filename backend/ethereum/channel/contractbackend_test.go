@@ -16,17 +16,22 @@ package channel_test
 
 import (
 	"context"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	ethchannel "perun.network/go-perun/backend/ethereum/channel"
 	"perun.network/go-perun/backend/ethereum/channel/test"
 	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/keystore"
 	pkgtest "perun.network/go-perun/pkg/test"
 	"perun.network/go-perun/wallet"
+	wallettest "perun.network/go-perun/wallet/test"
 )
 
 func fromEthAddr(a common.Address) wallet.Address {
@@ -100,3 +105,66 @@ func Test_NewWatchOpts(t *testing.T) {
 	assert.Equal(t, context.WithValue(context.Background(), &key, "bar"), watchOpts.Context, "context should be set")
 	assert.Equal(t, uint64(1), *watchOpts.Start, "startblock should be 1")
 }
+
+func Test_ConfirmTransaction_Reorg(t *testing.T) {
+	const depth = 3
+	rng := pkgtest.Prng(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	sb := test.NewSimulatedBackend()
+	ksWallet := wallettest.RandomWallet().(*keystore.Wallet)
+	acc := ksWallet.NewRandomAccount(rng).(*keystore.Account).Account
+	sb.FundAddress(ctx, acc.Address)
+	cb := ethchannel.NewContractBackendWithConfirmations(sb,
+		keystore.NewTransactor(*ksWallet, types.NewEIP155Signer(big.NewInt(1337))), depth)
+	assert.Equal(t, uint64(depth), cb.ConfirmationDepth())
+
+	opts, err := cb.NewTransactor(ctx, ethchannel.GasLimit, acc)
+	require.NoError(t, err)
+	nonce, err := sb.PendingNonceAt(ctx, acc.Address)
+	require.NoError(t, err)
+	tx, err := opts.Signer(acc.Address,
+		types.NewTransaction(nonce, common.Address{1}, big.NewInt(1), ethchannel.GasLimit, big.NewInt(1), nil))
+	require.NoError(t, err)
+	require.NoError(t, sb.SendTransaction(ctx, tx))
+
+	confirmed := make(chan *types.Receipt, 1)
+	go func() {
+		receipt, err := cb.ConfirmTransaction(ctx, tx, acc)
+		assert.NoError(t, err)
+		confirmed <- receipt
+	}()
+	assertPending := func() {
+		select {
+		case <-confirmed:
+			t.Fatal("transaction confirmed too early")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	assertPending()
+	sb.Commit()
+	assertPending()
+	// Replace the block of the transaction and the one after by three empty
+	// blocks, the transaction would have been confirmed in the last one.
+	require.NoError(t, sb.Reorg(ctx, 2))
+	assertPending()
+
+	// Mine the transaction again.
+	require.NoError(t, sb.SendTransaction(ctx, tx))
+	mined, err := sb.HeaderByNumber(ctx, nil)
+	require.NoError(t, err)
+	sb.Commit()
+	assertPending()
+	sb.Commit()
+
+	select {
+	case receipt := <-confirmed:
+		require.NotNil(t, receipt)
+		assert.Equal(t, mined.Number, receipt.BlockNumber)
+		assert.Equal(t, mined.Hash(), receipt.BlockHash)
+	case <-ctx.Done():
+		t.Fatal("transaction not confirmed")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/pkg/sortedkv"
+)
+
+type (
+	// A CursorStore stores an event cursor per channel. The cursor is the
+	// block from which on the Adjudicator scans for past ChannelUpdate events
+	// when subscribing to a channel. It is initialized by the Funder to the
+	// block at which the funding started and advanced by subscriptions to the
+	// block of the newest confirmed event.
+	CursorStore interface {
+		// Cursor returns the cursor of the given channel and whether it is
+		// set.
+		Cursor(id channel.ID) (block uint64, ok bool, err error)
+
+		// SetCursor sets the cursor of the given channel.
+		SetCursor(id channel.ID, block uint64) error
+	}
+
+	// MemCursorStore is a CursorStore that keeps the cursors in memory.
+	MemCursorStore struct {
+		mu      sync.Mutex
+		cursors map[channel.ID]uint64
+	}
+
+	// KVCursorStore is a CursorStore that persists the cursors in a sorted
+	// key-value database.
+	KVCursorStore struct {
+		db sortedkv.Database
+	}
+)
+
+// NewMemCursorStore returns a new, empty MemCursorStore.
+func NewMemCursorStore() *MemCursorStore {
+	return &MemCursorStore{cursors: make(map[channel.ID]uint64)}
+}
+
+// Cursor returns the cursor of the given channel and whether it is set.
+func (s *MemCursorStore) Cursor(id channel.ID) (uint64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	block, ok := s.cursors[id]
+	return block, ok, nil
+}
+
+// SetCursor sets the cursor of the given channel.
+func (s *MemCursorStore) SetCursor(id channel.ID, block uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cursors[id] = block
+	return nil
+}
+
+// cursorPrefix is the key prefix of cursors in a KVCursorStore.
+const cursorPrefix = "EthCursor:"
+
+// NewKVCursorStore returns a new KVCursorStore that stores the cursors in the
+// given database.
+func NewKVCursorStore(db sortedkv.Database) *KVCursorStore {
+	return &KVCursorStore{db: sortedkv.NewTable(db, cursorPrefix)}
+}
+
+// Cursor returns the cursor of the given channel and whether it is set.
+func (s *KVCursorStore) Cursor(id channel.ID) (uint64, bool, error) {
+	key := string(id[:])
+	if ok, err := s.db.Has(key); err != nil {
+		return 0, false, errors.WithMessage(err, "checking for cursor")
+	} else if !ok {
+		return 0, false, nil
+	}
+
+	b, err := s.db.GetBytes(key)
+	if err != nil {
+		return 0, false, errors.WithMessage(err, "reading cursor")
+	}
+	if len(b) != 8 {
+		return 0, false, errors.Errorf("invalid cursor length %d", len(b))
+	}
+	return binary.BigEndian.Uint64(b), true, nil
+}
+
+// SetCursor sets the cursor of the given channel.
+func (s *KVCursorStore) SetCursor(id channel.ID, block uint64) error {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], block)
+	return errors.WithMessage(s.db.PutBytes(string(id[:]), b[:]), "writing cursor")
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ethchannel "perun.network/go-perun/backend/ethereum/channel"
+	channeltest "perun.network/go-perun/channel/test"
+	"perun.network/go-perun/pkg/sortedkv/memorydb"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+func TestMemCursorStore(t *testing.T) {
+	testCursorStore(t, ethchannel.NewMemCursorStore())
+}
+
+func TestKVCursorStore(t *testing.T) {
+	db := memorydb.NewDatabase()
+	testCursorStore(t, ethchannel.NewKVCursorStore(db))
+
+	// Cursors are persisted in the database.
+	rng := pkgtest.Prng(t)
+	id := channeltest.NewRandomChannelID(rng)
+	require.NoError(t, ethchannel.NewKVCursorStore(db).SetCursor(id, 42))
+	block, ok, err := ethchannel.NewKVCursorStore(db).Cursor(id)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(42), block)
+}
+
+func testCursorStore(t *testing.T, s ethchannel.CursorStore) {
+	rng := pkgtest.Prng(t)
+	id := channeltest.NewRandomChannelID(rng)
+
+	_, ok, err := s.Cursor(id)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	for _, block := range []uint64{7, 3, 1 << 40} {
+		require.NoError(t, s.SetCursor(id, block))
+		got, ok, err := s.Cursor(id)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, block, got)
+	}
+
+	_, ok, err = s.Cursor(channeltest.NewRandomChannelID(rng))
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
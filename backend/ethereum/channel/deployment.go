@@ -0,0 +1,127 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A Deployment is a full set of Perun contracts on one chain: the
+	// Adjudicator, the ETH AssetHolder and one ERC20 AssetHolder per token. It
+	// can be written to and read from JSON config files.
+	Deployment struct {
+		ChainID        *big.Int          `json:"chainID"`
+		Adjudicator    common.Address    `json:"adjudicator"`
+		AssetHolderETH common.Address    `json:"assetHolderETH"`
+		ERC20          []ERC20Deployment `json:"erc20"`
+	}
+
+	// An ERC20Deployment is the AssetHolder of an ERC20 token.
+	ERC20Deployment struct {
+		Token       common.Address `json:"token"`
+		AssetHolder common.Address `json:"assetHolder"`
+	}
+)
+
+// DeployContracts deploys the Adjudicator, the ETH AssetHolder and an ERC20
+// AssetHolder for each of the given tokens with the deployer account. The
+// ChainID of the returned Deployment is queried from the backend if it
+// supports it and nil otherwise.
+func DeployContracts(ctx context.Context, backend ContractBackend, deployer accounts.Account, tokens []common.Address) (*Deployment, error) {
+	var d Deployment
+	if cr, ok := backend.ContractInterface.(chainIDReader); ok {
+		id, err := cr.ChainID(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "querying chain ID")
+		}
+		d.ChainID = id
+	}
+
+	var err error
+	if d.Adjudicator, err = DeployAdjudicator(ctx, backend, deployer); err != nil {
+		return nil, errors.WithMessage(err, "deploying Adjudicator")
+	}
+	if d.AssetHolderETH, err = DeployETHAssetholder(ctx, backend, d.Adjudicator, deployer); err != nil {
+		return nil, errors.WithMessage(err, "deploying ETH AssetHolder")
+	}
+	for _, token := range tokens {
+		ah, err := DeployERC20Assetholder(ctx, backend, d.Adjudicator, token, deployer)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "deploying ERC20 AssetHolder for token %v", token.Hex())
+		}
+		d.ERC20 = append(d.ERC20, ERC20Deployment{Token: token, AssetHolder: ah})
+	}
+	return &d, nil
+}
+
+// ValidateDeployment checks the bytecodes of all contracts of the Deployment
+// and that the AssetHolders are bound to its Adjudicator and tokens. Returns a
+// ContractBytecodeError if a bytecode is invalid. This error can be checked
+// with function IsErrInvalidContractCode.
+func ValidateDeployment(ctx context.Context, backend bind.ContractBackend, d *Deployment) error {
+	if err := ValidateAdjudicator(ctx, backend, d.Adjudicator); err != nil {
+		return errors.WithMessagef(err, "validating Adjudicator at %v", d.Adjudicator.Hex())
+	}
+	if err := ValidateAssetHolderETH(ctx, backend, d.AssetHolderETH, d.Adjudicator); err != nil {
+		return errors.WithMessagef(err, "validating ETH AssetHolder at %v", d.AssetHolderETH.Hex())
+	}
+	for _, erc20 := range d.ERC20 {
+		if err := ValidateAssetHolderERC20(ctx, backend, erc20.AssetHolder, d.Adjudicator, erc20.Token); err != nil {
+			return errors.WithMessagef(err, "validating ERC20 AssetHolder at %v for token %v",
+				erc20.AssetHolder.Hex(), erc20.Token.Hex())
+		}
+	}
+	return nil
+}
+
+// EIP712Domain returns the EIP-712 signing domain of the Deployment, see
+// EIP712Domain. Returns an error if the ChainID of the Deployment is unknown.
+func (d *Deployment) EIP712Domain() (*EIP712Domain, error) {
+	if d.ChainID == nil {
+		return nil, errors.New("deployment has no chain ID")
+	}
+	return NewEIP712Domain(d.ChainID, d.Adjudicator), nil
+}
+
+// chainIDReader is implemented by backends that know their chain ID, e.g.,
+// the ethclient.Client.
+type chainIDReader interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// WriteDeployment writes the Deployment as indented JSON to w.
+func WriteDeployment(w io.Writer, d *Deployment) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return errors.Wrap(enc.Encode(d), "encoding deployment")
+}
+
+// ReadDeployment reads a JSON encoded Deployment from r.
+func ReadDeployment(r io.Reader) (*Deployment, error) {
+	var d Deployment
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return nil, errors.Wrap(err, "decoding deployment")
+	}
+	return &d, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ethchannel "perun.network/go-perun/backend/ethereum/channel"
+	"perun.network/go-perun/backend/ethereum/channel/test"
+	ethwallettest "perun.network/go-perun/backend/ethereum/wallet/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+func TestDeployment(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	s := test.NewSimSetup(rng)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTxTimeout)
+	defer cancel()
+
+	tokens := []common.Address{
+		common.Address(ethwallettest.NewRandomAddress(rng)),
+		common.Address(ethwallettest.NewRandomAddress(rng)),
+	}
+	d, err := ethchannel.DeployContracts(ctx, *s.CB, s.TxSender.Account, tokens)
+	require.NoError(t, err)
+	require.Len(t, d.ERC20, len(tokens))
+	for i, token := range tokens {
+		assert.Equal(t, token, d.ERC20[i].Token)
+	}
+	require.NoError(t, ethchannel.ValidateDeployment(ctx, *s.CB, d))
+
+	t.Run("config", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, ethchannel.WriteDeployment(&buf, d))
+		read, err := ethchannel.ReadDeployment(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, d, read)
+	})
+
+	t.Run("wrong_adjudicator", func(t *testing.T) {
+		wrong := *d
+		wrong.Adjudicator = d.AssetHolderETH
+		assert.True(t, ethchannel.IsErrInvalidContractCode(ethchannel.ValidateDeployment(ctx, *s.CB, &wrong)))
+	})
+
+	t.Run("wrong_token", func(t *testing.T) {
+		wrong := *d
+		wrong.ERC20 = []ethchannel.ERC20Deployment{{Token: tokens[1], AssetHolder: d.ERC20[0].AssetHolder}}
+		assert.True(t, ethchannel.IsErrInvalidContractCode(ethchannel.ValidateDeployment(ctx, *s.CB, &wrong)))
+	})
+}
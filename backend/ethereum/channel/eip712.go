@@ -0,0 +1,298 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel
+
+import (
+	"math/big"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/backend/ethereum/bindings/adjudicator"
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+const (
+	// DefaultEIP712Name is the default name of the EIP-712 signing domain.
+	DefaultEIP712Name = "Perun"
+	// DefaultEIP712Version is the default version of the EIP-712 signing
+	// domain.
+	DefaultEIP712Version = "1"
+)
+
+// EIP-712 type hashes. A struct type is encoded together with the types it
+// references, sorted by name.
+var (
+	typeHashDomain = crypto.Keccak256Hash([]byte(
+		"EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	typeHashState = crypto.Keccak256Hash([]byte(
+		"State(bytes32 channelID,Params params,uint64 version,Allocation outcome,bytes appData,bool isFinal)" +
+			typeAllocation + typeParams))
+	typeHashParams     = crypto.Keccak256Hash([]byte(typeParams))
+	typeHashAllocation = crypto.Keccak256Hash([]byte(typeAllocation))
+)
+
+const (
+	typeParams     = "Params(uint256 challengeDuration,uint256 nonce,address app,address[] participants)"
+	typeAllocation = "Allocation(address[] assets,uint256[] balances,bytes32[] locked,uint256[] lockedBalances)"
+)
+
+// EIP712Domain is the EIP-712 signing domain of a deployment. It binds
+// signatures to a chain and an adjudicator contract. Use
+// Deployment.EIP712Domain to obtain the domain of a deployment.
+//
+// The Adjudicator contract only verifies signatures on ABI-encoded states, see
+// Sign, and rejects typed-data signatures. The channel backend therefore
+// always signs states that way and cannot be configured to sign typed data;
+// that would require an Adjudicator contract that verifies EIP-712
+// signatures. Typed-data signatures are only meant for off-chain use, e.g., to
+// let wallets display the state that is signed.
+//
+// The primary type of typed-data messages is
+//  State(bytes32 channelID,Params params,uint64 version,Allocation outcome,bytes appData,bool isFinal)
+// with the referenced types
+//  Params(uint256 challengeDuration,uint256 nonce,address app,address[] participants)
+//  Allocation(address[] assets,uint256[] balances,bytes32[] locked,uint256[] lockedBalances)
+// so that wallets can show the channel parameters along with the state. The
+// balances are flattened by asset, i.e., the balance of participant j in asset
+// i is at index i*len(participants)+j. The locked balances are flattened by
+// sub-channel, i.e., the balance of asset i locked in the sub-channel with ID
+// locked[k] is at index k*len(assets)+i. Nested arrays and arrays of structs
+// are avoided because not all signers, e.g., older versions of Clef, encode
+// them correctly.
+type EIP712Domain struct {
+	Name              string
+	Version           string
+	ChainID           *big.Int
+	VerifyingContract common.Address
+}
+
+// NewEIP712Domain returns the EIP712Domain of the adjudicator at the given
+// address on the given chain, with the default name and version.
+func NewEIP712Domain(chainID *big.Int, adjudicator common.Address) *EIP712Domain {
+	return &EIP712Domain{
+		Name:              DefaultEIP712Name,
+		Version:           DefaultEIP712Version,
+		ChainID:           new(big.Int).Set(chainID),
+		VerifyingContract: adjudicator,
+	}
+}
+
+// Separator returns the domain separator.
+func (d *EIP712Domain) Separator() common.Hash {
+	return crypto.Keccak256Hash(
+		typeHashDomain[:],
+		crypto.Keccak256([]byte(d.Name)),
+		crypto.Keccak256([]byte(d.Version)),
+		math.U256Bytes(new(big.Int).Set(d.ChainID)),
+		common.LeftPadBytes(d.VerifyingContract[:], 32),
+	)
+}
+
+// TypedData returns the state with the given params as EIP-712 typed data of
+// the domain. It can be signed by an ethwallet.TypedDataSigner.
+func (d *EIP712Domain) TypedData(p *channel.Params, s *channel.State) *core.TypedData {
+	params := ToEthParams(p)
+	state := ToEthState(s)
+	return &core.TypedData{
+		Types: core.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"State": {
+				{Name: "channelID", Type: "bytes32"},
+				{Name: "params", Type: "Params"},
+				{Name: "version", Type: "uint64"},
+				{Name: "outcome", Type: "Allocation"},
+				{Name: "appData", Type: "bytes"},
+				{Name: "isFinal", Type: "bool"},
+			},
+			"Params": {
+				{Name: "challengeDuration", Type: "uint256"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "app", Type: "address"},
+				{Name: "participants", Type: "address[]"},
+			},
+			"Allocation": {
+				{Name: "assets", Type: "address[]"},
+				{Name: "balances", Type: "uint256[]"},
+				{Name: "locked", Type: "bytes32[]"},
+				{Name: "lockedBalances", Type: "uint256[]"},
+			},
+		},
+		PrimaryType: "State",
+		Domain: core.TypedDataDomain{
+			Name:              d.Name,
+			Version:           d.Version,
+			ChainId:           (*math.HexOrDecimal256)(new(big.Int).Set(d.ChainID)),
+			VerifyingContract: d.VerifyingContract.Hex(),
+		},
+		Message: core.TypedDataMessage{
+			"channelID": hexutil.Encode(state.ChannelID[:]),
+			"params": map[string]interface{}{
+				"challengeDuration": params.ChallengeDuration.String(),
+				"nonce":             params.Nonce.String(),
+				"app":               params.App.Hex(),
+				"participants":      typedAddresses(params.Participants),
+			},
+			"version": strconv.FormatUint(state.Version, 10),
+			"outcome": typedAllocation(&state.Outcome),
+			"appData": hexutil.Encode(state.AppData),
+			"isFinal": state.IsFinal,
+		},
+	}
+}
+
+func typedAllocation(a *adjudicator.ChannelAllocation) map[string]interface{} {
+	var bals, lockedBals []*big.Int
+	for _, assetBals := range a.Balances {
+		bals = append(bals, assetBals...)
+	}
+	locked := make([]interface{}, len(a.Locked))
+	for i, sub := range a.Locked {
+		locked[i] = hexutil.Encode(sub.ID[:])
+		lockedBals = append(lockedBals, sub.Balances...)
+	}
+	return map[string]interface{}{
+		"assets":         typedAddresses(a.Assets),
+		"balances":       typedUints(bals),
+		"locked":         locked,
+		"lockedBalances": typedUints(lockedBals),
+	}
+}
+
+func typedAddresses(addrs []common.Address) []interface{} {
+	typed := make([]interface{}, len(addrs))
+	for i, a := range addrs {
+		typed[i] = a.Hex()
+	}
+	return typed
+}
+
+func typedUints(xs []*big.Int) []interface{} {
+	typed := make([]interface{}, len(xs))
+	for i, x := range xs {
+		typed[i] = x.String()
+	}
+	return typed
+}
+
+// EncodeTypedData returns the EIP-712 encoding of the state with the given
+// params, "\x19\x01" ‖ domainSeparator ‖ hashStruct(state), whose Keccak256
+// hash is signed. It equals the ethwallet.EncodeTypedData of TypedData.
+func (d *EIP712Domain) EncodeTypedData(p *channel.Params, s *channel.State) []byte {
+	params := ToEthParams(p)
+	state := ToEthState(s)
+	sep := d.Separator()
+	hash := hashStateEIP712(&params, &state)
+	return append(append([]byte{0x19, 0x01}, sep[:]...), hash[:]...)
+}
+
+// SignTypedData signs the state as EIP-712 typed data of the domain. The
+// account must be an ethwallet.TypedDataSigner.
+func (d *EIP712Domain) SignTypedData(acc wallet.Account, p *channel.Params, s *channel.State) (wallet.Sig, error) {
+	signer, ok := acc.(ethwallet.TypedDataSigner)
+	if !ok {
+		return nil, errors.Errorf("account of type %T cannot sign typed data", acc)
+	}
+	return signer.SignTypedData(d.TypedData(p, s))
+}
+
+// VerifyTypedData verifies that the state was signed as EIP-712 typed data of
+// the domain.
+func (d *EIP712Domain) VerifyTypedData(addr wallet.Address, p *channel.Params, s *channel.State, sig wallet.Sig) (bool, error) {
+	if err := s.Valid(); err != nil {
+		return false, errors.WithMessage(err, "invalid state")
+	}
+	return ethwallet.VerifyTypedDataSignature(d.TypedData(p, s), sig, addr)
+}
+
+func hashStateEIP712(p *adjudicator.ChannelParams, s *adjudicator.ChannelState) common.Hash {
+	return crypto.Keccak256Hash(
+		typeHashState[:],
+		s.ChannelID[:],
+		hashParamsEIP712(p).Bytes(),
+		encodeUint(new(big.Int).SetUint64(s.Version)),
+		hashAllocationEIP712(&s.Outcome).Bytes(),
+		crypto.Keccak256(s.AppData),
+		encodeBool(s.IsFinal),
+	)
+}
+
+func hashParamsEIP712(p *adjudicator.ChannelParams) common.Hash {
+	return crypto.Keccak256Hash(
+		typeHashParams[:],
+		encodeUint(p.ChallengeDuration),
+		encodeUint(p.Nonce),
+		common.LeftPadBytes(p.App[:], 32),
+		hashAddresses(p.Participants),
+	)
+}
+
+func hashAllocationEIP712(a *adjudicator.ChannelAllocation) common.Hash {
+	var bals, lockedBals []*big.Int
+	for _, assetBals := range a.Balances {
+		bals = append(bals, assetBals...)
+	}
+	locked := make([][]byte, len(a.Locked))
+	for i := range a.Locked {
+		locked[i] = a.Locked[i].ID[:]
+		lockedBals = append(lockedBals, a.Locked[i].Balances...)
+	}
+	return crypto.Keccak256Hash(
+		typeHashAllocation[:],
+		hashAddresses(a.Assets),
+		hashUints(bals),
+		crypto.Keccak256(locked...),
+		hashUints(lockedBals),
+	)
+}
+
+func hashAddresses(addrs []common.Address) []byte {
+	enc := make([][]byte, len(addrs))
+	for i, a := range addrs {
+		enc[i] = common.LeftPadBytes(a[:], 32)
+	}
+	return crypto.Keccak256(enc...)
+}
+
+func hashUints(xs []*big.Int) []byte {
+	enc := make([][]byte, len(xs))
+	for i, x := range xs {
+		enc[i] = encodeUint(x)
+	}
+	return crypto.Keccak256(enc...)
+}
+
+func encodeUint(x *big.Int) []byte {
+	return math.U256Bytes(new(big.Int).Set(x))
+}
+
+func encodeBool(b bool) []byte {
+	if b {
+		return encodeUint(big.NewInt(1))
+	}
+	return encodeUint(big.NewInt(0))
+}
@@ -0,0 +1,47 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+// TestEIP712_Reference compares the EIP-712 encoding of states with
+// go-ethereum's implementation, which is used by external signers like Clef.
+func TestEIP712_Reference(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	domain := NewEIP712Domain(big.NewInt(1337), common.Address{1, 2, 3})
+	for i := 0; i < 10; i++ {
+		params, state := test.NewRandomParamsAndState(rng, test.WithNumLocked(rng.Intn(3)))
+		typed := domain.TypedData(params, state)
+
+		sep, err := typed.HashStruct("EIP712Domain", typed.Domain.Map())
+		require.NoError(t, err)
+		assert.Equal(t, []byte(sep), domain.Separator().Bytes())
+
+		enc, err := ethwallet.EncodeTypedData(typed)
+		require.NoError(t, err)
+		assert.Equal(t, enc, domain.EncodeTypedData(params, state))
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/backend/ethereum/bindings/adjudicator"
+	"perun.network/go-perun/backend/ethereum/channel"
+	ethchanneltest "perun.network/go-perun/backend/ethereum/channel/test"
+	"perun.network/go-perun/backend/ethereum/wallet/keystore"
+	perunchannel "perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+	"perun.network/go-perun/wallet"
+	wallettest "perun.network/go-perun/wallet/test"
+)
+
+func TestEIP712_SignVerify(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	acc := wallettest.NewRandomAccount(rng)
+	params, state := test.NewRandomParamsAndState(rng, test.WithNumLocked(2))
+	domain := channel.NewEIP712Domain(big.NewInt(1337), common.Address{1})
+
+	sig, err := domain.SignTypedData(acc, params, state)
+	require.NoError(t, err)
+	ok, err := domain.VerifyTypedData(acc.Address(), params, state, sig)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	other := wallettest.NewRandomAccount(rng)
+	ok, err = domain.VerifyTypedData(other.Address(), params, state, sig)
+	require.NoError(t, err)
+	assert.False(t, ok, "wrong signer")
+
+	for _, d := range []*channel.EIP712Domain{
+		channel.NewEIP712Domain(big.NewInt(1), common.Address{1}),
+		channel.NewEIP712Domain(big.NewInt(1337), common.Address{2}),
+	} {
+		ok, err = d.VerifyTypedData(acc.Address(), params, state, sig)
+		require.NoError(t, err)
+		assert.False(t, ok, "wrong domain")
+	}
+
+	state.Version++
+	ok, err = domain.VerifyTypedData(acc.Address(), params, state, sig)
+	require.NoError(t, err)
+	assert.False(t, ok, "wrong state")
+}
+
+func TestEIP712_OffChain(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	acc := wallettest.NewRandomAccount(rng)
+	params, state := test.NewRandomParamsAndState(rng)
+	d := &channel.Deployment{ChainID: big.NewInt(1337), Adjudicator: common.Address{1}}
+	domain, err := d.EIP712Domain()
+	require.NoError(t, err)
+	assert.Equal(t, channel.NewEIP712Domain(d.ChainID, d.Adjudicator), domain)
+
+	typedSig, err := domain.SignTypedData(acc, params, state)
+	require.NoError(t, err)
+	b := new(channel.Backend)
+	ok, err := b.Verify(acc.Address(), params, state, typedSig)
+	require.NoError(t, err)
+	assert.False(t, ok, "typed-data signature should not be valid on-chain")
+	abiSig, err := b.Sign(acc, params, state)
+	require.NoError(t, err)
+	ok, err = channel.Verify(acc.Address(), params, state, abiSig)
+	require.NoError(t, err)
+	assert.True(t, ok, "backend should sign ABI-encoded states")
+
+	_, err = (&channel.Deployment{Adjudicator: d.Adjudicator}).EIP712Domain()
+	assert.Error(t, err, "deployment without chain ID")
+}
+
+// TestEIP712_RejectedOnChain shows that the Adjudicator contract does not
+// accept typed-data signatures, which is why the backend cannot sign states
+// as typed data.
+func TestEIP712_RejectedOnChain(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTxTimeout)
+	defer cancel()
+
+	s := ethchanneltest.NewSimSetup(rng)
+	adjAddr, err := channel.DeployAdjudicator(ctx, *s.CB, s.TxSender.Account)
+	require.NoError(t, err)
+	adj := channel.NewAdjudicator(*s.CB, adjAddr, common.Address{}, s.TxSender.Account)
+	domain, err := (&channel.Deployment{ChainID: big.NewInt(1337), Adjudicator: adjAddr}).EIP712Domain()
+	require.NoError(t, err)
+
+	ks := wallettest.RandomWallet().(*keystore.Wallet)
+	accs := []wallet.Account{ks.NewRandomAccount(rng), ks.NewRandomAccount(rng)}
+	params, state := test.NewRandomParamsAndState(rng,
+		test.WithChallengeDuration(uint64(100*time.Second)),
+		test.WithParts(accs[0].Address(), accs[1].Address()),
+		test.WithIsFinal(false))
+	register := func(sign func(wallet.Account) (wallet.Sig, error)) error {
+		tx := perunchannel.Transaction{State: state, Sigs: make([]wallet.Sig, len(accs))}
+		for i, acc := range accs {
+			sig, err := sign(acc)
+			require.NoError(t, err)
+			tx.Sigs[i] = sig
+		}
+		return adj.Register(ctx, perunchannel.AdjudicatorReq{Params: params, Acc: accs[0], Tx: tx})
+	}
+
+	// Register does not fail on reverted transactions, so the dispute is
+	// read from the contract.
+	contract, err := adjudicator.NewAdjudicatorCaller(adjAddr, *s.CB)
+	require.NoError(t, err)
+	registered := func() bool {
+		dispute, err := contract.Disputes(&bind.CallOpts{Context: ctx}, params.ID())
+		require.NoError(t, err)
+		return dispute.Timeout != 0
+	}
+
+	// nolint:errcheck,gosec
+	register(func(acc wallet.Account) (wallet.Sig, error) {
+		return domain.SignTypedData(acc, params, state)
+	})
+	assert.False(t, registered(), "typed-data signatures should be rejected")
+	require.NoError(t, register(func(acc wallet.Account) (wallet.Sig, error) {
+		return perunchannel.Sign(acc, params, state)
+	}))
+	assert.True(t, registered(), "ABI-encoded signatures should be accepted")
+}
@@ -71,13 +71,20 @@ func NewFunder(backend ContractBackend, accounts map[Asset]accounts.Account, dep
 // If funding on a real blockchain, make sure that the passed context doesn't
 // cancel before the funding period of length ChallengeDuration elapses, or
 // funding will be canceled prematurely.
+//
+// If the channel's event cursor is not set yet, it is set to the current block,
+// see CursorStore.
 func (f *Funder) Fund(ctx context.Context, request channel.FundingReq) error {
 	var channelID = request.Params.ID()
 	f.log.WithField("channel", channelID).Debug("Funding Channel.")
 
+	if err := f.initCursor(ctx, channelID); err != nil {
+		return errors.WithMessage(err, "initializing event cursor")
+	}
+
 	// We wait for the funding timeout in a go routine and cancel the funding
 	// context if the timeout elapses.
-	timeout, err := NewBlockTimeoutDuration(ctx, f.ContractInterface, request.Params.ChallengeDuration)
+	timeout, err := NewBlockTimeoutDuration(ctx, f.headReader(), request.Params.ChallengeDuration)
 	if err != nil {
 		return errors.WithMessage(err, "creating block timeout")
 	}
@@ -258,36 +265,27 @@ func (f *Funder) waitForFundingConfirmation(ctx context.Context, request channel
 		}
 	}()
 
-	// The allocation that all participants agreed on.
-	agreement := request.Agreement.Clone()[asset.assetIndex]
-	// Count how many zero balance funding requests are there
-	N := len(request.Params.Parts) - countZeroBalances(agreement)
+	// Only count deposits once they are buried deep enough in the chain.
+	conf, err := newEventConfirmer(ctx, &f.ContractBackend)
+	if err != nil {
+		return errors.WithMessage(err, "creating event confirmer")
+	}
+	defer conf.Close()
+
+	// The amounts that are still missing from the agreed allocation.
+	remaining := request.Agreement.Clone()[asset.assetIndex]
 
 	// Wait for all non-zero funding requests
-	for N > 0 {
+	for N := countPositiveBalances(remaining); N > 0; N = countPositiveBalances(remaining) {
+		var events []confirmedEvent
 		select {
 		case event := <-deposited:
-			log := f.log.WithField("fundingID", event.FundingID)
-
-			// Calculate the position in the participant array.
-			idx := getPartIdx(event.FundingID, fundingIDs)
-
-			amount := agreement[idx]
-			if amount.Sign() == 0 {
-				continue // ignore double events
-			}
-
-			amount.Sub(amount, event.Amount)
-			if amount.Sign() != 1 {
-				// participant funded successfully
-				N--
-				agreement[idx].SetUint64(0)
-			}
-			log.Debugf("peer[%d]: got: %v, remaining for [%d,%d] = %v. N: %d", request.Idx, event.Amount, asset.assetIndex, idx, amount, N)
-
+			events, err = conf.Add(ctx, event, event.Raw)
+		case head := <-conf.Heads():
+			events, err = conf.NewHead(ctx, head)
 		case <-ctx.Done():
 			var indices []channel.Index
-			for k, bals := range agreement {
+			for k, bals := range remaining {
 				if bals.Sign() == 1 {
 					indices = append(indices, channel.Index(k))
 				}
@@ -298,6 +296,29 @@ func (f *Funder) waitForFundingConfirmation(ctx context.Context, request channel
 			return nil
 		case err := <-errChan:
 			return err
+		case err := <-conf.Err():
+			return errors.Wrap(err, "header subscription")
+		}
+		if err != nil {
+			return errors.WithMessage(err, "confirming Deposited events")
+		}
+
+		for _, e := range events {
+			event := e.Event.(*assetholder.AssetHolderDeposited)
+			log := f.log.WithField("fundingID", event.FundingID)
+
+			// Calculate the position in the participant array.
+			idx := getPartIdx(event.FundingID, fundingIDs)
+
+			amount := remaining[idx]
+			if e.Removed {
+				// A reorg removed the deposit, so it is missing again.
+				amount.Add(amount, event.Amount)
+				log.Warnf("peer[%d]: deposit of %v for [%d,%d] was removed from the chain", request.Idx, event.Amount, asset.assetIndex, idx)
+			} else {
+				amount.Sub(amount, event.Amount)
+			}
+			log.Debugf("peer[%d]: got: %v, remaining for [%d,%d] = %v", request.Idx, event.Amount, asset.assetIndex, idx, amount)
 		}
 	}
 	return nil
@@ -312,9 +333,9 @@ func getPartIdx(partID [32]byte, fundingIDs [][32]byte) int {
 	return -1
 }
 
-func countZeroBalances(bals []channel.Bal) (n int) {
+func countPositiveBalances(bals []channel.Bal) (n int) {
 	for _, part := range bals {
-		if part.Sign() == 0 {
+		if part.Sign() == 1 {
 			n++
 		}
 	}
@@ -0,0 +1,224 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel
+
+import (
+	"context"
+	stderrors "errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/log"
+)
+
+const (
+	// DefaultGasLimitMargin is the default percentage that is added to the
+	// estimated gas of a transaction.
+	DefaultGasLimitMargin = 20
+	// DefaultGasPriceBump is the default percentage by which the gas price of
+	// a stuck transaction is increased. Nodes require at least 10% to replace
+	// a pending transaction.
+	DefaultGasPriceBump = 20
+	// DefaultStuckBlocks is the default number of blocks after which a
+	// transaction that was not included is re-sent with a bumped gas price.
+	DefaultStuckBlocks = 10
+)
+
+type (
+	// A GasStrategy determines the gas limit and gas price of transactions
+	// that are sent by a ContractBackend and whether stuck transactions are
+	// re-sent.
+	GasStrategy interface {
+		// GasLimit returns the gas limit for a transaction whose gas usage
+		// was estimated to be estimated.
+		GasLimit(estimated uint64) uint64
+
+		// GasPrice returns the gas price for a new transaction.
+		GasPrice(ctx context.Context) (*big.Int, error)
+
+		// BumpGasPrice returns the gas price for re-sending a stuck
+		// transaction that was sent with the given price.
+		BumpGasPrice(price *big.Int) *big.Int
+
+		// StuckBlocks returns the number of blocks after which a transaction
+		// that was not included is considered stuck and re-sent with the same
+		// nonce and a bumped gas price. Zero disables re-sending.
+		StuckBlocks() uint64
+	}
+
+	// A GasPriceOracle suggests gas prices. Every ContractInterface is a
+	// GasPriceOracle that asks the connected node.
+	GasPriceOracle interface {
+		SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	}
+
+	// FixedGasPrice is a GasPriceOracle that always suggests the same price.
+	FixedGasPrice big.Int
+
+	// EstimatingGasStrategy is a GasStrategy that adds a margin to the
+	// estimated gas usage of transactions and takes the gas price from an
+	// oracle.
+	EstimatingGasStrategy struct {
+		// Oracle suggests the gas price of new transactions.
+		Oracle GasPriceOracle
+		// LimitMargin is the percentage that is added to the estimated gas.
+		LimitMargin uint64
+		// PriceBump is the percentage by which the gas price of stuck
+		// transactions is increased.
+		PriceBump uint64
+		// MaxGasPrice caps the gas price, also of re-sent transactions. It
+		// is not capped if nil.
+		MaxGasPrice *big.Int
+		// Stuck is the number of blocks after which a transaction is
+		// re-sent, see GasStrategy.StuckBlocks.
+		Stuck uint64
+	}
+)
+
+// SuggestGasPrice returns the fixed gas price.
+func (p *FixedGasPrice) SuggestGasPrice(context.Context) (*big.Int, error) {
+	return new(big.Int).Set((*big.Int)(p)), nil
+}
+
+// NewEstimatingGasStrategy returns a new EstimatingGasStrategy that uses the
+// given oracle and the default margin, price bump and stuck blocks.
+func NewEstimatingGasStrategy(oracle GasPriceOracle) *EstimatingGasStrategy {
+	return &EstimatingGasStrategy{
+		Oracle:      oracle,
+		LimitMargin: DefaultGasLimitMargin,
+		PriceBump:   DefaultGasPriceBump,
+		Stuck:       DefaultStuckBlocks,
+	}
+}
+
+// GasLimit returns the estimated gas plus LimitMargin percent.
+func (s *EstimatingGasStrategy) GasLimit(estimated uint64) uint64 {
+	return estimated + estimated*s.LimitMargin/100
+}
+
+// GasPrice returns the price suggested by the Oracle, capped by MaxGasPrice.
+func (s *EstimatingGasStrategy) GasPrice(ctx context.Context) (*big.Int, error) {
+	price, err := s.Oracle.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "suggesting gas price")
+	}
+	return s.capPrice(price), nil
+}
+
+// BumpGasPrice returns the price increased by PriceBump percent, but at least
+// by one, capped by MaxGasPrice.
+func (s *EstimatingGasStrategy) BumpGasPrice(price *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(price, new(big.Int).SetUint64(100+s.PriceBump))
+	bumped.Div(bumped, big.NewInt(100))
+	if bumped.Cmp(price) <= 0 {
+		bumped.Add(price, big.NewInt(1))
+	}
+	return s.capPrice(bumped)
+}
+
+// StuckBlocks returns Stuck.
+func (s *EstimatingGasStrategy) StuckBlocks() uint64 {
+	return s.Stuck
+}
+
+func (s *EstimatingGasStrategy) capPrice(price *big.Int) *big.Int {
+	if s.MaxGasPrice != nil && price.Cmp(s.MaxGasPrice) > 0 {
+		return new(big.Int).Set(s.MaxGasPrice)
+	}
+	return price
+}
+
+// waitMined waits for the transaction to be mined and returns its receipt. If
+// the GasStrategy considers the transaction stuck, it is re-sent with the same
+// nonce and a bumped gas price. The transaction that was mined is returned
+// together with its receipt.
+func (c *ContractBackend) waitMined(ctx context.Context, tx *types.Transaction, acc accounts.Account) (*types.Transaction, *types.Receipt, error) {
+	if c.gas == nil || c.gas.StuckBlocks() == 0 {
+		receipt, err := bind.WaitMined(ctx, c, tx)
+		return tx, receipt, errors.Wrap(err, "waiting for TX to be mined")
+	}
+
+	heads := make(chan *types.Header)
+	sub, err := c.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "subscribing to new blocks")
+	}
+	defer sub.Unsubscribe()
+
+	head, err := c.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "retrieving latest block")
+	}
+	sentAt := head.Number.Uint64()
+	// Any of the sent transactions can be mined.
+	sent := []*types.Transaction{tx}
+	for {
+		for _, t := range sent {
+			receipt, err := c.TransactionReceipt(ctx, t.Hash())
+			if err != nil && !stderrors.Is(err, ethereum.NotFound) {
+				return nil, nil, errors.Wrap(err, "retrieving receipt")
+			} else if receipt != nil {
+				return t, receipt, nil
+			}
+		}
+
+		if head.Number.Uint64() >= sentAt+c.gas.StuckBlocks() {
+			last := sent[len(sent)-1]
+			if bumped, err := c.resend(ctx, last, acc); err != nil {
+				// One of the transactions might have been mined in the meantime.
+				log.WithField("tx", last.Hash()).Warn("Could not re-send stuck TX: ", err)
+			} else {
+				log.WithFields(log.Fields{"tx": last.Hash(), "new": bumped.Hash(), "gasPrice": bumped.GasPrice()}).Info("Re-sent stuck TX")
+				sent = append(sent, bumped)
+			}
+			sentAt = head.Number.Uint64()
+		}
+
+		select {
+		case head = <-heads:
+		case <-ctx.Done():
+			return nil, nil, errors.Wrap(ctx.Err(), "context cancelled")
+		case err := <-sub.Err():
+			return nil, nil, errors.Wrap(err, "header subscription")
+		}
+	}
+}
+
+// resend re-sends the transaction with the same nonce and a bumped gas price.
+func (c *ContractBackend) resend(ctx context.Context, tx *types.Transaction, acc accounts.Account) (*types.Transaction, error) {
+	auth, err := c.tr.NewTransactor(acc)
+	if err != nil {
+		return nil, errors.WithMessage(err, "creating transactor")
+	}
+	bumped, err := auth.Signer(acc.Address, copyTx(tx, tx.Nonce(), tx.Gas(), c.gas.BumpGasPrice(tx.GasPrice())))
+	if err != nil {
+		return nil, errors.Wrap(err, "signing transaction")
+	}
+	return bumped, errors.Wrap(c.SendTransaction(ctx, bumped), "sending transaction")
+}
+
+// copyTx returns an unsigned copy of the transaction with the given nonce, gas
+// limit and gas price.
+func copyTx(tx *types.Transaction, nonce, gasLimit uint64, gasPrice *big.Int) *types.Transaction {
+	if tx.To() == nil {
+		return types.NewContractCreation(nonce, tx.Value(), gasLimit, gasPrice, tx.Data())
+	}
+	return types.NewTransaction(nonce, *tx.To(), tx.Value(), gasLimit, gasPrice, tx.Data())
+}
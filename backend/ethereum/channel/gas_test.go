@@ -0,0 +1,137 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ethchannel "perun.network/go-perun/backend/ethereum/channel"
+	"perun.network/go-perun/backend/ethereum/channel/test"
+	"perun.network/go-perun/backend/ethereum/wallet/keystore"
+	pkgtest "perun.network/go-perun/pkg/test"
+	wallettest "perun.network/go-perun/wallet/test"
+)
+
+func TestEstimatingGasStrategy(t *testing.T) {
+	ctx := context.Background()
+	gs := ethchannel.NewEstimatingGasStrategy((*ethchannel.FixedGasPrice)(big.NewInt(100)))
+
+	assert.Equal(t, uint64(120000), gs.GasLimit(100000))
+	price, err := gs.GasPrice(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(100), price)
+	assert.Equal(t, big.NewInt(120), gs.BumpGasPrice(price))
+	assert.Equal(t, big.NewInt(2), gs.BumpGasPrice(big.NewInt(1)), "bump by at least one")
+	assert.Equal(t, uint64(ethchannel.DefaultStuckBlocks), gs.StuckBlocks())
+
+	gs.MaxGasPrice = big.NewInt(110)
+	assert.Equal(t, big.NewInt(110), gs.BumpGasPrice(price))
+	gs.Oracle = (*ethchannel.FixedGasPrice)(big.NewInt(200))
+	price, err = gs.GasPrice(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(110), price)
+}
+
+func TestGasStrategy_Estimate(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	sb, tr, acc := newTxSetup(rng)
+	cb := ethchannel.NewContractBackend(sb, tr)
+	gs := ethchannel.NewEstimatingGasStrategy((*ethchannel.FixedGasPrice)(big.NewInt(3)))
+	cb.SetGasStrategy(gs)
+	assert.Equal(t, gs, cb.GasStrategy())
+
+	_, err := ethchannel.DeployAdjudicator(ctx, cb, acc)
+	require.NoError(t, err)
+
+	block, err := sb.BlockByNumber(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, block.Transactions(), 1)
+	tx := block.Transactions()[0]
+	receipt, err := sb.TransactionReceipt(ctx, tx.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(3), tx.GasPrice())
+	assert.GreaterOrEqual(t, tx.Gas(), gs.GasLimit(receipt.GasUsed))
+	assert.Less(t, tx.Gas(), gs.GasLimit(gs.GasLimit(receipt.GasUsed)))
+}
+
+func TestGasStrategy_ResendStuck(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	sb, tr, acc := newTxSetup(rng)
+	cb := ethchannel.NewContractBackend(sb, tr)
+	gs := ethchannel.NewEstimatingGasStrategy((*ethchannel.FixedGasPrice)(big.NewInt(1)))
+	gs.Stuck = 2
+	cb.SetGasStrategy(gs)
+
+	opts, err := cb.NewTransactor(ctx, 0, acc)
+	require.NoError(t, err)
+	nonce, err := sb.PendingNonceAt(ctx, acc.Address)
+	require.NoError(t, err)
+	tx, err := opts.Signer(acc.Address,
+		types.NewTransaction(nonce, common.Address{1}, big.NewInt(1), ethchannel.GasLimit, opts.GasPrice, nil))
+	require.NoError(t, err)
+	// Send the transaction without mining it and drop it afterwards.
+	require.NoError(t, sb.SimulatedBackend.SendTransaction(ctx, tx))
+	sb.Rollback()
+
+	confirmed := make(chan *types.Receipt, 1)
+	go func() {
+		receipt, err := cb.ConfirmTransaction(ctx, tx, acc)
+		assert.NoError(t, err)
+		confirmed <- receipt
+	}()
+
+	for {
+		select {
+		case receipt := <-confirmed:
+			require.NotNil(t, receipt)
+			assert.NotEqual(t, tx.Hash(), receipt.TxHash)
+			block, err := sb.BlockByHash(ctx, receipt.BlockHash)
+			require.NoError(t, err)
+			resent := block.Transaction(receipt.TxHash)
+			require.NotNil(t, resent)
+			assert.Equal(t, tx.Nonce(), resent.Nonce())
+			assert.Equal(t, gs.BumpGasPrice(tx.GasPrice()), resent.GasPrice())
+			return
+		case <-ctx.Done():
+			t.Fatal("transaction not re-sent")
+		case <-time.After(50 * time.Millisecond):
+			sb.Commit()
+		}
+	}
+}
+
+// newTxSetup returns a simulated backend, a Transactor and a funded account
+// of the Transactor.
+func newTxSetup(rng *rand.Rand) (*test.SimulatedBackend, ethchannel.Transactor, accounts.Account) {
+	sb := test.NewSimulatedBackend()
+	ksWallet := wallettest.RandomWallet().(*keystore.Wallet)
+	acc := ksWallet.NewRandomAccount(rng).(*keystore.Account).Account
+	sb.FundAddress(context.Background(), acc.Address)
+	return sb, keystore.NewTransactor(*ksWallet, types.NewEIP155Signer(big.NewInt(1337))), acc
+}
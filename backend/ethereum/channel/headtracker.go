@@ -0,0 +1,247 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/log"
+)
+
+// DefaultHeadReconnectDelay is the default delay after which a
+// ChainHeadTracker resubscribes to new chain heads after its subscription
+// failed.
+const DefaultHeadReconnectDelay = time.Second
+
+type (
+	// ChainHeadTracker is a ChainReader that shares a single subscription to
+	// new chain heads between all of its subscribers. While it has
+	// subscribers, it caches the latest header and serves it from
+	// HeaderByNumber. If the underlying subscription fails, it resubscribes
+	// after ReconnectDelay without notifying its subscribers.
+	//
+	// Subscribers that do not keep up with new heads only receive the latest
+	// head, so the headers they receive might skip blocks.
+	ChainHeadTracker struct {
+		ethereum.ChainReader
+		// ReconnectDelay is the delay after which the tracker resubscribes
+		// after its subscription failed.
+		ReconnectDelay time.Duration
+
+		mu   sync.Mutex
+		head *types.Header // latest header, nil if unknown
+		subs map[*headSub]struct{}
+		stop context.CancelFunc // stops tracking, nil if not tracking
+	}
+
+	// headSub is a subscription to a ChainHeadTracker.
+	headSub struct {
+		t      *ChainHeadTracker
+		latest chan *types.Header // holds the latest undelivered head
+		err    chan error
+		quit   chan struct{}
+		once   sync.Once
+	}
+)
+
+// NewChainHeadTracker returns a new ChainHeadTracker that tracks the chain
+// head of the given ChainReader.
+func NewChainHeadTracker(cr ethereum.ChainReader) *ChainHeadTracker {
+	return &ChainHeadTracker{
+		ChainReader:    cr,
+		ReconnectDelay: DefaultHeadReconnectDelay,
+		subs:           make(map[*headSub]struct{}),
+	}
+}
+
+// SubscribeNewHead subscribes to new chain heads. The first subscriber starts
+// the shared subscription, which is stopped again when the last subscriber
+// unsubscribes. The latest known head is sent to new subscribers right away.
+//
+// The shared subscription is bound to the lifetime of the tracking and not to
+// the context of any subscriber, so the context is not used.
+func (t *ChainHeadTracker) SubscribeNewHead(_ context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stop == nil {
+		trackCtx, stop := context.WithCancel(context.Background())
+		heads := make(chan *types.Header)
+		sub, err := t.ChainReader.SubscribeNewHead(trackCtx, heads)
+		if err != nil {
+			stop()
+			return nil, errors.Wrap(err, "subscribing to new heads")
+		}
+		t.stop = stop
+		go t.track(trackCtx, sub, heads)
+	}
+
+	s := &headSub{
+		t:      t,
+		latest: make(chan *types.Header, 1),
+		err:    make(chan error),
+		quit:   make(chan struct{}),
+	}
+	t.subs[s] = struct{}{}
+	if t.head != nil {
+		s.deliver(t.head)
+	}
+	go s.forward(ch)
+	return s, nil
+}
+
+// HeaderByNumber returns the header with the given number. If number is nil
+// and the latest header is known from the shared subscription, the latest
+// header is returned without asking the node.
+func (t *ChainHeadTracker) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		t.mu.Lock()
+		head := t.head
+		t.mu.Unlock()
+		if head != nil {
+			return head, nil
+		}
+	}
+	return t.ChainReader.HeaderByNumber(ctx, number)
+}
+
+// track forwards new heads from the given subscription to all subscribers and
+// resubscribes if the subscription fails, until ctx is done.
+func (t *ChainHeadTracker) track(ctx context.Context, sub ethereum.Subscription, heads chan *types.Header) {
+	for {
+		err := t.forwardHeads(ctx, sub, heads)
+		sub.Unsubscribe()
+		t.setHead(ctx, nil)
+		for ctx.Err() == nil {
+			log.Warnf("ChainHeadTracker: head subscription failed, resubscribing: %v", err)
+			select {
+			case <-time.After(t.ReconnectDelay):
+			case <-ctx.Done():
+				return
+			}
+			heads = make(chan *types.Header)
+			if sub, err = t.ChainReader.SubscribeNewHead(ctx, heads); err == nil {
+				break
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// forwardHeads forwards new heads from the given subscription to all
+// subscribers until the subscription fails or ctx is done. Initially, it
+// fetches the latest head, so that no head is missed between subscribing and
+// receiving the first head.
+func (t *ChainHeadTracker) forwardHeads(ctx context.Context, sub ethereum.Subscription, heads chan *types.Header) error {
+	head, err := t.ChainReader.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "retrieving latest block")
+	}
+	t.setHead(ctx, head)
+
+	for {
+		select {
+		case head := <-heads:
+			t.setHead(ctx, head)
+		case err := <-sub.Err():
+			if err == nil {
+				err = errors.New("subscription closed")
+			}
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// setHead caches the given head and delivers it to all subscribers, unless
+// it is nil. Nothing is done if ctx is done, i.e., the tracking that set the
+// head was stopped.
+func (t *ChainHeadTracker) setHead(ctx context.Context, head *types.Header) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+	t.head = head
+	if head == nil {
+		return
+	}
+	for s := range t.subs {
+		s.deliver(head)
+	}
+}
+
+// remove removes the subscription and stops tracking if it was the last one.
+func (t *ChainHeadTracker) remove(s *headSub) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.subs, s)
+	if len(t.subs) == 0 && t.stop != nil {
+		t.stop()
+		t.stop = nil
+		t.head = nil
+	}
+}
+
+// deliver replaces the undelivered head of the subscription by the given one.
+// It is only called with the tracker's mutex held.
+func (s *headSub) deliver(head *types.Header) {
+	select {
+	case <-s.latest:
+	default:
+	}
+	s.latest <- head
+}
+
+// forward sends the delivered heads to ch until the subscription is closed.
+func (s *headSub) forward(ch chan<- *types.Header) {
+	for {
+		select {
+		case head := <-s.latest:
+			select {
+			case ch <- head:
+			case <-s.quit:
+				return
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// Unsubscribe stops the delivery of new heads and closes the error channel.
+func (s *headSub) Unsubscribe() {
+	s.once.Do(func() {
+		close(s.quit)
+		s.t.remove(s)
+		close(s.err)
+	})
+}
+
+// Err returns the error channel of the subscription. As the ChainHeadTracker
+// resubscribes on failures, it is only closed on Unsubscribe.
+func (s *headSub) Err() <-chan error {
+	return s.err
+}
@@ -0,0 +1,183 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ethchannel "perun.network/go-perun/backend/ethereum/channel"
+)
+
+// headChain is a ChainReader that only supports head subscriptions and
+// retrieving the latest header. It counts the calls to both.
+type headChain struct {
+	ethereum.ChainReader
+
+	mu          sync.Mutex
+	head        *types.Header
+	subscribes  int
+	headerCalls int
+	feed        event.Feed
+	fail        chan error // fails one subscription
+}
+
+func newHeadChain() *headChain {
+	return &headChain{
+		head: &types.Header{Number: big.NewInt(0)},
+		fail: make(chan error),
+	}
+}
+
+// SubscribeNewHead subscribes to new heads. The subscription fails when ctx is
+// done.
+func (c *headChain) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscribes++
+	inner := c.feed.Subscribe(ch)
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer inner.Unsubscribe()
+		select {
+		case <-quit:
+			return nil
+		case err := <-c.fail:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}), nil
+}
+
+func (c *headChain) HeaderByNumber(context.Context, *big.Int) (*types.Header, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.headerCalls++
+	return c.head, nil
+}
+
+// mine appends a block with the given timestamp.
+func (c *headChain) mine(time uint64) {
+	c.mu.Lock()
+	c.head = &types.Header{Number: new(big.Int).Add(c.head.Number, big.NewInt(1)), Time: time}
+	head := c.head
+	c.mu.Unlock()
+	c.feed.Send(head)
+}
+
+func (c *headChain) counts() (subscribes, headerCalls int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.subscribes, c.headerCalls
+}
+
+// awaitHead waits until a head with the given number is received.
+func awaitHead(t *testing.T, heads <-chan *types.Header, number int64) {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case h := <-heads:
+			if h.Number.Int64() == number {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("head %d not received", number)
+		}
+	}
+}
+
+func TestChainHeadTracker(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	chain := newHeadChain()
+	tracker := ethchannel.NewChainHeadTracker(chain)
+	tracker.ReconnectDelay = 10 * time.Millisecond
+
+	var heads [3]chan *types.Header
+	var subs [3]ethereum.Subscription
+	for i := range heads {
+		heads[i] = make(chan *types.Header)
+		var err error
+		subCtx, subCancel := context.WithCancel(ctx)
+		subs[i], err = tracker.SubscribeNewHead(subCtx, heads[i])
+		require.NoError(t, err)
+		// The shared subscription must outlive the subscribing context.
+		subCancel()
+	}
+	for i := range heads {
+		awaitHead(t, heads[i], 0)
+	}
+	subscribes, _ := chain.counts()
+	assert.Equal(t, 1, subscribes, "subscription should be shared")
+
+	t.Run("fan out", func(t *testing.T) {
+		chain.mine(10)
+		for i := range heads {
+			awaitHead(t, heads[i], 1)
+		}
+		time.Sleep(3 * tracker.ReconnectDelay)
+		subscribes, _ := chain.counts()
+		assert.Equal(t, 1, subscribes, "subscription should not fail with the subscribing context")
+	})
+
+	t.Run("cached head", func(t *testing.T) {
+		_, calls := chain.counts()
+		timeout := ethchannel.NewBlockTimeout(tracker, 20)
+		assert.False(t, timeout.IsElapsed(ctx))
+		chain.mine(20)
+		awaitHead(t, heads[0], 2)
+		assert.True(t, timeout.IsElapsed(ctx))
+		_, after := chain.counts()
+		assert.Equal(t, calls, after, "latest header should be cached")
+	})
+
+	t.Run("reconnect", func(t *testing.T) {
+		chain.fail <- errors.New("node hiccup")
+		for {
+			if subscribes, _ := chain.counts(); subscribes == 2 {
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		chain.mine(30)
+		for i := range heads {
+			awaitHead(t, heads[i], 3)
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		for _, sub := range subs {
+			sub.Unsubscribe()
+			_, ok := <-sub.Err()
+			assert.False(t, ok, "error channel should be closed")
+		}
+		_, calls := chain.counts()
+		_, err := tracker.HeaderByNumber(ctx, nil)
+		require.NoError(t, err)
+		_, after := chain.counts()
+		assert.Equal(t, calls+1, after, "latest header should not be cached without subscribers")
+	})
+}
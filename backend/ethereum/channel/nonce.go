@@ -0,0 +1,178 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+type (
+	// NonceReader reads the pending nonce of an account. Every
+	// ContractInterface is a NonceReader.
+	NonceReader interface {
+		PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	}
+
+	// NonceManager hands out sequential nonces per account, so that
+	// transactions that are created concurrently from the same account do not
+	// collide. It is shared by all copies of a ContractBackend and thereby by
+	// the Funder, Adjudicator and deploy helpers that use it.
+	//
+	// A nonce is in flight from signing a transaction until it is sent. When
+	// no nonce of an account is in flight, the NonceManager resynchronizes
+	// with the pending nonce of the node. This way, nonces of transactions
+	// that could not be sent or were dropped from the transaction pool are
+	// reused and transactions that were sent from the account by others are
+	// skipped.
+	NonceManager struct {
+		mu       sync.Mutex // mu protects accounts and signed.
+		nr       NonceReader
+		accounts map[common.Address]*accountNonces
+		signed   map[common.Hash]*accountNonces // in-flight transactions
+	}
+
+	// accountNonces are the nonces of a single account. No lock is held while
+	// the pending nonce is read or a transaction is signed, so that slow
+	// nodes or signers, like a Clef waiting for user approval, do not block
+	// other accounts or transactions.
+	accountNonces struct {
+		mu       sync.Mutex
+		next     uint64
+		inFlight int
+		epoch    uint64 // incremented whenever a nonce is reserved or released
+	}
+)
+
+// NewNonceManager returns a new NonceManager that reads the pending nonces
+// from the given NonceReader.
+func NewNonceManager(nr NonceReader) *NonceManager {
+	return &NonceManager{
+		nr:       nr,
+		accounts: make(map[common.Address]*accountNonces),
+		signed:   make(map[common.Hash]*accountNonces),
+	}
+}
+
+// Reset forgets the in-flight nonces of the given account, so that the next
+// nonce is read from the node again. Use it if transactions that were signed
+// with a transactor of a ContractBackend were not sent with its
+// SendTransaction method.
+func (m *NonceManager) Reset(addr common.Address) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	acc := m.accounts[addr]
+	delete(m.accounts, addr)
+	for tx, a := range m.signed {
+		if a == acc {
+			delete(m.signed, tx)
+		}
+	}
+}
+
+// signer returns a signer that sets the nonce of the transaction before
+// passing it to the given signer.
+func (m *NonceManager) signer(ctx context.Context, signer bind.SignerFn) bind.SignerFn {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		acc := m.account(addr)
+		nonce, err := acc.reserve(ctx, m.nr, addr)
+		if err != nil {
+			return nil, err
+		}
+		signed, err := signer(addr, copyTx(tx, nonce, tx.Gas(), tx.GasPrice()))
+		if err != nil {
+			acc.release(nonce)
+			return nil, err
+		}
+		m.mu.Lock()
+		m.signed[signed.Hash()] = acc
+		m.mu.Unlock()
+		return signed, nil
+	}
+}
+
+// account returns the nonces of the given account.
+func (m *NonceManager) account(addr common.Address) *accountNonces {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	acc, ok := m.accounts[addr]
+	if !ok {
+		acc = new(accountNonces)
+		m.accounts[addr] = acc
+	}
+	return acc
+}
+
+// reserve reads the pending nonce of the account and reserves the next nonce.
+func (a *accountNonces) reserve(ctx context.Context, nr NonceReader, addr common.Address) (uint64, error) {
+	a.mu.Lock()
+	epoch, idle := a.epoch, a.inFlight == 0
+	a.mu.Unlock()
+
+	pending, err := nr.PendingNonceAt(ctx, addr)
+	if err != nil {
+		return 0, errors.Wrap(err, "reading pending nonce")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// A lower pending nonce means that a transaction was not sent or dropped.
+	// It is only trusted if no nonce was in flight while it was read, because
+	// the transactions of in-flight nonces might not have reached the node yet.
+	if idle && a.epoch == epoch || pending > a.next {
+		a.next = pending
+	}
+	nonce := a.next
+	a.next++
+	a.inFlight++
+	a.epoch++
+	return nonce, nil
+}
+
+// release returns a reserved nonce whose transaction could not be signed. If
+// it is the latest reserved nonce, it is handed out again next.
+func (a *accountNonces) release(nonce uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inFlight--
+	a.epoch++
+	if a.next == nonce+1 {
+		a.next = nonce
+	}
+}
+
+// sent marks the nonce of the given transaction as not in flight anymore.
+// Transactions that were not signed by the NonceManager are ignored.
+func (m *NonceManager) sent(tx common.Hash) {
+	m.mu.Lock()
+	acc, ok := m.signed[tx]
+	delete(m.signed, tx)
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	acc.mu.Lock()
+	acc.inFlight--
+	acc.mu.Unlock()
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingNonceReader returns a pending nonce of zero. Reads for the account
+// block are blocked until unblock is closed.
+type blockingNonceReader struct {
+	block   common.Address
+	unblock chan struct{}
+}
+
+func (r *blockingNonceReader) PendingNonceAt(_ context.Context, acc common.Address) (uint64, error) {
+	if acc == r.block {
+		<-r.unblock
+	}
+	return 0, nil
+}
+
+func TestNonceManager_NoBlocking(t *testing.T) {
+	a, b, c := common.Address{1}, common.Address{2}, common.Address{3}
+	nr := &blockingNonceReader{block: c, unblock: make(chan struct{})}
+	m := NewNonceManager(nr)
+
+	// The first transaction of a waits for approval by the signer.
+	approve, signing := make(chan struct{}), make(chan struct{})
+	var first bool
+	sign := m.signer(context.Background(), func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		if addr == a && !first {
+			first = true
+			close(signing)
+			<-approve
+		}
+		return tx, nil
+	})
+	newTx := func() *types.Transaction {
+		return types.NewTransaction(0, common.Address{}, big.NewInt(1), GasLimit, big.NewInt(1), nil)
+	}
+
+	firstNonce := make(chan uint64, 1)
+	go func() {
+		tx, err := sign(a, newTx())
+		assert.NoError(t, err)
+		firstNonce <- tx.Nonce()
+	}()
+	<-signing
+	go sign(c, newTx()) // nolint:errcheck
+
+	// Neither the waiting signer nor the blocked nonce read block other
+	// transactions.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tx, err := sign(a, newTx())
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), tx.Nonce(), "in-flight nonce should not be reused")
+		tx, err = sign(b, newTx())
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(0), tx.Nonce())
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("signing blocked by other signer or nonce read")
+	}
+
+	close(approve)
+	assert.Equal(t, uint64(0), <-firstNonce)
+	close(nr.unblock)
+}
+
+func TestNonceManager_Release(t *testing.T) {
+	a := common.Address{1}
+	m := NewNonceManager(&blockingNonceReader{})
+	fail := true
+	sign := m.signer(context.Background(), func(_ common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		if fail {
+			return nil, errors.New("rejected")
+		}
+		return tx, nil
+	})
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(1), GasLimit, big.NewInt(1), nil)
+
+	_, err := sign(a, tx)
+	require.Error(t, err)
+	fail = false
+	signed, err := sign(a, tx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), signed.Nonce(), "nonce of rejected TX should be reused")
+	signed, err = sign(a, tx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), signed.Nonce())
+}
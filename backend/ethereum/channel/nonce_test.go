@@ -0,0 +1,161 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ethchannel "perun.network/go-perun/backend/ethereum/channel"
+	"perun.network/go-perun/backend/ethereum/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+// droppingBackend drops transactions instead of sending them if drop is set.
+type droppingBackend struct {
+	*test.SimulatedBackend
+	drop bool
+}
+
+func (b *droppingBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if b.drop {
+		return nil
+	}
+	return b.SimulatedBackend.SendTransaction(ctx, tx)
+}
+
+func sendTransfer(ctx context.Context, cb *ethchannel.ContractBackend, acc accounts.Account) (*types.Transaction, error) {
+	opts, err := cb.NewTransactor(ctx, ethchannel.GasLimit, acc)
+	if err != nil {
+		return nil, err
+	}
+	// The nonce is set by the NonceManager.
+	tx, err := opts.Signer(acc.Address,
+		types.NewTransaction(0, common.Address{1}, big.NewInt(1), ethchannel.GasLimit, big.NewInt(1), nil))
+	if err != nil {
+		return nil, err
+	}
+	return tx, cb.SendTransaction(ctx, tx)
+}
+
+func TestNonceManager_Concurrent(t *testing.T) {
+	const n = 10
+	rng := pkgtest.Prng(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	sb, tr, acc := newTxSetup(rng)
+	cb := ethchannel.NewContractBackend(sb, tr)
+	start, err := sb.PendingNonceAt(ctx, acc.Address)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var nonces []uint64
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			tx, err := sendTransfer(ctx, &cb, acc)
+			if assert.NoError(t, err) {
+				mu.Lock()
+				nonces = append(nonces, tx.Nonce())
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, nonces, n)
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+	for i, nonce := range nonces {
+		assert.Equal(t, start+uint64(i), nonce)
+	}
+	pending, err := sb.PendingNonceAt(ctx, acc.Address)
+	require.NoError(t, err)
+	assert.Equal(t, start+n, pending)
+}
+
+func TestNonceManager_Recover(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	sb, tr, acc := newTxSetup(rng)
+	db := &droppingBackend{SimulatedBackend: sb}
+	cb := ethchannel.NewContractBackend(db, tr)
+	require.NotNil(t, cb.NonceManager())
+
+	tx, err := sendTransfer(ctx, &cb, acc)
+	require.NoError(t, err)
+	nonce := tx.Nonce()
+
+	t.Run("dropped", func(t *testing.T) {
+		db.drop = true
+		tx, err := sendTransfer(ctx, &cb, acc)
+		require.NoError(t, err)
+		assert.Equal(t, nonce+1, tx.Nonce())
+		db.drop = false
+
+		tx, err = sendTransfer(ctx, &cb, acc)
+		require.NoError(t, err)
+		assert.Equal(t, nonce+1, tx.Nonce(), "nonce of dropped TX should be reused")
+	})
+
+	t.Run("external", func(t *testing.T) {
+		// Send a transaction from the account without the NonceManager.
+		other := ethchannel.NewContractBackend(sb, tr)
+		other.SetNonceManager(nil)
+		opts, err := other.NewTransactor(ctx, ethchannel.GasLimit, acc)
+		require.NoError(t, err)
+		tx, err := opts.Signer(acc.Address,
+			types.NewTransaction(nonce+2, common.Address{1}, big.NewInt(1), ethchannel.GasLimit, big.NewInt(1), nil))
+		require.NoError(t, err)
+		require.NoError(t, other.SendTransaction(ctx, tx))
+
+		tx, err = sendTransfer(ctx, &cb, acc)
+		require.NoError(t, err)
+		assert.Equal(t, nonce+3, tx.Nonce(), "external TX should be skipped")
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		other := ethchannel.NewContractBackend(sb, tr)
+		other.SetNonceManager(cb.NonceManager())
+		sign := func(cb *ethchannel.ContractBackend) *types.Transaction {
+			opts, err := cb.NewTransactor(ctx, ethchannel.GasLimit, acc)
+			require.NoError(t, err)
+			tx, err := opts.Signer(acc.Address,
+				types.NewTransaction(0, common.Address{1}, big.NewInt(1), ethchannel.GasLimit, big.NewInt(1), nil))
+			require.NoError(t, err)
+			return tx
+		}
+		// Sign without sending.
+		assert.Equal(t, nonce+4, sign(&other).Nonce())
+		assert.Equal(t, nonce+5, sign(&cb).Nonce(), "in-flight nonce should not be reused")
+
+		cb.NonceManager().Reset(acc.Address)
+		tx, err := sendTransfer(ctx, &cb, acc)
+		require.NoError(t, err)
+		assert.Equal(t, nonce+4, tx.Nonce(), "nonce should be reused after Reset")
+	})
+}
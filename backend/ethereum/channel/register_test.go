@@ -180,3 +180,39 @@ func TestRegister_CancelledContext(t *testing.T) {
 	}
 	assert.Error(t, adj.Register(ctx, req), "Registering with canceled context should error")
 }
+
+func TestSubscribe_EventCursor(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	s := test.NewSetup(t, rng, 1)
+	params, state := channeltest.NewRandomParamsAndState(rng, channeltest.WithChallengeDuration(uint64(100*time.Second)), channeltest.WithParts(s.Parts...), channeltest.WithAssets((*ethchannel.Asset)(&s.Asset)), channeltest.WithIsFinal(false))
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTxTimeout)
+	defer cancel()
+	// funding sets the event cursor
+	reqFund := channel.NewFundingReq(params, state, channel.Index(0), state.Balances)
+	require.NoError(t, s.Funders[0].Fund(ctx, *reqFund), "funding should succeed")
+	adj := s.Adjs[0]
+	_, ok, err := adj.CursorStore().Cursor(params.ID())
+	require.NoError(t, err)
+	require.True(t, ok, "funding should set event cursor")
+
+	req := channel.AdjudicatorReq{
+		Params: params,
+		Acc:    s.Accs[0],
+		Idx:    channel.Index(0),
+		Tx:     testSignState(t, s.Accs, params, state),
+	}
+	require.NoError(t, adj.Register(ctx, req), "Registering should succeed")
+
+	// Mine more blocks than the default look-back of subscriptions.
+	for i := 0; i < 150; i++ {
+		s.SimBackend.Commit()
+	}
+
+	sub, err := adj.Subscribe(ctx, params)
+	require.NoError(t, err)
+	defer sub.Close()
+	require.True(t, sub.(interface{ HasPast() bool }).HasPast(), "subscription should find past event")
+	event := sub.Next()
+	require.IsType(t, &channel.RegisteredEvent{}, event)
+	assert.Equal(t, state.Version, event.Version())
+}
@@ -19,6 +19,7 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/pkg/errors"
@@ -28,16 +29,26 @@ import (
 )
 
 // Subscribe returns a new AdjudicatorSubscription to adjudicator events.
+//
+// Past events are scanned from the channel's event cursor on, see
+// CursorStore. If the cursor is not set, the last 100 blocks are scanned.
 func (a *Adjudicator) Subscribe(ctx context.Context, params *channel.Params) (channel.AdjudicatorSubscription, error) {
 	events := make(chan *adjudicator.AdjudicatorChannelUpdate)
-	sub, iter, err := a.filterWatch(ctx, events, params)
+	sub, past, err := a.filterWatch(ctx, events, params)
 	if err != nil {
 		return nil, errors.WithMessage(err, "creating filter-watch event subscription")
 	}
+	conf, err := newEventConfirmer(ctx, &a.ContractBackend)
+	if err != nil {
+		sub.Unsubscribe()
+		return nil, errors.WithMessage(err, "creating event confirmer")
+	}
 
 	rsub := &RegisteredSub{
 		cr:   a.ContractInterface,
 		sub:  sub,
+		conf: conf,
+		id:   params.ID(),
 		next: make(chan channel.AdjudicatorEvent, 1),
 		err:  make(chan error, 1),
 	}
@@ -45,33 +56,17 @@ func (a *Adjudicator) Subscribe(ctx context.Context, params *channel.Params) (ch
 	// Start event updater routine
 	go rsub.updateNext(ctx, events, a)
 
-	// find past event, if any
-	var ev *adjudicator.AdjudicatorChannelUpdate
-	for iter.Next() {
-		ev = iter.Event // fast-forward to newest event
-	}
-	// nolint:errcheck,gosec,gosec
-	iter.Close()
-	if err := iter.Error(); err != nil {
-		sub.Unsubscribe()
-		return nil, errors.Wrap(err, "event iterator")
-	}
-	// Pass non-nil past event to updater
-	if ev != nil {
+	// Pass newest past event to updater, if any
+	if len(past) != 0 {
 		rsub.past = true
-		events <- ev
+		events <- past[len(past)-1]
 	}
 
 	return rsub, nil
 }
 
-// filterWatch sets up a filter and a subscription on events.
-func (a *Adjudicator) filterWatch(ctx context.Context, events chan *adjudicator.AdjudicatorChannelUpdate, params *channel.Params) (sub event.Subscription, iter *adjudicator.AdjudicatorChannelUpdateIterator, err error) {
-	defer func() {
-		if err != nil && sub != nil {
-			sub.Unsubscribe()
-		}
-	}()
+// filterWatch sets up a subscription on events and returns all past events.
+func (a *Adjudicator) filterWatch(ctx context.Context, events chan *adjudicator.AdjudicatorChannelUpdate, params *channel.Params) (sub event.Subscription, past []*adjudicator.AdjudicatorChannelUpdate, err error) {
 	// Watch new events
 	watchOpts, err := a.NewWatchOpts(ctx)
 	if err != nil {
@@ -83,25 +78,60 @@ func (a *Adjudicator) filterWatch(ctx context.Context, events chan *adjudicator.
 	}
 
 	// Filter old Events
-	filterOpts, err := a.NewFilterOpts(ctx)
+	past, err = a.filterChannelUpdates(ctx, params.ID())
 	if err != nil {
-		return nil, nil, errors.WithMessage(err, "creating filter opts")
+		sub.Unsubscribe()
+		return nil, nil, err
 	}
-	iter, err = a.contract.FilterChannelUpdate(filterOpts, []channel.ID{params.ID()})
+	return sub, past, nil
+}
+
+// filterChannelUpdates returns all past ChannelUpdate events of the given
+// channel from the block returned by startBlockNum on. The blocks are scanned
+// in chunks of at most filterChunkSize blocks.
+func (a *Adjudicator) filterChannelUpdates(ctx context.Context, id channel.ID) ([]*adjudicator.AdjudicatorChannelUpdate, error) {
+	from, err := a.startBlockNum(ctx, id)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "filtering events")
+		return nil, err
+	}
+	head, err := a.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving latest block")
 	}
 
-	return sub, iter, nil
+	var events []*adjudicator.AdjudicatorChannelUpdate
+	err = forEachChunk(from, head.Number.Uint64(), filterChunkSize, func(start, end uint64) error {
+		opts := &bind.FilterOpts{Start: start, End: &end, Context: ctx}
+		iter, err := a.contract.FilterChannelUpdate(opts, []channel.ID{id})
+		if err != nil {
+			return errors.Wrapf(err, "filtering events in blocks %d to %d", start, end)
+		}
+		defer iter.Close() // nolint: errcheck
+		for iter.Next() {
+			events = append(events, iter.Event)
+		}
+		return errors.Wrap(iter.Error(), "event iterator")
+	})
+	return events, err
 }
 
 // RegisteredSub implements the channel.RegisteredSubscription interface.
+//
+// Events are only passed on once they are confirmed, see
+// ContractBackend.ConfirmationDepth. If the newest passed on event is removed
+// from the chain by a reorg, the newest remaining confirmed event is passed on
+// again.
 type RegisteredSub struct {
 	cr   ethereum.ChainReader          // chain reader to read block time
 	sub  event.Subscription            // Event subscription
+	conf *eventConfirmer               // Event confirmer
 	next chan channel.AdjudicatorEvent // Event sink
 	err  chan error                    // error from subscription
 	past bool                          // whether there was a past event when the subscription was created
+	id   channel.ID                    // channel ID, for updating the event cursor
+
+	confirmed []*adjudicator.AdjudicatorChannelUpdate // confirmed events, only accessed by updateNext
+	latest    *adjudicator.AdjudicatorChannelUpdate   // newest confirmed event
 }
 
 // HasPast indicates whether there was a past event when the subscription was created.
@@ -110,36 +140,40 @@ func (r *RegisteredSub) HasPast() bool {
 }
 
 func (r *RegisteredSub) updateNext(ctx context.Context, events chan *adjudicator.AdjudicatorChannelUpdate, a *Adjudicator) {
+	defer r.conf.Close()
 evloop:
 	for {
+		var (
+			confirmed []confirmedEvent
+			err       error
+		)
 		select {
 		case next := <-events:
-			select {
-			// drain next-channel on new event
-			case current := <-r.next:
-				currentTimeout := current.Timeout().(*BlockTimeout)
-				// if newer version or same version and newer timeout, replace
-				if current.Version() < next.Version || current.Version() == next.Version && currentTimeout.Time < next.Timeout {
-					e, err := a.convertEvent(ctx, next)
-					if err != nil {
-						r.err <- err
-						break evloop
-					}
-
-					r.next <- e
-				} else { // otherwise, reuse old
-					r.next <- current
+			confirmed, err = r.conf.Add(ctx, next, next.Raw)
+		case head := <-r.conf.Heads():
+			confirmed, err = r.conf.NewHead(ctx, head)
+		case err := <-r.sub.Err():
+			r.err <- err
+			break evloop
+		case err := <-r.conf.Err():
+			r.err <- errors.Wrap(err, "header subscription")
+			break evloop
+		}
+
+		if err == nil {
+			for _, e := range confirmed {
+				next := e.Event.(*adjudicator.AdjudicatorChannelUpdate)
+				if e.Removed {
+					err = r.retract(ctx, next, a)
+				} else {
+					err = r.update(ctx, next, a)
 				}
-			default: // next-channel is empty
-				e, err := a.convertEvent(ctx, next)
 				if err != nil {
-					r.err <- err
-					break evloop
+					break
 				}
-
-				r.next <- e
 			}
-		case err := <-r.sub.Err():
+		}
+		if err != nil {
 			r.err <- err
 			break evloop
 		}
@@ -153,6 +187,81 @@ evloop:
 	close(r.next)
 }
 
+// update passes the confirmed event next on if it is newer than the newest
+// event so far.
+func (r *RegisteredSub) update(ctx context.Context, next *adjudicator.AdjudicatorChannelUpdate, a *Adjudicator) error {
+	r.confirmed = append(r.confirmed, next)
+	if r.latest != nil && !isNewerEvent(next, r.latest) {
+		return nil
+	}
+	r.latest = next
+	r.updateCursor(next, a)
+	return r.replaceNext(ctx, next, a)
+}
+
+// updateCursor sets the channel's event cursor to the block of event e, so
+// that e is found as past event by future subscriptions.
+func (r *RegisteredSub) updateCursor(e *adjudicator.AdjudicatorChannelUpdate, a *Adjudicator) {
+	if a.cursors == nil {
+		return
+	}
+	if err := a.cursors.SetCursor(r.id, e.Raw.BlockNumber); err != nil {
+		a.log.WithError(err).Warn("Could not update event cursor")
+	}
+}
+
+// retract removes the confirmed event e, which was removed from the chain. If
+// it was the newest event, the newest remaining event is passed on again.
+func (r *RegisteredSub) retract(ctx context.Context, e *adjudicator.AdjudicatorChannelUpdate, a *Adjudicator) error {
+	for i, c := range r.confirmed {
+		if c == e {
+			r.confirmed = append(r.confirmed[:i], r.confirmed[i+1:]...)
+			break
+		}
+	}
+	if e != r.latest {
+		return nil
+	}
+
+	r.latest = nil
+	for _, c := range r.confirmed {
+		if r.latest == nil || isNewerEvent(c, r.latest) {
+			r.latest = c
+		}
+	}
+	if r.latest == nil {
+		// drain next-channel, there is no event left
+		select {
+		case <-r.next:
+		default:
+		}
+		return nil
+	}
+	r.updateCursor(r.latest, a)
+	return r.replaceNext(ctx, r.latest, a)
+}
+
+// replaceNext converts the event and replaces the current next event with it.
+func (r *RegisteredSub) replaceNext(ctx context.Context, e *adjudicator.AdjudicatorChannelUpdate, a *Adjudicator) error {
+	ev, err := a.convertEvent(ctx, e)
+	if err != nil {
+		return err
+	}
+	// drain next-channel on new event
+	select {
+	case <-r.next:
+	default:
+	}
+	r.next <- ev
+	return nil
+}
+
+// isNewerEvent returns whether event a has a newer version than b or the same
+// version and a later timeout.
+func isNewerEvent(a, b *adjudicator.AdjudicatorChannelUpdate) bool {
+	return a.Version > b.Version || a.Version == b.Version && a.Timeout > b.Timeout
+}
+
 // Next returns the newest past or next blockchain event.
 // It blocks until an event is returned from the blockchain or the subscription
 // is closed. If the subscription is closed, Next immediately returns nil.
@@ -179,7 +288,7 @@ func (r *RegisteredSub) Err() error {
 }
 
 func (a *Adjudicator) convertEvent(ctx context.Context, e *adjudicator.AdjudicatorChannelUpdate) (channel.AdjudicatorEvent, error) {
-	base := channel.NewAdjudicatorEventBase(e.ChannelID, NewBlockTimeout(a.ContractInterface, e.Timeout), e.Version)
+	base := channel.NewAdjudicatorEventBase(e.ChannelID, NewBlockTimeout(a.headReader(), e.Timeout), e.Version)
 	switch e.Phase {
 	case phaseDispute:
 		return &channel.RegisteredEvent{AdjudicatorEventBase: *base}, nil
@@ -22,9 +22,12 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/pkg/errors"
 
 	"perun.network/go-perun/channel"
@@ -38,6 +41,7 @@ const GasLimit = 500000
 // SimulatedBackend provides a simulated ethereum blockchain for tests.
 type SimulatedBackend struct {
 	backends.SimulatedBackend
+	database   ethdb.Database
 	faucetKey  *ecdsa.PrivateKey
 	faucetAddr common.Address
 	clockMu    sync.Mutex // Mutex for clock adjustments. Locked by SimTimeouts.
@@ -62,8 +66,10 @@ func NewSimulatedBackend() *SimulatedBackend {
 		faucetAddr:                       {Balance: new(big.Int).Sub(channel.MaxBalance, big.NewInt(9))},
 	}
 	alloc := core.GenesisAlloc(addr)
+	db := rawdb.NewMemoryDatabase()
 	return &SimulatedBackend{
-		SimulatedBackend: *backends.NewSimulatedBackend(alloc, 8000000),
+		SimulatedBackend: *backends.NewSimulatedBackendWithDatabase(db, alloc, 8000000),
+		database:         db,
 		faucetKey:        sk,
 		faucetAddr:       faucetAddr,
 	}
@@ -95,3 +101,29 @@ func (s *SimulatedBackend) FundAddress(ctx context.Context, addr common.Address)
 	}
 	bind.WaitMined(context.Background(), s, signedTX)
 }
+
+// Reorg simulates a chain reorganization that replaces the newest depth blocks
+// by depth+1 empty blocks. Transactions in the replaced blocks are dropped and
+// their events are removed.
+func (s *SimulatedBackend) Reorg(ctx context.Context, depth uint64) error {
+	head, err := s.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return errors.WithMessage(err, "retrieving latest block")
+	}
+	if head.Number.Uint64() < depth {
+		return errors.Errorf("cannot reorg %d blocks of chain with height %d", depth, head.Number)
+	}
+	parent, err := s.BlockByNumber(ctx, new(big.Int).SetUint64(head.Number.Uint64()-depth))
+	if err != nil {
+		return errors.WithMessage(err, "retrieving fork parent")
+	}
+
+	chain := s.Blockchain()
+	blocks, _ := core.GenerateChain(chain.Config(), parent, ethash.NewFaker(), s.database, int(depth)+1, func(int, *core.BlockGen) {})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		return errors.Wrap(err, "inserting fork")
+	}
+	// Reset the pending block to the new head.
+	s.Rollback()
+	return nil
+}
@@ -24,7 +24,9 @@ import (
 )
 
 // BlockTimeout is a timeout on an Ethereum blockchain. A ChainReader is used to
-// wait for the timeout to pass.
+// wait for the timeout to pass. If it is a ChainHeadTracker, all BlockTimeouts
+// share its head subscription and IsElapsed is answered from its latest
+// header.
 //
 // This is much better than a channel.TimeTimeout because the local clock might
 // not match the blockchain's timestamp at the point in time when the timeout
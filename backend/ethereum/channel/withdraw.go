@@ -157,7 +157,7 @@ func (a *Adjudicator) newWithdrawalAuth(request channel.AdjudicatorReq, asset as
 	auth := assetholder.AssetHolderWithdrawalAuth{
 		ChannelID:   request.Params.ID(),
 		Participant: wallet.AsEthAddr(request.Acc.Address()),
-		Receiver:    a.Receiver,
+		Receiver:    a.receiver(request),
 		Amount:      request.Tx.Allocation.Balances[asset.assetIndex][request.Idx],
 	}
 	enc, err := encodeAssetHolderWithdrawalAuth(auth)
@@ -169,6 +169,15 @@ func (a *Adjudicator) newWithdrawalAuth(request channel.AdjudicatorReq, asset as
 	return auth, sig, errors.WithMessage(err, "sign data")
 }
 
+// receiver returns the withdrawal receiver of the request or the default
+// Receiver of the Adjudicator if the request has none.
+func (a *Adjudicator) receiver(req channel.AdjudicatorReq) common.Address {
+	if req.Receiver != nil {
+		return wallet.AsEthAddr(req.Receiver)
+	}
+	return a.Receiver
+}
+
 func encodeAssetHolderWithdrawalAuth(auth assetholder.AssetHolderWithdrawalAuth) ([]byte, error) {
 	// encodeAssetHolderWithdrawalAuth encodes the AssetHolderWithdrawalAuth as with abi.encode() in the smart contracts.
 	args := abi.Arguments{
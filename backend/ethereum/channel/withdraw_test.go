@@ -20,6 +20,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -29,6 +30,7 @@ import (
 	"perun.network/go-perun/channel"
 	channeltest "perun.network/go-perun/channel/test"
 	pkgtest "perun.network/go-perun/pkg/test"
+	wallettest "perun.network/go-perun/wallet/test"
 )
 
 // nolint: dupl
@@ -224,6 +226,33 @@ func TestWithdraw(t *testing.T) {
 	})
 }
 
+func TestWithdraw_Receiver(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	s := test.NewSetup(t, rng, 1)
+	params, state := channeltest.NewRandomParamsAndState(rng, channeltest.WithParts(s.Parts...), channeltest.WithAssets((*ethchannel.Asset)(&s.Asset)), channeltest.WithIsFinal(true))
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTxTimeout)
+	defer cancel()
+	fundingReq := channel.NewFundingReq(params, state, channel.Index(0), state.Balances)
+	require.NoError(t, s.Funders[0].Fund(ctx, *fundingReq), "funding should succeed")
+
+	receiver := ethwallet.AsEthAddr(wallettest.NewRandomAddress(rng))
+	req := channel.AdjudicatorReq{
+		Params:   params,
+		Acc:      s.Accs[0],
+		Idx:      channel.Index(0),
+		Tx:       testSignState(t, s.Accs, params, state),
+		Receiver: ethwallet.AsWalletAddr(receiver),
+	}
+	require.NoError(t, s.Adjs[0].Withdraw(ctx, req, nil))
+
+	bal, err := s.SimBackend.BalanceAt(ctx, receiver, nil)
+	require.NoError(t, err)
+	assert.Zero(t, bal.Cmp(state.Balances[0][0]), "request receiver should get the funds")
+	bal, err = s.SimBackend.BalanceAt(ctx, common.Address(*s.Recvs[0]), nil)
+	require.NoError(t, err)
+	assert.Zero(t, bal.Sign(), "default receiver should not get funds")
+}
+
 func TestWithdrawNonFinal(t *testing.T) {
 	assert := assert.New(t)
 	rng := pkgtest.Prng(t)
@@ -0,0 +1,152 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client_test
+
+import (
+	"context"
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/backend/ethereum/channel/test"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/keystore"
+	ethwtest "perun.network/go-perun/backend/ethereum/wallet/test"
+	"perun.network/go-perun/channel"
+	perunclient "perun.network/go-perun/client"
+	pkgtest "perun.network/go-perun/pkg/test"
+	"perun.network/go-perun/wire"
+)
+
+// nonFundingFunder does not deposit anything.
+type nonFundingFunder struct{}
+
+func (nonFundingFunder) Fund(context.Context, channel.FundingReq) error { return nil }
+
+// acceptingHandler accepts all ledger channel proposals and rejects updates.
+type acceptingHandler struct {
+	ctx    context.Context
+	rng    *rand.Rand
+	wallet *keystore.Wallet
+}
+
+func (h *acceptingHandler) HandleProposal(prop perunclient.ChannelProposal, res *perunclient.ProposalResponder) {
+	part := h.wallet.NewRandomAccount(h.rng).Address()
+	// nolint:errcheck,gosec
+	res.Accept(h.ctx, prop.(*perunclient.LedgerChannelProposal).Accept(part, perunclient.WithNonceFrom(h.rng)))
+}
+
+func (h *acceptingHandler) HandleUpdate(_ perunclient.ChannelUpdate, res *perunclient.UpdateResponder) {
+	// nolint:errcheck,gosec
+	res.Reject(h.ctx, "no updates")
+}
+
+func TestFundingRecovery(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 4*defaultTimeout)
+	defer cancel()
+
+	s, ch, err := proposeUnfundedChannel(ctx, t, rng, perunclient.WithFundingRecovery())
+	require.Error(t, err)
+	assert.True(t, channel.IsFundingTimeoutError(err), "funding should time out")
+	require.NotNil(t, ch)
+	assert.Equal(t, channel.Withdrawn, ch.Phase())
+
+	bal, err := s.SimBackend.BalanceAt(ctx, common.Address(*s.Recvs[0]), nil)
+	require.NoError(t, err)
+	assert.Zero(t, bal.Cmp(unfundedDeposit), "Alice should get her deposit back")
+	require.Error(t, ch.RecoverFunding(ctx), "recovering twice should fail")
+}
+
+func TestFundingRecovery_WithdrawalReceiver(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 4*defaultTimeout)
+	defer cancel()
+
+	s, ch, err := proposeUnfundedChannel(ctx, t, rng)
+	require.True(t, channel.IsFundingTimeoutError(err), "funding should time out")
+	require.NotNil(t, ch)
+
+	receiver := ethwtest.NewRandomAddress(rng)
+	require.NoError(t, ch.SetWithdrawalReceiver(ctx, &receiver))
+	require.NoError(t, ch.RecoverFunding(ctx))
+	assert.Equal(t, channel.Withdrawn, ch.Phase())
+
+	bal, err := s.SimBackend.BalanceAt(ctx, common.Address(receiver), nil)
+	require.NoError(t, err)
+	assert.Zero(t, bal.Cmp(unfundedDeposit), "receiver should get Alice's deposit")
+	bal, err = s.SimBackend.BalanceAt(ctx, common.Address(*s.Recvs[0]), nil)
+	require.NoError(t, err)
+	assert.Zero(t, bal.Sign(), "default receiver should get nothing")
+}
+
+// unfundedDeposit is Alice's deposit in the channels of proposeUnfundedChannel.
+var unfundedDeposit = big.NewInt(100)
+
+// proposeUnfundedChannel lets Alice, created with the given options, propose
+// a ledger channel to Bob, who accepts but does not fund it. The funding times
+// out once Alice deposited. It returns the setup, the channel and the error of
+// ProposeChannel.
+func proposeUnfundedChannel(ctx context.Context, t *testing.T, rng *rand.Rand, opts ...perunclient.Option) (*test.Setup, *perunclient.Channel, error) {
+	const A, B = 0, 1 // Indices of Alice and Bob
+	s := test.NewSetup(t, rng, 2)
+	bus := wire.NewLocalBus()
+	wallets := [2]*keystore.Wallet{ethwtest.NewTmpWallet(), ethwtest.NewTmpWallet()}
+	alice, err := perunclient.New(s.Accs[A].Address(), bus, s.Funders[A], s.Adjs[A], wallets[A], opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { alice.Close() })
+	// Bob accepts the channel but does not fund it.
+	bob, err := perunclient.New(s.Accs[B].Address(), bus, nonFundingFunder{}, s.Adjs[B], wallets[B])
+	require.NoError(t, err)
+	t.Cleanup(func() { bob.Close() })
+	h := &acceptingHandler{ctx: ctx, rng: rng, wallet: wallets[B]}
+	go bob.Handle(h, h)
+
+	prop, err := perunclient.NewLedgerChannelProposal(
+		60,
+		wallets[A].NewRandomAccount(rng).Address(),
+		&channel.Allocation{
+			Assets:   []channel.Asset{(*wallet.Address)(&s.Asset)},
+			Balances: channel.Balances{{unfundedDeposit, big.NewInt(50)}},
+		},
+		[]wire.Address{s.Accs[A].Address(), s.Accs[B].Address()},
+		perunclient.WithNonceFrom(rng),
+		perunclient.WithoutApp(),
+	)
+	require.NoError(t, err)
+
+	// Let the funding time out once Alice deposited.
+	go func() {
+		for {
+			bal, err := s.SimBackend.BalanceAt(ctx, s.Asset, nil)
+			if err != nil || bal.Cmp(unfundedDeposit) == 0 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err := s.SimBackend.AdjustTime(60 * time.Second); err != nil {
+			panic(err)
+		}
+		s.SimBackend.Commit()
+	}()
+
+	ch, err := alice.ProposeChannel(ctx, prop)
+	return s, ch, err
+}
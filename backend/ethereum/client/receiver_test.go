@@ -0,0 +1,131 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client_test
+
+import (
+	"context"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/backend/ethereum/channel/test"
+	"perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/keystore"
+	ethwtest "perun.network/go-perun/backend/ethereum/wallet/test"
+	"perun.network/go-perun/channel"
+	chprtest "perun.network/go-perun/channel/persistence/test"
+	perunclient "perun.network/go-perun/client"
+	pkgtest "perun.network/go-perun/pkg/test"
+	wallettest "perun.network/go-perun/wallet/test"
+	"perun.network/go-perun/wire"
+)
+
+// updateAcceptingHandler accepts all ledger channel proposals and updates and
+// passes the accepted channels to chs.
+type updateAcceptingHandler struct {
+	ctx    context.Context
+	rng    *rand.Rand
+	wallet *keystore.Wallet
+	chs    chan *perunclient.Channel
+}
+
+func (h *updateAcceptingHandler) HandleProposal(prop perunclient.ChannelProposal, res *perunclient.ProposalResponder) {
+	part := h.wallet.NewRandomAccount(h.rng).Address()
+	ch, err := res.Accept(h.ctx, prop.(*perunclient.LedgerChannelProposal).Accept(part, perunclient.WithNonceFrom(h.rng)))
+	if err != nil {
+		close(h.chs)
+		return
+	}
+	h.chs <- ch
+}
+
+func (h *updateAcceptingHandler) HandleUpdate(_ perunclient.ChannelUpdate, res *perunclient.UpdateResponder) {
+	// nolint:errcheck,gosec
+	res.Accept(h.ctx)
+}
+
+func TestWithdrawalReceiver(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 4*defaultTimeout)
+	defer cancel()
+
+	const A, B = 0, 1 // Indices of Alice and Bob
+	s := test.NewSetup(t, rng, 2)
+	bus := wire.NewLocalBus()
+	wallets := [2]*keystore.Wallet{ethwtest.NewTmpWallet(), ethwtest.NewTmpWallet()}
+	alice, err := perunclient.New(s.Accs[A].Address(), bus, s.Funders[A], s.Adjs[A], wallets[A])
+	require.NoError(t, err)
+	defer alice.Close()
+	pr := chprtest.NewPersistRestorer(t)
+	alice.EnablePersistence(pr)
+	bob, err := perunclient.New(s.Accs[B].Address(), bus, s.Funders[B], s.Adjs[B], wallets[B])
+	require.NoError(t, err)
+	defer bob.Close()
+	h := &updateAcceptingHandler{ctx: ctx, rng: rng, wallet: wallets[B], chs: make(chan *perunclient.Channel, 1)}
+	go bob.Handle(h, h)
+
+	bals := []*big.Int{big.NewInt(100), big.NewInt(50)}
+	prop, err := perunclient.NewLedgerChannelProposal(
+		60,
+		wallets[A].NewRandomAccount(rng).Address(),
+		&channel.Allocation{
+			Assets:   []channel.Asset{(*wallet.Address)(&s.Asset)},
+			Balances: channel.Balances{bals},
+		},
+		[]wire.Address{s.Accs[A].Address(), s.Accs[B].Address()},
+		perunclient.WithNonceFrom(rng),
+		perunclient.WithoutApp(),
+	)
+	require.NoError(t, err)
+	chA, err := alice.ProposeChannel(ctx, prop)
+	require.NoError(t, err)
+	chB, ok := <-h.chs
+	require.True(t, ok, "Bob should accept the channel")
+
+	// Alice withdraws to her channel's receiver, Bob overrides the default
+	// receiver when settling.
+	recvs := [2]common.Address{
+		wallet.AsEthAddr(wallettest.NewRandomAddress(rng)),
+		wallet.AsEthAddr(wallettest.NewRandomAddress(rng)),
+	}
+	require.NoError(t, chA.SetWithdrawalReceiver(ctx, wallet.AsWalletAddr(recvs[A])))
+	assert.True(t, chA.WithdrawalReceiver().Equals(wallet.AsWalletAddr(recvs[A])))
+	restored, err := pr.RestoreChannel(ctx, chA.ID())
+	require.NoError(t, err)
+	assert.True(t, restored.Receiver.Equals(wallet.AsWalletAddr(recvs[A])), "receiver should be persisted")
+
+	require.NoError(t, chA.UpdateBy(ctx, func(s *channel.State) error {
+		s.IsFinal = true
+		return nil
+	}))
+	require.NoError(t, chA.Register(ctx))
+	require.NoError(t, chA.Settle(ctx, false))
+	require.NoError(t, chB.Register(ctx))
+	require.NoError(t, chB.SettleWithReceiver(ctx, nil, wallet.AsWalletAddr(recvs[B]), false))
+	assert.Nil(t, chB.WithdrawalReceiver(), "settling should not change the receiver")
+
+	for i, recv := range recvs {
+		bal, err := s.SimBackend.BalanceAt(ctx, recv, nil)
+		require.NoError(t, err)
+		assert.Zerof(t, bal.Cmp(bals[i]), "receiver %d should get the funds", i)
+		bal, err = s.SimBackend.BalanceAt(ctx, common.Address(*s.Recvs[i]), nil)
+		require.NoError(t, err)
+		assert.Zerof(t, bal.Sign(), "default receiver %d should not get funds", i)
+	}
+}
@@ -18,6 +18,8 @@ import (
 	"io"
 
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/pkg/errors"
 
 	perunio "perun.network/go-perun/pkg/io"
 	"perun.network/go-perun/wallet"
@@ -34,6 +36,14 @@ const SigLen = 65
 // compile-time check that the ethereum backend implements the perun backend.
 var _ wallet.Backend = (*Backend)(nil)
 
+// TypedDataSigner is implemented by accounts that can sign EIP-712 typed
+// data. The accounts of all Ethereum wallets implement it.
+type TypedDataSigner interface {
+	// SignTypedData signs the Keccak256 hash of the EIP-712 encoding of the
+	// typed data, see EncodeTypedData.
+	SignTypedData(data *core.TypedData) ([]byte, error)
+}
+
 // DecodeAddress decodes an address from an io.Reader.
 func (*Backend) DecodeAddress(r io.Reader) (wallet.Address, error) {
 	return DecodeAddress(r)
@@ -63,7 +73,36 @@ func DecodeSig(r io.Reader) (wallet.Sig, error) {
 
 // VerifySignature verifies if a signature was made by this account.
 func VerifySignature(msg []byte, sig wallet.Sig, a wallet.Address) (bool, error) {
-	hash := PrefixedHash(msg)
+	return verifyHash(PrefixedHash(msg), sig, a)
+}
+
+// EncodeTypedData returns the EIP-712 encoding of the typed data,
+// "\x19\x01" ‖ domainSeparator ‖ hashStruct(message), whose Keccak256 hash
+// is signed.
+func EncodeTypedData(data *core.TypedData) ([]byte, error) {
+	sep, err := data.HashStruct("EIP712Domain", data.Domain.Map())
+	if err != nil {
+		return nil, errors.Wrap(err, "hashing domain")
+	}
+	hash, err := data.HashStruct(data.PrimaryType, data.Message)
+	if err != nil {
+		return nil, errors.Wrap(err, "hashing message")
+	}
+	return append(append([]byte{0x19, 0x01}, sep...), hash...), nil
+}
+
+// VerifyTypedDataSignature verifies if a signature of EIP-712 typed data was
+// made by this account, see TypedDataSigner.
+func VerifyTypedDataSignature(data *core.TypedData, sig wallet.Sig, a wallet.Address) (bool, error) {
+	enc, err := EncodeTypedData(data)
+	if err != nil {
+		return false, err
+	}
+	return verifyHash(crypto.Keccak256(enc), sig, a)
+}
+
+// verifyHash verifies if a signature of a hash was made by this account.
+func verifyHash(hash []byte, sig wallet.Sig, a wallet.Address) (bool, error) {
 	sigCopy := make([]byte, SigLen)
 	copy(sigCopy, sig)
 	if len(sigCopy) == SigLen && (sigCopy[SigLen-1] >= 27) {
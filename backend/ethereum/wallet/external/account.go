@@ -0,0 +1,87 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/pkg/errors"
+
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wallet"
+)
+
+// Account represents an ethereum account whose key is held by an external
+// signer.
+type Account struct {
+	Account accounts.Account
+	wallet  *Wallet
+}
+
+// NewAccount creates a new perun account for the given address that signs
+// with the external signer of the given wallet.
+func NewAccount(w *Wallet, addr common.Address) *Account {
+	return &Account{
+		Account: accounts.Account{Address: addr},
+		wallet:  w,
+	}
+}
+
+// Address returns the ethereum address of this account.
+func (a *Account) Address() wallet.Address {
+	return ethwallet.AsWalletAddr(a.Account.Address)
+}
+
+// SignData is used to sign data with this account. The external signer is
+// asked to sign the Keccak256 hash of the data as text, which results in the
+// signature of ethwallet.PrefixedHash(data).
+func (a *Account) SignData(data []byte) ([]byte, error) {
+	var sig hexutil.Bytes
+	addr := common.NewMixedcaseAddress(a.Account.Address)
+	if err := a.wallet.call(&sig, "account_signData",
+		accounts.MimetypeTextPlain, &addr, hexutil.Encode(crypto.Keccak256(data))); err != nil {
+		return nil, errors.WithMessage(err, "signing data")
+	}
+	return checkSig(sig)
+}
+
+// SignTypedData signs EIP-712 typed data with this account, see
+// ethwallet.TypedDataSigner. The external signer is asked to sign the typed
+// data with account_signTypedData, so it can show the structured data to the
+// user for approval.
+func (a *Account) SignTypedData(data *core.TypedData) ([]byte, error) {
+	var sig hexutil.Bytes
+	addr := common.NewMixedcaseAddress(a.Account.Address)
+	if err := a.wallet.call(&sig, "account_signTypedData", &addr, data); err != nil {
+		return nil, errors.WithMessage(err, "signing typed data")
+	}
+	return checkSig(sig)
+}
+
+// checkSig checks the length of a signature returned by the external signer
+// and normalizes its v value to the 27/28 form.
+func checkSig(sig []byte) ([]byte, error) {
+	if len(sig) != ethwallet.SigLen {
+		return nil, errors.Errorf("external signer returned signature of length %d", len(sig))
+	}
+	// Clef already returns v in the 27/28 form, but other signers might not.
+	if sig[64] < 27 {
+		sig[64] += 27
+	}
+	return sig, nil
+}
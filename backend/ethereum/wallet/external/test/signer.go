@@ -0,0 +1,206 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"crypto/ecdsa"
+	"math/rand"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/secp256k1"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/pkg/errors"
+)
+
+// Version is the version that the mock Signer reports.
+const Version = "6.1.0"
+
+type (
+	// Signer is a mock external signer that serves the account namespace of
+	// the Clef JSON-RPC API over HTTP. It holds its keys in memory and
+	// approves all requests unless it is set to deny them.
+	Signer struct {
+		mu     sync.Mutex
+		keys   map[common.Address]*ecdsa.PrivateKey
+		signer types.Signer
+		deny   bool
+
+		rpc    *rpc.Server
+		server *httptest.Server
+	}
+
+	// api is the RPC service of a Signer.
+	api struct{ s *Signer }
+
+	signTxResult struct {
+		Raw hexutil.Bytes      `json:"raw"`
+		Tx  *types.Transaction `json:"tx"`
+	}
+)
+
+// NewSigner starts a new mock signer that signs transactions with the given
+// signer. Its endpoint is returned by Endpoint.
+func NewSigner(signer types.Signer) *Signer {
+	s := &Signer{
+		keys:   make(map[common.Address]*ecdsa.PrivateKey),
+		signer: signer,
+		rpc:    rpc.NewServer(),
+	}
+	if err := s.rpc.RegisterName("account", &api{s}); err != nil {
+		panic(err) // only fails if api has no suitable methods
+	}
+	s.server = httptest.NewServer(s.rpc)
+	return s
+}
+
+// Endpoint returns the URL of the signer's RPC endpoint.
+func (s *Signer) Endpoint() string {
+	return s.server.URL
+}
+
+// Close stops the signer.
+func (s *Signer) Close() {
+	s.server.Close()
+	s.rpc.Stop()
+}
+
+// NewRandomAccount creates a new random key in the signer and returns its
+// address.
+func (s *Signer) NewRandomAccount(rng *rand.Rand) common.Address {
+	key, err := ecdsa.GenerateKey(secp256k1.S256(), rng)
+	if err != nil {
+		panic(err)
+	}
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[addr] = key
+	return addr
+}
+
+// SetDeny sets whether the signer denies all listing and signing requests.
+func (s *Signer) SetDeny(deny bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deny = deny
+}
+
+// approve returns the key of the given address or core.ErrRequestDenied if
+// the signer denies requests.
+func (s *Signer) approve(addr common.Address) (*ecdsa.PrivateKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.deny {
+		return nil, core.ErrRequestDenied
+	}
+	key, ok := s.keys[addr]
+	if !ok {
+		return nil, errors.Errorf("unknown account %v", addr)
+	}
+	return key, nil
+}
+
+// Version implements account_version.
+func (a *api) Version() string {
+	return Version
+}
+
+// List implements account_list.
+func (a *api) List() ([]common.Address, error) {
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	if a.s.deny {
+		return nil, core.ErrRequestDenied
+	}
+	addrs := make([]common.Address, 0, len(a.s.keys))
+	for addr := range a.s.keys {
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// SignData implements account_signData for the text/plain content type.
+func (a *api) SignData(contentType string, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error) {
+	if contentType != accounts.MimetypeTextPlain {
+		return nil, errors.Errorf("unsupported content type %s", contentType)
+	}
+	key, err := a.s.approve(addr.Address())
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(accounts.TextHash(data), key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
+// SignTypedData implements account_signTypedData.
+func (a *api) SignTypedData(addr common.MixedcaseAddress, data core.TypedData) (hexutil.Bytes, error) {
+	key, err := a.s.approve(addr.Address())
+	if err != nil {
+		return nil, err
+	}
+	sep, err := data.HashStruct("EIP712Domain", data.Domain.Map())
+	if err != nil {
+		return nil, err
+	}
+	hash, err := data.HashStruct(data.PrimaryType, data.Message)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(crypto.Keccak256([]byte{0x19, 0x01}, sep, hash), key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
+// SignTransaction implements account_signTransaction.
+func (a *api) SignTransaction(args core.SendTxArgs, _ *string) (*signTxResult, error) {
+	key, err := a.s.approve(args.From.Address())
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	if args.Data != nil {
+		data = *args.Data
+	}
+	var tx *types.Transaction
+	if args.To == nil {
+		tx = types.NewContractCreation(uint64(args.Nonce), args.Value.ToInt(), uint64(args.Gas), args.GasPrice.ToInt(), data)
+	} else {
+		tx = types.NewTransaction(uint64(args.Nonce), args.To.Address(), args.Value.ToInt(), uint64(args.Gas), args.GasPrice.ToInt(), data)
+	}
+	signed, err := types.SignTx(tx, a.s.signer, key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		return nil, err
+	}
+	return &signTxResult{Raw: raw, Tx: signed}, nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/pkg/errors"
+)
+
+// Transactor can be used to make TransactOpts for accounts held by an
+// external signer.
+type Transactor struct {
+	Wallet *Wallet
+	Signer types.Signer
+}
+
+// signTxResult is the result of Clef's account_signTransaction.
+type signTxResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+// NewTransactor returns a TransactOpts for the given account. It errors if the
+// account is not listed by the external signer, see Wallet.Contains.
+//
+// Transactions are signed by the external signer, which has to be configured
+// for the same chain as the Signer of the Transactor. The signed transaction
+// is rejected if the external signer changed it, e.g., after the user edited
+// it during approval.
+func (t *Transactor) NewTransactor(account accounts.Account) (*bind.TransactOpts, error) {
+	if ok, err := t.Wallet.Contains(account.Address); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errors.New("account not found in external signer")
+	}
+	return &bind.TransactOpts{
+		From: account.Address,
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != account.Address {
+				return nil, bind.ErrNotAuthorized
+			}
+			return t.signTx(account, tx)
+		},
+	}, nil
+}
+
+func (t *Transactor) signTx(account accounts.Account, tx *types.Transaction) (*types.Transaction, error) {
+	data := hexutil.Bytes(tx.Data())
+	var to *common.MixedcaseAddress
+	if tx.To() != nil {
+		addr := common.NewMixedcaseAddress(*tx.To())
+		to = &addr
+	}
+	args := &core.SendTxArgs{
+		From:     common.NewMixedcaseAddress(account.Address),
+		To:       to,
+		Gas:      hexutil.Uint64(tx.Gas()),
+		GasPrice: hexutil.Big(*tx.GasPrice()),
+		Value:    hexutil.Big(*tx.Value()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		Data:     &data,
+	}
+	var res signTxResult
+	if err := t.Wallet.call(&res, "account_signTransaction", args); err != nil {
+		return nil, errors.WithMessage(err, "signing transaction")
+	}
+	if res.Tx == nil {
+		return nil, errors.New("external signer returned no transaction")
+	}
+
+	if t.Signer.Hash(res.Tx) != t.Signer.Hash(tx) {
+		return nil, errors.New("external signer modified the transaction")
+	}
+	sender, err := types.Sender(t.Signer, res.Tx)
+	if err != nil {
+		return nil, errors.Wrap(err, "recovering sender of signed transaction")
+	} else if sender != account.Address {
+		return nil, errors.Errorf("external signer signed with %v instead of %v", sender, account.Address)
+	}
+	return res.Tx, nil
+}
+
+// NewTransactor returns a backend that can make TransactOpts for accounts
+// held by the external signer of the given wallet.
+func NewTransactor(w *Wallet, s types.Signer) *Transactor {
+	return &Transactor{Wallet: w, Signer: s}
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	stderrors "errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/pkg/errors"
+
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/log"
+	"perun.network/go-perun/wallet"
+)
+
+// ErrRequestDenied is returned if the user or the rule set of the external
+// signer denied a request.
+var ErrRequestDenied = stderrors.New("request denied by external signer")
+
+var _ wallet.Wallet = (*Wallet)(nil)
+
+// Wallet is a perun wallet whose accounts are managed by an external signer
+// that implements the Clef JSON-RPC API. Every listing and signing request has
+// to be approved by the signer. The listed accounts are cached, so that
+// unlocking an account only lists the accounts if it is not known yet.
+// Accessing the wallet is threadsafe.
+type Wallet struct {
+	client *rpc.Client
+
+	mu     sync.Mutex
+	listed map[common.Address]bool // accounts of the last listing
+}
+
+// NewWallet connects to the external signer at the given endpoint, e.g., the
+// path of Clef's IPC socket, and checks that it is reachable.
+func NewWallet(endpoint string) (*Wallet, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "connecting to external signer")
+	}
+	w, err := NewWalletFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewWalletFromClient creates a new Wallet that uses the given RPC client to
+// access the external signer and checks that it is reachable.
+func NewWalletFromClient(client *rpc.Client) (*Wallet, error) {
+	w := &Wallet{client: client, listed: make(map[common.Address]bool)}
+	var version string
+	if err := w.call(&version, "account_version"); err != nil {
+		return nil, errors.WithMessage(err, "querying external signer version")
+	}
+	log.WithField("version", version).Debug("Connected to external signer")
+	return w, nil
+}
+
+// Accounts returns the addresses of all accounts the external signer grants
+// access to. The listing replaces the cached accounts.
+func (w *Wallet) Accounts() ([]common.Address, error) {
+	var addrs []common.Address
+	if err := w.call(&addrs, "account_list"); err != nil {
+		return nil, errors.WithMessage(err, "listing accounts")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.listed = make(map[common.Address]bool, len(addrs))
+	for _, addr := range addrs {
+		w.listed[addr] = true
+	}
+	return addrs, nil
+}
+
+// Contains checks whether the external signer grants access to the account
+// with the given address. The accounts are only listed if the account is not
+// cached from a previous listing.
+func (w *Wallet) Contains(a common.Address) (bool, error) {
+	w.mu.Lock()
+	listed := w.listed[a]
+	w.mu.Unlock()
+	if listed {
+		return true, nil
+	}
+
+	if _, err := w.Accounts(); err != nil {
+		return false, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.listed[a], nil
+}
+
+// Unlock returns the account with the given address. It returns an error if
+// the external signer does not list the account or denies the listing, see
+// Contains.
+func (w *Wallet) Unlock(addr wallet.Address) (wallet.Account, error) {
+	log.Debugf("Unlocking account %v", addr)
+	ethAddr := ethwallet.AsEthAddr(addr)
+	if ok, err := w.Contains(ethAddr); err != nil {
+		return nil, errors.WithMessagef(err, "unlocking %v", addr)
+	} else if !ok {
+		return nil, errors.Errorf("unlocking %v: account not found in external signer", addr)
+	}
+	return NewAccount(w, ethAddr), nil
+}
+
+// LockAll closes the connection to the external signer. The wallet is no
+// longer usable after this call.
+func (w *Wallet) LockAll() {
+	log.Debug("Closing connection to external signer")
+	w.client.Close()
+}
+
+// IncrementUsage currently does nothing. The keys are managed by the external
+// signer.
+func (w *Wallet) IncrementUsage(a wallet.Address) {
+	log.Trace("IncrementUsage ", a)
+}
+
+// DecrementUsage currently does nothing. The keys are managed by the external
+// signer.
+func (w *Wallet) DecrementUsage(a wallet.Address) {
+	log.Trace("DecrementUsage ", a)
+}
+
+// call calls the given method of the external signer and maps denied
+// requests to ErrRequestDenied.
+func (w *Wallet) call(result interface{}, method string, args ...interface{}) error {
+	err := w.client.Call(result, method, args...)
+	if err == nil {
+		return nil
+	}
+	if err.Error() == core.ErrRequestDenied.Error() {
+		return errors.WithMessage(ErrRequestDenied, method)
+	}
+	return errors.Wrap(err, method)
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external_test
+
+import (
+	"encoding/hex"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/external"
+	exttest "perun.network/go-perun/backend/ethereum/wallet/external/test"
+	ethwallettest "perun.network/go-perun/backend/ethereum/wallet/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wallet/test"
+)
+
+var dataToSign = []byte("SomeLongDataThatShouldBeSignedPlease")
+
+const sampleAddr = "1234560000000000000000000000000000000000"
+
+func TestGenericSignatureTests(t *testing.T) {
+	setup, _, _ := newSetup(t, pkgtest.Prng(t))
+	test.GenericSignatureTest(t, setup)
+	test.GenericSignatureSizeTest(t, setup)
+	test.GenericAddressTest(t, setup)
+}
+
+func TestNewWallet(t *testing.T) {
+	signer := exttest.NewSigner(types.HomesteadSigner{})
+	w, err := external.NewWallet(signer.Endpoint())
+	require.NoError(t, err)
+	w.LockAll()
+
+	signer.Close()
+	_, err = external.NewWallet(signer.Endpoint())
+	assert.Error(t, err, "connecting to a stopped signer should fail")
+}
+
+func TestWallet_Unlock(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	setup, w, signer := newSetup(t, rng)
+
+	missingAddr := common.BytesToAddress(setup.AddressBytes)
+	_, err := w.Unlock(ethwallet.AsWalletAddr(missingAddr))
+	assert.Error(t, err, "should error on unlocking missing address")
+
+	validAcc, _ := setup.UnlockedAccount()
+	acc, err := w.Unlock(validAcc.Address())
+	require.NoError(t, err)
+	assert.Equal(t, validAcc.Address(), acc.Address())
+
+	newAddr := ethwallet.AsWalletAddr(signer.NewRandomAccount(rng))
+	acc, err = w.Unlock(newAddr)
+	require.NoError(t, err, "unlocking a new account should list the accounts again")
+	assert.Equal(t, newAddr, acc.Address())
+
+	signer.SetDeny(true)
+	_, err = w.Unlock(validAcc.Address())
+	assert.NoError(t, err, "unlocking a listed account should not list the accounts again")
+	_, err = w.Unlock(ethwallet.AsWalletAddr(signer.NewRandomAccount(rng)))
+	assert.True(t, errors.Is(err, external.ErrRequestDenied), "denied listing should return ErrRequestDenied")
+}
+
+func TestAccount_Denied(t *testing.T) {
+	setup, _, signer := newSetup(t, pkgtest.Prng(t))
+	acc, err := setup.UnlockedAccount()
+	require.NoError(t, err)
+
+	signer.SetDeny(true)
+	_, err = acc.SignData(dataToSign)
+	assert.True(t, errors.Is(err, external.ErrRequestDenied), "denied signing should return ErrRequestDenied")
+
+	signer.SetDeny(false)
+	sig, err := acc.SignData(dataToSign)
+	require.NoError(t, err)
+	valid, err := new(ethwallet.Backend).VerifySignature(dataToSign, sig, acc.Address())
+	assert.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestAccount_SignTypedData(t *testing.T) {
+	setup, _, signer := newSetup(t, pkgtest.Prng(t))
+	acc, err := setup.UnlockedAccount()
+	require.NoError(t, err)
+	ethwallettest.GenericTypedDataTest(t, acc)
+
+	signer.SetDeny(true)
+	_, err = acc.(*external.Account).SignTypedData(ethwallettest.NewTypedData())
+	assert.True(t, errors.Is(err, external.ErrRequestDenied), "denied signing should return ErrRequestDenied")
+}
+
+func TestTransactor_Denied(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	ethSigner := types.NewEIP155Signer(big.NewInt(1337))
+	s := newTransactorSetup(t, rng, ethSigner, 1337)
+	signer := s.signer
+
+	opts, err := s.Tr.NewTransactor(s.ValidAcc)
+	require.NoError(t, err)
+	signer.SetDeny(true)
+	_, err = opts.Signer(s.ValidAcc.Address, types.NewTransaction(1, common.Address{}, big.NewInt(1), 1, big.NewInt(1), nil))
+	assert.True(t, errors.Is(err, external.ErrRequestDenied), "denied signing should return ErrRequestDenied")
+
+	_, err = s.Tr.NewTransactor(s.ValidAcc)
+	assert.NoError(t, err, "making TransactOpts for a listed account should not list the accounts again")
+	_, err = s.Tr.NewTransactor(accounts.Account{Address: signer.NewRandomAccount(rng)})
+	assert.True(t, errors.Is(err, external.ErrRequestDenied), "denied listing should return ErrRequestDenied")
+}
+
+func newSetup(t *testing.T, prng *rand.Rand) (*test.Setup, *external.Wallet, *exttest.Signer) {
+	signer := exttest.NewSigner(types.HomesteadSigner{})
+	t.Cleanup(signer.Close)
+	numAccounts := prng.Intn(9) + 1
+	var addr common.Address
+	for i := 0; i < numAccounts; i++ {
+		addr = signer.NewRandomAccount(prng)
+	}
+
+	w, err := external.NewWallet(signer.Endpoint())
+	require.NoError(t, err)
+	acc, err := w.Unlock(ethwallet.AsWalletAddr(addr))
+	require.NoError(t, err)
+
+	validAddrBytes, err := hex.DecodeString(sampleAddr)
+	require.NoError(t, err, "invalid sample address")
+
+	return &test.Setup{
+		UnlockedAccount: func() (wallet.Account, error) { return acc, nil },
+		Backend:         new(ethwallet.Backend),
+		AddressBytes:    validAddrBytes,
+		DataToSign:      dataToSign,
+	}, w, signer
+}
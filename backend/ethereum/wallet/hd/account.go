@@ -17,6 +17,7 @@ package hd
 import (
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core"
 	"github.com/pkg/errors"
 
 	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
@@ -45,6 +46,21 @@ func (a *Account) SignData(data []byte) ([]byte, error) {
 	return sig, nil
 }
 
+// SignTypedData signs EIP-712 typed data with this account, see
+// ethwallet.TypedDataSigner.
+func (a *Account) SignTypedData(data *core.TypedData) ([]byte, error) {
+	enc, err := ethwallet.EncodeTypedData(data)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := a.wallet.SignData(a.Account, accounts.MimetypeTypedData, enc)
+	if err != nil {
+		return nil, errors.Wrap(err, "SignData")
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
 // NewAccountFromEth creates a new perun account from a given ethereum account.
 func NewAccountFromEth(wallet accounts.Wallet, account accounts.Account) *Account {
 	return &Account{
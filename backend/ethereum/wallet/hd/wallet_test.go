@@ -27,6 +27,7 @@ import (
 
 	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
 	"perun.network/go-perun/backend/ethereum/wallet/hd"
+	ethwallettest "perun.network/go-perun/backend/ethereum/wallet/test"
 	pkgtest "perun.network/go-perun/pkg/test"
 	"perun.network/go-perun/wallet"
 	"perun.network/go-perun/wallet/test"
@@ -42,6 +43,13 @@ func TestGenericSignatureTests(t *testing.T) {
 	test.GenericAddressTest(t, s)
 }
 
+func TestTypedDataSignatures(t *testing.T) {
+	s, _, _ := newSetup(t, pkgtest.Prng(t))
+	acc, err := s.UnlockedAccount()
+	require.NoError(t, err)
+	ethwallettest.GenericTypedDataTest(t, acc)
+}
+
 func TestNewWallet(t *testing.T) {
 	prng := pkgtest.Prng(t)
 
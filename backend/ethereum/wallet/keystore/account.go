@@ -16,6 +16,8 @@ package keystore
 
 import (
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core"
 	"github.com/pkg/errors"
 
 	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
@@ -44,6 +46,21 @@ func (a *Account) SignData(data []byte) ([]byte, error) {
 	return sig, nil
 }
 
+// SignTypedData signs EIP-712 typed data with this account, see
+// ethwallet.TypedDataSigner.
+func (a *Account) SignTypedData(data *core.TypedData) ([]byte, error) {
+	enc, err := ethwallet.EncodeTypedData(data)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := a.wallet.Ks.SignHash(a.Account, crypto.Keccak256(enc))
+	if err != nil {
+		return nil, errors.Wrap(err, "SignHash")
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
 // NewAccountFromEth creates a new perun account from a given ethereum account.
 func NewAccountFromEth(wallet *Wallet, account *accounts.Account) *Account {
 	return &Account{
@@ -68,6 +68,10 @@ func TestSignatures(t *testing.T) {
 	assert.NoError(t, err, "Verification should succeed")
 }
 
+func TestTypedDataSignatures(t *testing.T) {
+	ethwallettest.GenericTypedDataTest(t, ethwallettest.NewTmpWallet().NewAccount())
+}
+
 func TestBackend(t *testing.T) {
 	backend := new(ethwallet.Backend)
 
@@ -19,6 +19,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core"
 	"github.com/pkg/errors"
 
 	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
@@ -47,6 +48,21 @@ func (a *Account) SignData(data []byte) ([]byte, error) {
 	return sig, nil
 }
 
+// SignTypedData signs EIP-712 typed data with this account, see
+// ethwallet.TypedDataSigner.
+func (a *Account) SignTypedData(data *core.TypedData) ([]byte, error) {
+	enc, err := ethwallet.EncodeTypedData(data)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := a.SignHash(crypto.Keccak256(enc))
+	if err != nil {
+		return nil, errors.Wrap(err, "SignHash")
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
 // SignHash is used to sign an already prefixed hash with this account.
 func (a *Account) SignHash(hash []byte) ([]byte, error) {
 	return crypto.Sign(hash, a.key)
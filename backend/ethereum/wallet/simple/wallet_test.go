@@ -28,6 +28,7 @@ import (
 
 	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
 	"perun.network/go-perun/backend/ethereum/wallet/simple"
+	ethwallettest "perun.network/go-perun/backend/ethereum/wallet/test"
 	pkgtest "perun.network/go-perun/pkg/test"
 	"perun.network/go-perun/wallet"
 	"perun.network/go-perun/wallet/test"
@@ -88,6 +89,11 @@ func TestSignatures(t *testing.T) {
 	assert.NoError(t, err, "Verification should succeed")
 }
 
+func TestTypedDataSignatures(t *testing.T) {
+	simpleWallet := simple.NewWallet()
+	ethwallettest.GenericTypedDataTest(t, simpleWallet.NewRandomAccount(pkgtest.Prng(t)))
+}
+
 func newSetup(t require.TestingT, prng *rand.Rand) (*test.Setup, *simple.Wallet) {
 	numAccounts := prng.Intn(99) + 1
 	privateKeys := make([]*ecdsa.PrivateKey, numAccounts)
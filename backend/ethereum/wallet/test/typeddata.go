@@ -0,0 +1,95 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/wallet"
+)
+
+// NewTypedData returns the typed data of the example in EIP-712.
+func NewTypedData() *core.TypedData {
+	person := []core.Type{
+		{Name: "name", Type: "string"},
+		{Name: "wallet", Type: "address"},
+	}
+	return &core.TypedData{
+		Types: core.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"Person": person,
+			"Mail": {
+				{Name: "from", Type: "Person"},
+				{Name: "to", Type: "Person"},
+				{Name: "contents", Type: "string"},
+			},
+		},
+		PrimaryType: "Mail",
+		Domain: core.TypedDataDomain{
+			Name:              "Ether Mail",
+			Version:           "1",
+			ChainId:           (*math.HexOrDecimal256)(big.NewInt(1)),
+			VerifyingContract: "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC",
+		},
+		Message: core.TypedDataMessage{
+			"from": map[string]interface{}{
+				"name":   "Cow",
+				"wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826",
+			},
+			"to": map[string]interface{}{
+				"name":   "Bob",
+				"wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB",
+			},
+			"contents": "Hello, Bob!",
+		},
+	}
+}
+
+// GenericTypedDataTest tests that the account signs the typed data of
+// NewTypedData as an ethwallet.TypedDataSigner.
+func GenericTypedDataTest(t *testing.T, acc wallet.Account) {
+	signer, ok := acc.(ethwallet.TypedDataSigner)
+	require.True(t, ok, "account should be a TypedDataSigner")
+	data := NewTypedData()
+	sig, err := signer.SignTypedData(data)
+	require.NoError(t, err, "Signing typed data should succeed")
+	assert.Equal(t, len(sig), ethwallet.SigLen, "Ethereum signature has wrong length")
+	valid, err := ethwallet.VerifyTypedDataSignature(data, sig, acc.Address())
+	assert.NoError(t, err)
+	assert.True(t, valid, "Verification should succeed")
+
+	enc, err := ethwallet.EncodeTypedData(data)
+	require.NoError(t, err)
+	valid, err = ethwallet.VerifySignature(enc, sig, acc.Address())
+	assert.NoError(t, err)
+	assert.False(t, valid, "Typed data signature should not be a valid personal signature")
+
+	data.Message["contents"] = "Hello, Alice!"
+	valid, err = ethwallet.VerifyTypedDataSignature(data, sig, acc.Address())
+	assert.NoError(t, err)
+	assert.False(t, valid, "Verification of modified data should fail")
+}
@@ -70,12 +70,16 @@ type (
 	// on-chain request that is executed by the other channel participants as well
 	// and the Adjudicator backend may run an optimized on-chain transaction
 	// protocol, possibly saving unnecessary double sending of transactions.
+	//
+	// If Receiver is set, Withdraw should withdraw the funds to the Receiver
+	// instead of the Adjudicator backend's default receiver.
 	AdjudicatorReq struct {
 		Params    *Params
 		Acc       wallet.Account
 		Tx        Transaction
-		Idx       Index // Always the own index
-		Secondary bool  // Optimized secondary call protocol
+		Idx       Index          // Always the own index
+		Secondary bool           // Optimized secondary call protocol
+		Receiver  wallet.Address // Withdrawal receiver, backend default if nil
 	}
 
 	// A ProgressReq collects all necessary information to do a progress call to
@@ -144,7 +144,8 @@ type OptAppDec struct {
 	App *App
 }
 
-// Decode decodes an optional App value.
+// Decode decodes an optional App value. The app definition is resolved with
+// the AppRegistry of the reader, see AppRegistryOf.
 func (d OptAppDec) Decode(r io.Reader) (err error) {
 	var hasApp bool
 	if err = perunio.Decode(r, &hasApp); err != nil {
@@ -158,7 +159,7 @@ func (d OptAppDec) Decode(r io.Reader) (err error) {
 	if err != nil {
 		return errors.WithMessage(err, "decode app address")
 	}
-	*d.App, err = Resolve(appDef)
+	*d.App, err = AppRegistryOf(r).Resolve(appDef)
 	return errors.WithMessage(err, "resolve app")
 }
 
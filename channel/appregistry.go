@@ -15,6 +15,7 @@
 package channel
 
 import (
+	"io"
 	"log"
 	"sync"
 
@@ -23,10 +24,18 @@ import (
 )
 
 // appRegistry is the global registry for `AppResolver`s.
-var appRegistry appReg = appReg{singles: make(map[wallet.AddrKey]App)}
+var appRegistry = NewAppRegistry("global")
 
-type appReg struct {
-	sync.RWMutex
+// An AppRegistry resolves app definitions to Apps. Single apps, resolvers for
+// address predicates and a default resolver can be registered with it.
+//
+// The package-level functions Resolve, RegisterApp, RegisterAppResolver and
+// RegisterDefaultApp operate on the global registry, see GlobalAppRegistry.
+// Independent registries can be created with NewAppRegistry, e.g., to give
+// clients in the same process different sets of apps.
+type AppRegistry struct {
+	mu         sync.RWMutex
+	name       string
 	resolvers  []appRegEntry
 	singles    map[wallet.AddrKey]App
 	defaultRes AppResolver
@@ -37,64 +46,151 @@ type appRegEntry struct {
 	res  AppResolver
 }
 
-// Resolve is a global wrapper call to the global `appRegistry`.
-// This function is intended to resolve app definitions coming in on the wire.
-func Resolve(def wallet.Address) (App, error) {
-	appRegistry.RLock()
-	defer appRegistry.RUnlock()
+// NewAppRegistry returns a new empty AppRegistry. The name is used in error
+// messages to identify the registry.
+func NewAppRegistry(name string) *AppRegistry {
+	return &AppRegistry{
+		name:    name,
+		singles: make(map[wallet.AddrKey]App),
+	}
+}
+
+// GlobalAppRegistry returns the global AppRegistry that is used if no other
+// registry is specified.
+func GlobalAppRegistry() *AppRegistry {
+	return appRegistry
+}
+
+// Name returns the name of the registry.
+func (r *AppRegistry) Name() string {
+	return r.name
+}
+
+// Resolve resolves the given app definition to an App. Single apps take
+// precedence over resolvers, which are tried in registration order. If no
+// resolver matches, the default resolver is used.
+func (r *AppRegistry) Resolve(def wallet.Address) (App, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	if def == nil {
 		log.Panic("resolving nil address")
 	}
-	if app, ok := appRegistry.singles[wallet.Key(def)]; ok {
+	if app, ok := r.singles[wallet.Key(def)]; ok {
 		return app, nil
 	}
-	for _, e := range appRegistry.resolvers {
+	for _, e := range r.resolvers {
 		if e.pred(def) {
-			return e.res.Resolve(def)
+			app, err := e.res.Resolve(def)
+			return app, errors.WithMessagef(err, "app registry %q", r.name)
 		}
 	}
-	if appRegistry.defaultRes == nil {
-		return nil, errors.Errorf("def %v could not be resolved and no default resolver set", def)
+	if r.defaultRes == nil {
+		return nil, errors.Errorf(
+			"app registry %q: def %v could not be resolved and no default resolver set",
+			r.name, def)
 	}
-	return appRegistry.defaultRes.Resolve(def)
+	app, err := r.defaultRes.Resolve(def)
+	return app, errors.WithMessagef(err, "app registry %q: default resolver", r.name)
 }
 
-// RegisterAppResolver appends the given `AddressPredicate` and `AppResolver` to the
-// global `appRegistry`.
-func RegisterAppResolver(pred wallet.AddressPredicate, appRes AppResolver) {
-	appRegistry.Lock()
-	defer appRegistry.Unlock()
+// RegisterAppResolver appends the given `AddressPredicate` and `AppResolver`
+// to the registry.
+func (r *AppRegistry) RegisterAppResolver(pred wallet.AddressPredicate, appRes AppResolver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
 	if pred == nil || appRes == nil {
 		log.Panic("nil AddressPredicate or AppResolver")
 	}
 
-	appRegistry.resolvers = append(appRegistry.resolvers, appRegEntry{pred, appRes})
+	r.resolvers = append(r.resolvers, appRegEntry{pred, appRes})
 }
 
 // RegisterApp registers a single app for a single address.
-func RegisterApp(app App) {
-	appRegistry.Lock()
-	defer appRegistry.Unlock()
+func (r *AppRegistry) RegisterApp(app App) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
 	if app == nil || app.Def() == nil {
 		log.Panic("nil Address or App")
 	}
 
-	appRegistry.singles[wallet.Key(app.Def())] = app
+	r.singles[wallet.Key(app.Def())] = app
 }
 
-// RegisterDefaultApp allows to specify a default `AppResolver` which is used by
-// the `AppRegistry` if no predicate matches. It must be set during the
-// initialization of the program, before any app is resolved.
-func RegisterDefaultApp(appRes AppResolver) {
-	appRegistry.Lock()
-	defer appRegistry.Unlock()
+// RegisterDefaultApp allows to specify a default `AppResolver` which is used
+// if no predicate matches. It must be set during the initialization of the
+// program, before any app is resolved.
+func (r *AppRegistry) RegisterDefaultApp(appRes AppResolver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if appRes == nil {
 		log.Panic("nil AppResolver")
 	}
-	if appRegistry.defaultRes != nil {
+	if r.defaultRes != nil {
 		log.Panic("default resolver already set")
 	}
-	appRegistry.defaultRes = appRes
+	r.defaultRes = appRes
+}
+
+// Resolve is a global wrapper call to the global `appRegistry`.
+// This function is intended to resolve app definitions coming in on the wire.
+func Resolve(def wallet.Address) (App, error) {
+	return appRegistry.Resolve(def)
+}
+
+// RegisterAppResolver appends the given `AddressPredicate` and `AppResolver` to the
+// global `appRegistry`.
+func RegisterAppResolver(pred wallet.AddressPredicate, appRes AppResolver) {
+	appRegistry.RegisterAppResolver(pred, appRes)
+}
+
+// RegisterApp registers a single app for a single address in the global
+// `appRegistry`.
+func RegisterApp(app App) {
+	appRegistry.RegisterApp(app)
+}
+
+// RegisterDefaultApp allows to specify a default `AppResolver` which is used by
+// the global `appRegistry` if no predicate matches. It must be set during the
+// initialization of the program, before any app is resolved.
+func RegisterDefaultApp(appRes AppResolver) {
+	appRegistry.RegisterDefaultApp(appRes)
+}
+
+// appRegistryReader is an io.Reader that carries the AppRegistry that should
+// be used to resolve apps while decoding from it.
+type appRegistryReader struct {
+	io.Reader
+	reg *AppRegistry
+}
+
+// WithAppRegistry returns a reader that reads from r and makes decoders of
+// Params, States and optional apps resolve app definitions with the given
+// registry instead of the global one.
+func WithAppRegistry(r io.Reader, reg *AppRegistry) io.Reader {
+	if reg == nil {
+		log.Panic("nil AppRegistry")
+	}
+	if rr, ok := r.(*appRegistryReader); ok {
+		r = rr.Reader
+	}
+	return &appRegistryReader{Reader: r, reg: reg}
+}
+
+// Scope returns a reader that reads from r and makes decoders resolve app
+// definitions with the registry, see WithAppRegistry. It can be used as the
+// reader scope of network connections to resolve the apps in incoming messages
+// with the registry.
+func (r *AppRegistry) Scope(rd io.Reader) io.Reader {
+	return WithAppRegistry(rd, r)
+}
+
+// AppRegistryOf returns the AppRegistry that r was wrapped with using
+// WithAppRegistry, or the global registry if r was not wrapped.
+func AppRegistryOf(r io.Reader) *AppRegistry {
+	if rr, ok := r.(*appRegistryReader); ok {
+		return rr.reg
+	}
+	return appRegistry
 }
@@ -15,10 +15,12 @@
 package channel
 
 import (
+	"bytes"
 	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"perun.network/go-perun/pkg/test"
 	"perun.network/go-perun/wallet"
@@ -26,48 +28,35 @@ import (
 )
 
 func TestAppRegistry(t *testing.T) {
-	test.OnlyOnce(t)
 	rng := test.Prng(t)
 
-	backup := struct {
-		resolvers  []appRegEntry
-		singles    map[wallet.AddrKey]App
-		defaultRes AppResolver
-	}{
-		resolvers:  appRegistry.resolvers,
-		singles:    appRegistry.singles,
-		defaultRes: appRegistry.defaultRes,
-	}
-
-	t.Cleanup(func() {
-		appRegistry.resolvers = backup.resolvers
-		appRegistry.singles = backup.singles
-		appRegistry.defaultRes = backup.defaultRes
-	})
-
 	t.Run("TestAppregistryPanicsAndErrors", func(t *testing.T) {
 		testAppRegistryPanicsAndErrors(t)
 	})
 	t.Run("TestAppRegistryIdentity", func(t *testing.T) {
 		testAppRegistryIdentity(t, rng)
 	})
+	t.Run("TestAppRegistryScoped", func(t *testing.T) {
+		testAppRegistryScoped(t, rng)
+	})
 }
 
 func testAppRegistryPanicsAndErrors(t *testing.T) {
-	resetAppRegistry()
-	assert.Panics(t, func() { RegisterAppResolver(nil, nil) })
-	assert.Panics(t, func() { RegisterAppResolver(func(wallet.Address) bool { return true }, nil) })
-	assert.Panics(t, func() { RegisterAppResolver(nil, &MockAppResolver{}) })
+	reg := NewAppRegistry("test")
+	assert.Panics(t, func() { reg.RegisterAppResolver(nil, nil) })
+	assert.Panics(t, func() { reg.RegisterAppResolver(func(wallet.Address) bool { return true }, nil) })
+	assert.Panics(t, func() { reg.RegisterAppResolver(nil, &MockAppResolver{}) })
 
-	assert.Panics(t, func() { RegisterApp(nil) })
-	assert.Panics(t, func() { RegisterApp(&MockApp{definition: nil}) })
+	assert.Panics(t, func() { reg.RegisterApp(nil) })
+	assert.Panics(t, func() { reg.RegisterApp(&MockApp{definition: nil}) })
 
-	assert.PanicsWithValue(t, "nil AppResolver", func() { RegisterDefaultApp(nil) })
-	assert.NotPanics(t, func() { RegisterDefaultApp(&MockAppResolver{}) })
+	assert.PanicsWithValue(t, "nil AppResolver", func() { reg.RegisterDefaultApp(nil) })
+	assert.NotPanics(t, func() { reg.RegisterDefaultApp(&MockAppResolver{}) })
 	assert.PanicsWithValue(t, "default resolver already set", func() {
-		RegisterDefaultApp(&MockAppResolver{})
+		reg.RegisterDefaultApp(&MockAppResolver{})
 	})
 
+	assert.Panics(t, func() { reg.Resolve(nil) })
 	assert.Panics(t, func() { Resolve(nil) })
 }
 
@@ -78,22 +67,22 @@ func (r defaultRes) Resolve(wallet.Address) (App, error) {
 }
 
 func testAppRegistryIdentity(t *testing.T, rng *rand.Rand) {
-	resetAppRegistry()
+	reg := NewAppRegistry("test")
 	a0 := newRandomMockApp(rng)
-	RegisterApp(a0)
-	assertIdentity(t, a0)
+	reg.RegisterApp(a0)
+	assertIdentity(t, reg, a0)
 
 	a1 := newRandomMockApp(rng)
-	RegisterAppResolver(a1.Def().Equals, &MockAppResolver{})
-	assertIdentity(t, a1)
+	reg.RegisterAppResolver(a1.Def().Equals, &MockAppResolver{})
+	assertIdentity(t, reg, a1)
 
 	a2 := newRandomMockApp(rng)
-	RegisterDefaultApp(defaultRes{a2.Def()})
-	assertIdentity(t, a2)
+	reg.RegisterDefaultApp(defaultRes{a2.Def()})
+	assertIdentity(t, reg, a2)
 }
 
-func assertIdentity(t *testing.T, expected App) {
-	actual, err := Resolve(expected.Def())
+func assertIdentity(t *testing.T, reg *AppRegistry, expected App) {
+	actual, err := reg.Resolve(expected.Def())
 	assert.NoError(t, err)
 	assert.True(t, actual.Def().Equals(expected.Def()))
 }
@@ -102,10 +91,29 @@ func newRandomMockApp(rng *rand.Rand) App {
 	return NewMockApp(wtest.NewRandomAddress(rng))
 }
 
-func resetAppRegistry() {
-	appRegistry.Lock()
-	defer appRegistry.Unlock()
-	appRegistry.resolvers = nil
-	appRegistry.singles = make(map[wallet.AddrKey]App)
-	appRegistry.defaultRes = nil
+func testAppRegistryScoped(t *testing.T, rng *rand.Rand) {
+	reg := NewAppRegistry("scoped")
+	assert.Equal(t, "scoped", reg.Name())
+	assert.Same(t, appRegistry, GlobalAppRegistry())
+
+	scoped := newRandomMockApp(rng)
+	reg.RegisterApp(scoped)
+	_, err := reg.Resolve(newRandomMockApp(rng).Def())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `app registry "scoped"`)
+	_, err = Resolve(scoped.Def())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `app registry "global"`)
+
+	// Decoding an app resolves it with the registry of the reader.
+	var buf bytes.Buffer
+	require.NoError(t, OptAppEnc{scoped}.Encode(&buf))
+	data := buf.Bytes()
+	var app App
+	assert.Same(t, GlobalAppRegistry(), AppRegistryOf(bytes.NewReader(data)))
+	assert.Error(t, OptAppDec{&app}.Decode(bytes.NewReader(data)))
+	r := reg.Scope(bytes.NewReader(data))
+	assert.Same(t, reg, AppRegistryOf(r))
+	require.NoError(t, OptAppDec{&app}.Decode(r))
+	assert.True(t, app.Def().Equals(scoped.Def()))
 }
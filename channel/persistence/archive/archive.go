@@ -0,0 +1,153 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	perunio "perun.network/go-perun/pkg/io"
+	"perun.network/go-perun/pkg/sortedkv"
+	"perun.network/go-perun/wire"
+)
+
+// KeepForever is a retention period that never prunes archived histories.
+const KeepForever time.Duration = -1
+
+var prefix = struct{ History, Settled string }{
+	History: "Hist:",
+	Settled: "Settled:",
+}
+
+// Entry is an archived transaction with the time it was archived.
+type Entry struct {
+	Time time.Time
+	channel.Transaction
+}
+
+// Archive is a persistence.PersistRestorer that forwards all calls to the
+// wrapped PersistRestorer and additionally archives all fully signed current
+// transactions of all channels in a sorted key-value database.
+type Archive struct {
+	persistence.PersistRestorer
+
+	db        sortedkv.Database
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewArchive creates an Archive that wraps pr and stores the histories in db.
+// The history of a settled channel is pruned by Prune after the retention
+// period has passed since its settlement, or never if retention is
+// KeepForever. Prune must be called by the application, e.g., by running
+// PrunePeriodically in a goroutine.
+func NewArchive(pr persistence.PersistRestorer, db sortedkv.Database, retention time.Duration) *Archive {
+	return &Archive{
+		PersistRestorer: pr,
+		db:              db,
+		retention:       retention,
+		now:             time.Now,
+	}
+}
+
+// ChannelCreated persists the channel with the wrapped PersistRestorer and
+// archives its parameters and current transaction.
+func (a *Archive) ChannelCreated(ctx context.Context, s channel.Source, peers []wire.Address, parent *channel.ID) error {
+	if err := a.PersistRestorer.ChannelCreated(ctx, s, peers, parent); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := s.Params().Encode(&buf); err != nil {
+		return errors.WithMessage(err, "encoding params")
+	}
+	if err := a.channelDB(s.ID()).PutBytes("params", buf.Bytes()); err != nil {
+		return errors.WithMessage(err, "archiving params")
+	}
+	return a.archive(s.ID(), s.CurrentTX())
+}
+
+// Enabled persists the channel with the wrapped PersistRestorer and archives
+// the new current transaction.
+func (a *Archive) Enabled(ctx context.Context, s channel.Source) error {
+	if err := a.PersistRestorer.Enabled(ctx, s); err != nil {
+		return err
+	}
+	return a.archive(s.ID(), s.CurrentTX())
+}
+
+// ChannelRemoved removes the channel from the wrapped PersistRestorer and
+// marks its history as settled. The history is kept until it is pruned.
+func (a *Archive) ChannelRemoved(ctx context.Context, id channel.ID) error {
+	if err := a.PersistRestorer.ChannelRemoved(ctx, id); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := perunio.Encode(&buf, a.now()); err != nil {
+		return errors.WithMessage(err, "encoding settlement time")
+	}
+	return errors.WithMessage(
+		sortedkv.NewTable(a.db, prefix.Settled).PutBytes(string(id[:]), buf.Bytes()),
+		"archiving settlement time")
+}
+
+// Close closes the wrapped PersistRestorer and the archive database.
+func (a *Archive) Close() error {
+	if err := a.PersistRestorer.Close(); err != nil {
+		return err
+	}
+	return a.db.Close()
+}
+
+// archive stores the transaction if it is fully signed and not yet archived.
+// Archived transactions are never overwritten.
+func (a *Archive) archive(id channel.ID, tx channel.Transaction) error {
+	if !tx.IsFullySigned() {
+		return nil
+	}
+	db := a.channelDB(id)
+	key := txKey(tx.Version)
+	if has, err := db.Has(key); err != nil {
+		return errors.WithMessage(err, "checking archive")
+	} else if has {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := perunio.Encode(&buf, a.now(), tx); err != nil {
+		return errors.WithMessage(err, "encoding archive entry")
+	}
+	return errors.WithMessage(db.PutBytes(key, buf.Bytes()), "archiving transaction")
+}
+
+// txKey creates the key of a transaction. The version is padded so that the
+// keys are sorted by version.
+func txKey(version uint64) string {
+	return fmt.Sprintf("tx:%020d", version)
+}
+
+// channelDB creates a prefixed database for the history of a channel.
+func (a *Archive) channelDB(id channel.ID) sortedkv.Database {
+	return sortedkv.NewTable(a.db, channelPrefix(id))
+}
+
+func channelPrefix(id channel.ID) string {
+	return prefix.History + string(id[:]) + ":"
+}
@@ -0,0 +1,175 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "perun.network/go-perun/backend/sim"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence/test"
+	perunio "perun.network/go-perun/pkg/io"
+	"perun.network/go-perun/pkg/sortedkv/memorydb"
+	pkgtest "perun.network/go-perun/pkg/test"
+	wtest "perun.network/go-perun/wallet/test"
+)
+
+func TestArchive_Generic(t *testing.T) {
+	a := NewArchive(test.NewPersistRestorer(t), memorydb.NewDatabase(), KeepForever)
+	test.GenericPersistRestorerTest(context.Background(), t, pkgtest.Prng(t), a, 4, 16)
+}
+
+func TestArchive_History(t *testing.T) {
+	ctx := context.Background()
+	rng := pkgtest.Prng(t)
+	const retention = time.Hour
+	now := time.Unix(1600000000, 0)
+	a := NewArchive(test.NewPersistRestorer(t), memorydb.NewDatabase(), retention)
+	a.now = func() time.Time { return now }
+
+	ch := test.NewClient(ctx, t, rng, a).NewChannel(t, wtest.NewRandomAddress(rng), nil)
+	ch.Init(t, rng)
+	ch.SignAll(t)
+	ch.EnableInit(t)
+	ch.SetFunded(t)
+	for i := 0; i < 3; i++ {
+		now = now.Add(time.Minute)
+		state := ch.State().Clone()
+		state.Version++
+		require.NoError(t, ch.Update(t, state, ch.Idx()))
+		if i == 1 {
+			// Discarded updates are not archived.
+			ch.DiscardUpdate(t)
+			continue
+		}
+		ch.SignAll(t)
+		ch.EnableUpdate(t)
+	}
+
+	history, err := a.History(ch.ID())
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	for i, version := range []uint64{0, 1, 2} {
+		assert.Equal(t, version, history[i].Version)
+	}
+	assert.Equal(t, ch.State(), history[2].State)
+	assert.True(t, now.Equal(history[2].Time))
+	require.NoError(t, a.Verify(ch.ID()))
+
+	t.Run("tampered", func(t *testing.T) {
+		e := history[2]
+		e.Version++
+		var buf bytes.Buffer
+		require.NoError(t, perunio.Encode(&buf, e.Time, e.Transaction))
+		db := a.channelDB(ch.ID())
+		key := txKey(e.Version)
+		require.NoError(t, db.PutBytes(key, buf.Bytes()))
+		assert.Error(t, a.Verify(ch.ID()))
+		require.NoError(t, db.Delete(key))
+	})
+
+	t.Run("foreign params", func(t *testing.T) {
+		params, err := a.Params(ch.ID())
+		require.NoError(t, err)
+		// Params of another channel and params that were changed without
+		// updating the ID.
+		foreign, err := channel.NewParams(params.ChallengeDuration, params.Parts, params.App,
+			new(big.Int).Add(params.Nonce, big.NewInt(1)))
+		require.NoError(t, err)
+		tampered := *params
+		tampered.ChallengeDuration++
+		db := a.channelDB(ch.ID())
+		var buf bytes.Buffer
+		for _, p := range []*channel.Params{foreign, &tampered} {
+			buf.Reset()
+			require.NoError(t, p.Encode(&buf))
+			require.NoError(t, db.PutBytes("params", buf.Bytes()))
+			assert.Error(t, a.Verify(ch.ID()))
+		}
+		buf.Reset()
+		require.NoError(t, params.Encode(&buf))
+		require.NoError(t, db.PutBytes("params", buf.Bytes()))
+		require.NoError(t, a.Verify(ch.ID()))
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		n, err := a.Prune()
+		require.NoError(t, err)
+		assert.Zero(t, n, "unsettled channel pruned")
+
+		require.NoError(t, a.ChannelRemoved(ctx, ch.ID()))
+		settledAt, settled, err := a.Settled(ch.ID())
+		require.NoError(t, err)
+		require.True(t, settled)
+		assert.True(t, now.Equal(settledAt))
+
+		now = now.Add(retention - time.Second)
+		n, err = a.Prune()
+		require.NoError(t, err)
+		assert.Zero(t, n, "pruned before retention period")
+		require.NoError(t, a.Verify(ch.ID()))
+
+		now = now.Add(time.Second)
+		n, err = a.Prune()
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		history, err := a.History(ch.ID())
+		require.NoError(t, err)
+		assert.Empty(t, history)
+		_, settled, err = a.Settled(ch.ID())
+		require.NoError(t, err)
+		assert.False(t, settled)
+	})
+}
+
+func TestArchive_PrunePeriodically(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rng := pkgtest.Prng(t)
+	a := NewArchive(test.NewPersistRestorer(t), memorydb.NewDatabase(), time.Hour)
+	settledAt := time.Unix(1600000000, 0)
+	a.now = func() time.Time { return settledAt }
+
+	ch := test.NewClient(ctx, t, rng, a).NewChannel(t, wtest.NewRandomAddress(rng), nil)
+	ch.Init(t, rng)
+	ch.SignAll(t)
+	ch.EnableInit(t)
+	require.NoError(t, a.ChannelRemoved(ctx, ch.ID()))
+	a.now = func() time.Time { return settledAt.Add(time.Hour) }
+
+	pruneCtx, stop := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() { done <- a.PrunePeriodically(pruneCtx, time.Millisecond) }()
+	for {
+		if _, settled, err := a.Settled(ch.ID()); err == nil && !settled {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("history not pruned")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	stop()
+	assert.True(t, errors.Is(<-done, context.Canceled))
+}
@@ -0,0 +1,24 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package archive implements an append-only archive of all fully signed
+// channel states for audits and as dispute evidence.
+//
+// An Archive wraps a persistence.PersistRestorer, to which it forwards all
+// calls. Additionally, it stores every fully signed current transaction of a
+// channel together with the time it was persisted. The history of a channel
+// can be listed and verified. After a channel is settled, its history is kept
+// for the configured retention period and then removed by Prune, which the
+// application either calls itself or runs periodically with PrunePeriodically.
+package archive // import "perun.network/go-perun/channel/persistence/archive"
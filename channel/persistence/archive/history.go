@@ -0,0 +1,180 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	perunio "perun.network/go-perun/pkg/io"
+	"perun.network/go-perun/pkg/sortedkv"
+)
+
+// History returns all archived transactions of the channel, ordered by
+// version.
+func (a *Archive) History(id channel.ID) ([]Entry, error) {
+	it := a.channelDB(id).NewIteratorWithPrefix("tx:")
+	var entries []Entry
+	for it.Next() {
+		var e Entry
+		if err := perunio.Decode(bytes.NewReader(it.ValueBytes()), &e.Time, &e.Transaction); err != nil {
+			it.Close() // nolint:errcheck
+			return nil, errors.WithMessagef(err, "decoding archive entry %s", it.Key())
+		}
+		entries = append(entries, e)
+	}
+	return entries, errors.WithMessage(it.Close(), "iterating archive")
+}
+
+// Params returns the archived parameters of the channel.
+func (a *Archive) Params(id channel.ID) (*channel.Params, error) {
+	b, err := a.channelDB(id).GetBytes("params")
+	if err != nil {
+		return nil, errors.WithMessage(err, "getting archived params")
+	}
+	params := new(channel.Params)
+	return params, errors.WithMessage(params.Decode(bytes.NewReader(b)), "decoding params")
+}
+
+// Verify verifies the history of the channel. The archived parameters and all
+// transactions must belong to the channel, carry valid signatures of all participants and have strictly
+// increasing versions and non-decreasing timestamps.
+func (a *Archive) Verify(id channel.ID) error {
+	params, err := a.Params(id)
+	if err != nil {
+		return err
+	}
+	if params.ID() != id || channel.CalcID(params) != id {
+		return errors.Errorf("archived params belong to channel %x", channel.CalcID(params))
+	}
+	entries, err := a.History(id)
+	if err != nil {
+		return err
+	}
+
+	for i, e := range entries {
+		if e.State.ID != id {
+			return errors.Errorf("entry %d: state belongs to channel %x", i, e.State.ID)
+		}
+		if i > 0 {
+			if prev := entries[i-1]; e.Version <= prev.Version {
+				return errors.Errorf("entry %d: version %d not greater than %d", i, e.Version, prev.Version)
+			} else if e.Time.Before(prev.Time) {
+				return errors.Errorf("entry %d: archived before previous entry", i)
+			}
+		}
+		if len(e.Sigs) != len(params.Parts) {
+			return errors.Errorf("entry %d: %d signatures for %d participants", i, len(e.Sigs), len(params.Parts))
+		}
+		for j, sig := range e.Sigs {
+			if ok, err := channel.Verify(params.Parts[j], params, e.State, sig); err != nil {
+				return errors.WithMessagef(err, "entry %d: verifying signature %d", i, j)
+			} else if !ok {
+				return errors.Errorf("entry %d: invalid signature of participant %d", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+// Settled returns when the channel was settled, or false if it is not settled.
+func (a *Archive) Settled(id channel.ID) (time.Time, bool, error) {
+	settled := sortedkv.NewTable(a.db, prefix.Settled)
+	if has, err := settled.Has(string(id[:])); err != nil || !has {
+		return time.Time{}, false, errors.WithMessage(err, "getting settlement time")
+	}
+	b, err := settled.GetBytes(string(id[:]))
+	if err != nil {
+		return time.Time{}, false, errors.WithMessage(err, "getting settlement time")
+	}
+	var t time.Time
+	return t, true, errors.WithMessage(perunio.Decode(bytes.NewReader(b), &t), "decoding settlement time")
+}
+
+// Prune removes the histories of all channels that were settled at least the
+// retention period ago. It returns the number of pruned channels. Prune is not
+// called automatically, see PrunePeriodically.
+func (a *Archive) Prune() (int, error) {
+	if a.retention == KeepForever {
+		return 0, nil
+	}
+	deadline := a.now().Add(-a.retention)
+
+	var expired []string
+	it := sortedkv.NewTable(a.db, prefix.Settled).NewIterator()
+	for it.Next() {
+		var t time.Time
+		if err := perunio.Decode(bytes.NewReader(it.ValueBytes()), &t); err != nil {
+			it.Close() // nolint:errcheck
+			return 0, errors.WithMessage(err, "decoding settlement time")
+		}
+		if !t.After(deadline) {
+			expired = append(expired, it.Key())
+		}
+	}
+	if err := it.Close(); err != nil {
+		return 0, errors.WithMessage(err, "iterating settled channels")
+	}
+
+	for _, id := range expired {
+		if err := a.prune(id); err != nil {
+			return 0, errors.WithMessagef(err, "pruning channel %x", id)
+		}
+	}
+	return len(expired), nil
+}
+
+// prune removes the history and the settlement time of the channel in one
+// batch.
+func (a *Archive) prune(id string) error {
+	b := a.db.NewBatch()
+	it := a.db.NewIteratorWithPrefix(prefix.History + id + ":")
+	for it.Next() {
+		if err := b.Delete(it.Key()); err != nil {
+			it.Close() // nolint:errcheck
+			return errors.WithMessage(err, "deleting entry")
+		}
+	}
+	if err := it.Close(); err != nil {
+		return errors.WithMessage(err, "iterating history")
+	}
+	if err := b.Delete(prefix.Settled + id); err != nil {
+		return errors.WithMessage(err, "deleting settlement time")
+	}
+	return errors.WithMessage(b.Apply(), "applying batch")
+}
+
+// PrunePeriodically calls Prune every interval until the context is done, in
+// which case it returns the context's error, or until Prune fails.
+func (a *Archive) PrunePeriodically(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if _, err := a.Prune(); err != nil {
+			return errors.WithMessage(err, "pruning")
+		}
+	}
+}
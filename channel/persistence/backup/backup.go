@@ -0,0 +1,291 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	perunio "perun.network/go-perun/pkg/io"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// Version is the version of the backup format written by Write.
+const Version uint16 = 1
+
+var magic = []byte("PERUNBAK")
+
+var (
+	// ErrUnsupportedVersion is returned when reading a backup with an unknown
+	// format version.
+	ErrUnsupportedVersion = errors.New("unsupported backup version")
+	// ErrInvalidSignature is returned when the signature of a backup file or of
+	// a channel state in it is invalid.
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Write writes the channels to w as a backup file signed by signer. If aead is
+// not nil, the channels are encrypted with it.
+//
+// The file consists of the magic bytes "PERUNBAK", the format version, whether
+// the payload is encrypted, the signer's address, the payload and the signer's
+// signature over all previous fields.
+func Write(w io.Writer, chans []*persistence.Channel, signer wallet.Account, aead cipher.AEAD) error {
+	var payload bytes.Buffer
+	if err := encodeChannels(&payload, chans); err != nil {
+		return err
+	}
+	data := payload.Bytes()
+	if aead != nil {
+		var err error
+		if data, err = seal(aead, data); err != nil {
+			return err
+		}
+	}
+
+	var file bytes.Buffer
+	file.Write(magic)
+	if err := perunio.Encode(&file, Version, aead != nil, signer.Address()); err != nil {
+		return errors.WithMessage(err, "encoding header")
+	}
+	if err := encodeBytes(&file, data); err != nil {
+		return errors.WithMessage(err, "encoding payload")
+	}
+	sig, err := signer.SignData(file.Bytes())
+	if err != nil {
+		return errors.WithMessage(err, "signing backup")
+	}
+	if err := encodeBytes(&file, sig); err != nil {
+		return errors.WithMessage(err, "encoding signature")
+	}
+
+	_, err = file.WriteTo(w)
+	return errors.Wrap(err, "writing backup")
+}
+
+// Read reads a backup file from r that was written by Write. The backup has to
+// be signed by signer and all channel states have to carry valid signatures of
+// the respective participants. If the backup is encrypted, aead must be the
+// cipher it was encrypted with.
+func Read(r io.Reader, signer wallet.Address, aead cipher.AEAD) ([]*persistence.Channel, error) {
+	file, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading backup")
+	}
+	if !bytes.HasPrefix(file, magic) {
+		return nil, errors.New("not a backup file")
+	}
+	buf := bytes.NewReader(file[len(magic):])
+
+	var (
+		version   uint16
+		encrypted bool
+	)
+	if err := perunio.Decode(buf, &version); err != nil {
+		return nil, errors.WithMessage(err, "decoding version")
+	}
+	if version != Version {
+		return nil, errors.WithMessagef(ErrUnsupportedVersion, "version %d", version)
+	}
+	if err := perunio.Decode(buf, &encrypted); err != nil {
+		return nil, errors.WithMessage(err, "decoding header")
+	}
+	fileSigner, err := wallet.DecodeAddress(buf)
+	if err != nil {
+		return nil, errors.WithMessage(err, "decoding signer")
+	}
+	data, err := decodeBytes(buf)
+	if err != nil {
+		return nil, errors.WithMessage(err, "decoding payload")
+	}
+	signed := file[:len(file)-buf.Len()]
+	sig, err := decodeBytes(buf)
+	if err != nil {
+		return nil, errors.WithMessage(err, "decoding signature")
+	}
+	if buf.Len() != 0 {
+		return nil, errors.Errorf("%d trailing bytes after signature", buf.Len())
+	}
+
+	if !fileSigner.Equals(signer) {
+		return nil, errors.WithMessagef(ErrInvalidSignature, "backup signed by %v, expected %v", fileSigner, signer)
+	}
+	if ok, err := wallet.VerifySignature(signed, sig, signer); err != nil {
+		return nil, errors.WithMessage(err, "verifying backup signature")
+	} else if !ok {
+		return nil, errors.WithMessage(ErrInvalidSignature, "backup")
+	}
+
+	if encrypted {
+		if aead == nil {
+			return nil, errors.New("backup is encrypted but no cipher was given")
+		}
+		if data, err = open(aead, data); err != nil {
+			return nil, err
+		}
+	}
+
+	chans, err := decodeChannels(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for _, ch := range chans {
+		if err := verifyChannel(ch); err != nil {
+			return nil, errors.WithMessagef(err, "channel %x", ch.ID())
+		}
+	}
+	return chans, nil
+}
+
+// encodeBytes writes b with a length prefix.
+func encodeBytes(w io.Writer, b []byte) error {
+	return perunio.Encode(w, uint32(len(b)), b)
+}
+
+// decodeBytes reads a byte slice that was written with encodeBytes.
+func decodeBytes(r *bytes.Reader) ([]byte, error) {
+	var n uint32
+	if err := perunio.Decode(r, &n); err != nil {
+		return nil, err
+	}
+	if int64(n) > int64(r.Len()) {
+		return nil, errors.Errorf("length %d exceeds remaining %d bytes", n, r.Len())
+	}
+	b := make([]byte, n)
+	return b, perunio.Decode(r, &b)
+}
+
+// seal encrypts data with a fresh random nonce, which is prepended to the
+// ciphertext.
+func seal(aead cipher.AEAD, data []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "generating nonce")
+	}
+	return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+// open decrypts data that was encrypted with seal.
+func open(aead cipher.AEAD, data []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("decrypting backup: payload too short")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	return plain, errors.Wrap(err, "decrypting backup")
+}
+
+func encodeChannels(w io.Writer, chans []*persistence.Channel) error {
+	if err := perunio.Encode(w, uint32(len(chans))); err != nil {
+		return errors.WithMessage(err, "encoding number of channels")
+	}
+	for _, ch := range chans {
+		hasParent, hasReceiver := ch.Parent != nil, ch.Receiver != nil
+		if err := perunio.Encode(w,
+			ch.IdxV, ch.ParamsV, ch.CurrentTXV, ch.StagingTXV, ch.PhaseV,
+			wire.AddressesWithLen(ch.PeersV), hasParent, hasReceiver); err != nil {
+			return errors.WithMessagef(err, "encoding channel %x", ch.ID())
+		}
+		if hasParent {
+			if err := perunio.Encode(w, *ch.Parent); err != nil {
+				return errors.WithMessagef(err, "encoding parent of channel %x", ch.ID())
+			}
+		}
+		if hasReceiver {
+			if err := perunio.Encode(w, ch.Receiver); err != nil {
+				return errors.WithMessagef(err, "encoding receiver of channel %x", ch.ID())
+			}
+		}
+	}
+	return nil
+}
+
+func decodeChannels(r io.Reader) ([]*persistence.Channel, error) {
+	var n uint32
+	if err := perunio.Decode(r, &n); err != nil {
+		return nil, errors.WithMessage(err, "decoding number of channels")
+	}
+	chans := make([]*persistence.Channel, n)
+	for i := range chans {
+		ch := persistence.NewChannel()
+		var hasParent, hasReceiver bool
+		if err := perunio.Decode(r,
+			&ch.IdxV, ch.ParamsV, &ch.CurrentTXV, &ch.StagingTXV, &ch.PhaseV,
+			(*wire.AddressesWithLen)(&ch.PeersV), &hasParent, &hasReceiver); err != nil {
+			return nil, errors.WithMessagef(err, "decoding channel %d", i)
+		}
+		if hasParent {
+			ch.Parent = new(channel.ID)
+			if err := perunio.Decode(r, ch.Parent); err != nil {
+				return nil, errors.WithMessagef(err, "decoding parent of channel %d", i)
+			}
+		}
+		if hasReceiver {
+			var err error
+			if ch.Receiver, err = wallet.DecodeAddress(r); err != nil {
+				return nil, errors.WithMessagef(err, "decoding receiver of channel %d", i)
+			}
+		}
+		chans[i] = ch
+	}
+	return chans, nil
+}
+
+// verifyChannel verifies that the current state of the channel is signed by
+// all participants and that all signatures on the staging state are valid.
+func verifyChannel(ch *persistence.Channel) error {
+	if int(ch.IdxV) >= len(ch.ParamsV.Parts) {
+		return errors.Errorf("index %d out of range", ch.IdxV)
+	}
+	if err := verifyTX(ch.ParamsV, ch.CurrentTXV, true); err != nil {
+		return errors.WithMessage(err, "current transaction")
+	}
+	return errors.WithMessage(verifyTX(ch.ParamsV, ch.StagingTXV, false), "staging transaction")
+}
+
+// verifyTX verifies all signatures of the transaction. If complete is true, all
+// participants' signatures must be present.
+func verifyTX(params *channel.Params, tx channel.Transaction, complete bool) error {
+	if tx.State == nil {
+		return nil
+	}
+	if tx.State.ID != params.ID() {
+		return errors.New("state does not belong to channel")
+	}
+	if len(tx.Sigs) != len(params.Parts) {
+		return errors.Errorf("%d signatures for %d participants", len(tx.Sigs), len(params.Parts))
+	}
+	for i, sig := range tx.Sigs {
+		if sig == nil {
+			if complete {
+				return errors.Errorf("missing signature of participant %d", i)
+			}
+			continue
+		}
+		if ok, err := channel.Verify(params.Parts[i], params, tx.State, sig); err != nil {
+			return errors.WithMessagef(err, "verifying signature of participant %d", i)
+		} else if !ok {
+			return errors.WithMessagef(ErrInvalidSignature, "participant %d", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	gosql "database/sql"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	_ "perun.network/go-perun/backend/sim"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	"perun.network/go-perun/channel/persistence/backup"
+	"perun.network/go-perun/channel/persistence/keyvalue"
+	"perun.network/go-perun/channel/persistence/sql"
+	"perun.network/go-perun/channel/persistence/test"
+	"perun.network/go-perun/pkg/sortedkv/memorydb"
+	pkgtest "perun.network/go-perun/pkg/test"
+	wtest "perun.network/go-perun/wallet/test"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	rng := pkgtest.Prng(t)
+	src, err := keyvalue.OpenPersistRestorer(memorydb.NewDatabase())
+	require.NoError(t, err)
+	chans := newChannels(t, rng, src)
+	signer := wtest.NewRandomAccount(rng)
+	aead := newAEAD(t, rng)
+
+	var buf bytes.Buffer
+	require.NoError(t, backup.Export(ctx, &buf, src, signer, aead))
+
+	t.Run("import", func(t *testing.T) {
+		dst := test.NewPersistRestorer(t)
+		n, err := backup.Import(ctx, bytes.NewReader(buf.Bytes()), dst, signer.Address(), aead)
+		require.NoError(t, err)
+		assert.Equal(t, len(chans), n)
+		for _, ch := range chans {
+			requireEqualChannel(ctx, t, src, dst, ch)
+		}
+
+		// Importing again does not change anything.
+		n, err = backup.Import(ctx, bytes.NewReader(buf.Bytes()), dst, signer.Address(), aead)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("wrong signer", func(t *testing.T) {
+		_, err := backup.Import(ctx, bytes.NewReader(buf.Bytes()), test.NewPersistRestorer(t),
+			wtest.NewRandomAddress(rng), aead)
+		assert.True(t, errors.Is(err, backup.ErrInvalidSignature))
+	})
+
+	t.Run("wrong cipher", func(t *testing.T) {
+		_, err := backup.Read(bytes.NewReader(buf.Bytes()), signer.Address(), newAEAD(t, rng))
+		assert.Error(t, err)
+		_, err = backup.Read(bytes.NewReader(buf.Bytes()), signer.Address(), nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		file := append([]byte(nil), buf.Bytes()...)
+		file[len(file)/2] ^= 0xff
+		_, err := backup.Read(bytes.NewReader(file), signer.Address(), aead)
+		assert.Error(t, err)
+	})
+
+	t.Run("newer not overwritten", func(t *testing.T) {
+		// Update the first channel after the backup was made.
+		ch := chans[0]
+		state := ch.State().Clone()
+		state.Version++
+		require.NoError(t, ch.Update(t, state, ch.Idx()))
+		ch.SignAll(t)
+		ch.EnableUpdate(t)
+
+		var newer bytes.Buffer
+		require.NoError(t, backup.Export(ctx, &newer, src, signer, nil))
+
+		n, err := backup.Import(ctx, bytes.NewReader(buf.Bytes()), src, signer.Address(), aead)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+		ch.AssertPersisted(ctx, t)
+
+		// The newer backup overwrites the older channel.
+		dsts := map[string]persistence.PersistRestorer{
+			"remove and create": test.NewPersistRestorer(t),
+			"keyvalue":          newKeyValuePersistRestorer(t),
+			"sql":               newSQLPersistRestorer(t),
+		}
+		for name, dst := range dsts {
+			dst := dst
+			t.Run(name, func(t *testing.T) {
+				_, err := backup.Import(ctx, bytes.NewReader(buf.Bytes()), dst, signer.Address(), aead)
+				require.NoError(t, err)
+				n, err := backup.Import(ctx, bytes.NewReader(newer.Bytes()), dst, signer.Address(), nil)
+				require.NoError(t, err)
+				assert.Equal(t, 1, n)
+				for _, ch := range chans {
+					requireEqualChannel(ctx, t, src, dst, ch)
+				}
+				// The peer table entries are replaced, too.
+				expected, err := src.ActivePeers(ctx)
+				require.NoError(t, err)
+				peers, err := dst.ActivePeers(ctx)
+				require.NoError(t, err)
+				assert.Len(t, peers, len(expected))
+			})
+		}
+	})
+}
+
+func TestExport_ChannelWithoutPeers(t *testing.T) {
+	ctx := context.Background()
+	rng := pkgtest.Prng(t)
+	src := newKeyValuePersistRestorer(t)
+	chans := newChannels(t, rng, src)
+	signer := wtest.NewRandomAccount(rng)
+
+	// Remove the peers of the first channel, so that it is not found via
+	// ActivePeers.
+	ch, err := src.RestoreChannel(ctx, chans[0].ID())
+	require.NoError(t, err)
+	ch.PeersV = nil
+	require.NoError(t, src.ReplaceChannel(ctx, ch))
+
+	var buf bytes.Buffer
+	require.NoError(t, backup.Export(ctx, &buf, src, signer, nil))
+	exported, err := backup.Read(&buf, signer.Address(), nil)
+	require.NoError(t, err)
+	require.Len(t, exported, len(chans))
+	ids := make(map[channel.ID]bool)
+	for _, ch := range exported {
+		ids[ch.ID()] = true
+	}
+	for _, ch := range chans {
+		assert.True(t, ids[ch.ID()], "channel %x not exported", ch.ID())
+	}
+}
+
+var (
+	_ backup.Replacer    = (*keyvalue.PersistRestorer)(nil)
+	_ backup.Replacer    = (*sql.PersistRestorer)(nil)
+	_ backup.AllRestorer = (*keyvalue.PersistRestorer)(nil)
+)
+
+// newKeyValuePersistRestorer creates a keyvalue.PersistRestorer on a new
+// in-memory database.
+func newKeyValuePersistRestorer(t *testing.T) *keyvalue.PersistRestorer {
+	pr, err := keyvalue.OpenPersistRestorer(memorydb.NewDatabase())
+	require.NoError(t, err)
+	return pr
+}
+
+// newSQLPersistRestorer creates a sql.PersistRestorer on a new SQLite database.
+func newSQLPersistRestorer(t *testing.T) *sql.PersistRestorer {
+	db, err := gosql.Open("sqlite", filepath.Join(t.TempDir(), "channels.db"))
+	require.NoError(t, err)
+	// SQLite does not support concurrent writers.
+	db.SetMaxOpenConns(1)
+	pr, err := sql.NewPersistRestorer(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, pr.Close()) })
+	return pr
+}
+
+func TestRead_InvalidChannelSignature(t *testing.T) {
+	ctx := context.Background()
+	rng := pkgtest.Prng(t)
+	src := test.NewPersistRestorer(t)
+	chans := newChannels(t, rng, src)
+	signer := wtest.NewRandomAccount(rng)
+
+	restored, err := src.RestoreChannel(ctx, chans[0].ID())
+	require.NoError(t, err)
+	restored.CurrentTXV.Sigs[0], restored.CurrentTXV.Sigs[1] =
+		restored.CurrentTXV.Sigs[1], restored.CurrentTXV.Sigs[0]
+
+	var buf bytes.Buffer
+	require.NoError(t, backup.Write(&buf, []*persistence.Channel{restored}, signer, nil))
+	_, err = backup.Read(&buf, signer.Address(), nil)
+	assert.True(t, errors.Is(err, backup.ErrInvalidSignature))
+}
+
+// newChannels persists a funded channel, a funded sub-channel with a withdrawal
+// receiver and a channel without an initial state.
+func newChannels(t *testing.T, rng *rand.Rand, pr persistence.PersistRestorer) []*test.Channel {
+	c := test.NewClient(context.Background(), t, rng, pr)
+	peer := wtest.NewRandomAddress(rng)
+
+	parent := c.NewChannel(t, peer, nil)
+	sub := c.NewChannel(t, peer, parent)
+	for _, ch := range []*test.Channel{parent, sub} {
+		ch.Init(t, rng)
+		ch.SignAll(t)
+		ch.EnableInit(t)
+		ch.SetFunded(t)
+	}
+	sub.SetReceiver(t, wtest.NewRandomAddress(rng))
+
+	return []*test.Channel{parent, sub, c.NewChannel(t, peer, nil)}
+}
+
+// requireEqualChannel requires that both restorers contain the same data for
+// the channel.
+func requireEqualChannel(ctx context.Context, t *testing.T, expected, actual persistence.Restorer, ch *test.Channel) {
+	exp, err := expected.RestoreChannel(ctx, ch.ID())
+	require.NoError(t, err)
+	act, err := actual.RestoreChannel(ctx, ch.ID())
+	require.NoError(t, err)
+
+	ch.RequireEqual(t, act)
+	require.Equal(t, exp.Parent, act.Parent)
+	require.Len(t, act.PeersV, len(exp.PeersV))
+	for i, p := range exp.PeersV {
+		require.True(t, p.Equals(act.PeersV[i]))
+	}
+	if exp.Receiver == nil {
+		require.Nil(t, act.Receiver)
+	} else {
+		require.True(t, exp.Receiver.Equals(act.Receiver))
+	}
+}
+
+func newAEAD(t *testing.T, rng *rand.Rand) cipher.AEAD {
+	key := make([]byte, 32)
+	rng.Read(key)
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	return aead
+}
@@ -0,0 +1,23 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package backup implements the export of persisted channels into a single
+// backup file and their import into any persistence.PersistRestorer.
+//
+// A backup file is signed by the account that exported it and its payload is
+// optionally encrypted with an AEAD cipher supplied by the application. When
+// importing, the file signature and the signatures of all channel states are
+// verified, and a channel is only imported if the backup contains a newer
+// state than the one already persisted.
+package backup // import "perun.network/go-perun/channel/persistence/backup"
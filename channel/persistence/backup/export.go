@@ -0,0 +1,166 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"crypto/cipher"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	"perun.network/go-perun/wallet"
+)
+
+// Replacer is implemented by persisters that can atomically replace a
+// persisted channel, e.g., the keyvalue and sql PersistRestorers.
+type Replacer interface {
+	// ReplaceChannel atomically replaces a persisted channel with ch,
+	// including its peers, parent and receiver. If the channel is not
+	// persisted yet, it is inserted.
+	ReplaceChannel(ctx context.Context, ch *persistence.Channel) error
+}
+
+// AllRestorer is implemented by restorers that can iterate over all persisted
+// channels, e.g., the keyvalue PersistRestorer.
+type AllRestorer interface {
+	// RestoreAll returns an iterator over all persisted channels.
+	RestoreAll() (persistence.ChannelIterator, error)
+}
+
+// Export writes all channels of the restorer to w as a backup file signed by
+// signer. If aead is not nil, the backup is encrypted with it.
+//
+// If r does not implement AllRestorer, only channels that are persisted with
+// at least one peer are exported because the restorer only lists channels per
+// active peer.
+func Export(ctx context.Context, w io.Writer, r persistence.Restorer, signer wallet.Account, aead cipher.AEAD) error {
+	chans, err := restoreAll(ctx, r)
+	if err != nil {
+		return err
+	}
+	return Write(w, chans, signer, aead)
+}
+
+// Import reads a backup file that was signed by signer from r and persists its
+// channels with pr. A channel is skipped if pr already contains the same or a
+// newer version of it. If the backup is encrypted, aead must be the cipher it
+// was encrypted with. It returns the number of imported channels.
+//
+// If pr implements Replacer, each channel is written atomically. Otherwise, an
+// outdated channel is removed before the newer version is written, so a crash
+// during the import can lose the channel, and the receiver is written
+// separately. The import should then be repeated.
+func Import(ctx context.Context, r io.Reader, pr persistence.PersistRestorer, signer wallet.Address, aead cipher.AEAD) (int, error) {
+	chans, err := Read(r, signer, aead)
+	if err != nil {
+		return 0, err
+	}
+	existing, err := restoreAll(ctx, pr)
+	if err != nil {
+		return 0, err
+	}
+	persisted := make(map[channel.ID]*persistence.Channel, len(existing))
+	for _, ch := range existing {
+		persisted[ch.ID()] = ch
+	}
+
+	imported := 0
+	for _, ch := range chans {
+		old, ok := persisted[ch.ID()]
+		if ok && !isNewer(ch, old) {
+			continue
+		}
+		if err := importChannel(ctx, pr, ch, ok); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// importChannel persists ch with pr, replacing the persisted channel if exists
+// is true.
+func importChannel(ctx context.Context, pr persistence.PersistRestorer, ch *persistence.Channel, exists bool) error {
+	if r, ok := pr.(Replacer); ok {
+		return errors.WithMessagef(r.ReplaceChannel(ctx, ch), "replacing channel %x", ch.ID())
+	}
+	if exists {
+		if err := pr.ChannelRemoved(ctx, ch.ID()); err != nil {
+			return errors.WithMessagef(err, "removing outdated channel %x", ch.ID())
+		}
+	}
+	if err := pr.ChannelCreated(ctx, ch, ch.PeersV, ch.Parent); err != nil {
+		return errors.WithMessagef(err, "persisting channel %x", ch.ID())
+	}
+	if ch.Receiver != nil {
+		if err := pr.ReceiverChanged(ctx, ch.ID(), ch.Receiver); err != nil {
+			return errors.WithMessagef(err, "persisting receiver of channel %x", ch.ID())
+		}
+	}
+	return nil
+}
+
+// isNewer returns whether the current state of ch has a higher version than
+// the current state of old.
+func isNewer(ch, old *persistence.Channel) bool {
+	if ch.CurrentTXV.State == nil {
+		return false
+	}
+	return old.CurrentTXV.State == nil || ch.CurrentTXV.Version > old.CurrentTXV.Version
+}
+
+// restoreAll restores all channels of the restorer. If r does not implement
+// AllRestorer, the channels of all active peers are restored, so channels
+// without peers are not found.
+func restoreAll(ctx context.Context, r persistence.Restorer) ([]*persistence.Channel, error) {
+	if r, ok := r.(AllRestorer); ok {
+		it, err := r.RestoreAll()
+		if err != nil {
+			return nil, errors.WithMessage(err, "restoring all channels")
+		}
+		var chans []*persistence.Channel
+		for it.Next(ctx) {
+			chans = append(chans, it.Channel())
+		}
+		return chans, errors.WithMessage(it.Close(), "restoring all channels")
+	}
+
+	peers, err := r.ActivePeers(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "restoring active peers")
+	}
+
+	var chans []*persistence.Channel
+	seen := make(map[channel.ID]bool)
+	for _, peer := range peers {
+		it, err := r.RestorePeer(peer)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "restoring channels of peer %v", peer)
+		}
+		for it.Next(ctx) {
+			if ch := it.Channel(); !seen[ch.ID()] {
+				seen[ch.ID()] = true
+				chans = append(chans, ch)
+			}
+		}
+		if err := it.Close(); err != nil {
+			return nil, errors.WithMessagef(err, "restoring channels of peer %v", peer)
+		}
+	}
+	return chans, nil
+}
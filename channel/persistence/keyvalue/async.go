@@ -0,0 +1,85 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyvalue
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	"perun.network/go-perun/pkg/sortedkv"
+	"perun.network/go-perun/pkg/sortedkv/groupcommit"
+)
+
+var (
+	_ persistence.PersistRestorer   = (*AsyncPersistRestorer)(nil)
+	_ persistence.AppRegistryScoper = (*AsyncPersistRestorer)(nil)
+)
+
+// AsyncPersistRestorer is a PersistRestorer that writes to its database in
+// group commits, see package groupcommit. All persister calls except Enabled
+// return before their writes are committed. Enabled returns after the enabled
+// transaction and all earlier writes of all channels are committed, so that
+// the cost of syncing the database is shared by concurrent channel updates.
+//
+// Enabled only returns the error of its own transaction. If another queued
+// write fails, the next Flush returns the error.
+type AsyncPersistRestorer struct {
+	*PersistRestorer
+	db *groupcommit.Database
+}
+
+// OpenAsyncPersistRestorer migrates the supplied database like
+// OpenPersistRestorer and creates an AsyncPersistRestorer that writes to it in
+// group commits. The interval is passed to groupcommit.NewDatabase. For
+// durability, db must sync its batches, e.g., a leveldb.Database with Sync
+// set.
+func OpenAsyncPersistRestorer(db sortedkv.Database, interval time.Duration) (*AsyncPersistRestorer, error) {
+	if err := Migrate(db); err != nil {
+		return nil, errors.WithMessage(err, "migrating database")
+	}
+	gdb := groupcommit.NewDatabase(db, interval)
+	return &AsyncPersistRestorer{
+		PersistRestorer: newPersistRestorer(gdb, channel.GlobalAppRegistry()),
+		db:              gdb,
+	}, nil
+}
+
+// Enabled persists the channel's staging and current transaction, and phase,
+// and returns after they are committed.
+func (pr *AsyncPersistRestorer) Enabled(_ context.Context, s channel.Source) error {
+	b := pr.db.NewBatch().(*groupcommit.Batch)
+	if err := putEnabled(channelBatch(b, s.ID()), s); err != nil {
+		return err
+	}
+	return errors.WithMessage(b.Commit(), "committing batch")
+}
+
+// Flush returns after all previous writes are committed.
+func (pr *AsyncPersistRestorer) Flush() error {
+	return errors.WithMessage(pr.db.Flush(), "committing writes")
+}
+
+// WithAppRegistry returns an AsyncPersistRestorer on the same database that
+// resolves the apps of restored channels with the given app registry.
+func (pr *AsyncPersistRestorer) WithAppRegistry(reg *channel.AppRegistry) persistence.PersistRestorer {
+	return &AsyncPersistRestorer{
+		PersistRestorer: newPersistRestorer(pr.db, reg),
+		db:              pr.db,
+	}
+}
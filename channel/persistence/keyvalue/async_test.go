@@ -0,0 +1,160 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyvalue
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	"perun.network/go-perun/channel/persistence/test"
+	"perun.network/go-perun/pkg/sortedkv"
+	"perun.network/go-perun/pkg/sortedkv/memorydb"
+	pkgtest "perun.network/go-perun/pkg/test"
+	"perun.network/go-perun/wallet"
+)
+
+func TestAsyncPersistRestorer_Generic(t *testing.T) {
+	pr, err := OpenAsyncPersistRestorer(memorydb.NewDatabase(), 0)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, pr.Close()) }()
+	test.GenericPersistRestorerTest(context.Background(), t, pkgtest.Prng(t), pr, 4, 16)
+}
+
+func TestAsyncPersistRestorer_Enabled(t *testing.T) {
+	ctx := context.Background()
+	db := memorydb.NewDatabase()
+	pr, err := OpenAsyncPersistRestorer(db, 0)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, pr.Close()) }()
+	sm, remotes := newTestChannel(t, pkgtest.Prng(t), pr)
+
+	state := sm.State().Clone()
+	state.Version++
+	require.NoError(t, sm.Update(ctx, state, sm.Idx()))
+	require.NoError(t, signAll(ctx, sm, remotes))
+	require.NoError(t, sm.EnableUpdate(ctx))
+
+	// The enabled state must be committed to the wrapped database.
+	restored, err := newPersistRestorer(db, channel.GlobalAppRegistry()).RestoreChannel(ctx, sm.ID())
+	require.NoError(t, err)
+	assert.Equal(t, sm.State(), restored.CurrentTXV.State)
+	assert.Equal(t, sm.Phase(), restored.PhaseV)
+}
+
+func TestAsyncPersistRestorer_EnabledError(t *testing.T) {
+	ctx := context.Background()
+	rng := pkgtest.Prng(t)
+	db := &failingDatabase{Database: memorydb.NewDatabase()}
+	pr, err := OpenAsyncPersistRestorer(db, 10*time.Millisecond)
+	require.NoError(t, err)
+	failing, failingRemotes := newTestChannel(t, rng, pr)
+	ok, okRemotes := newTestChannel(t, rng, pr)
+	require.NoError(t, pr.Flush())
+	db.failPrefix(channelPrefix(failing.ID()))
+
+	// Both channels enable an update concurrently, so that their writes are
+	// committed in the same group.
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, ch := range []struct {
+		sm      *persistence.StateMachine
+		remotes []wallet.Account
+	}{{failing, failingRemotes}, {ok, okRemotes}} {
+		wg.Add(1)
+		go func(i int, sm *persistence.StateMachine, remotes []wallet.Account) {
+			defer wg.Done()
+			state := sm.State().Clone()
+			state.Version++
+			if errs[i] = sm.Update(ctx, state, sm.Idx()); errs[i] != nil {
+				return
+			}
+			if errs[i] = signAll(ctx, sm, remotes); errs[i] != nil {
+				return
+			}
+			errs[i] = sm.EnableUpdate(ctx)
+		}(i, ch.sm, ch.remotes)
+	}
+	wg.Wait()
+
+	assert.True(t, errors.Is(errs[0], errFailingDatabase), "failing channel: %v", errs[0])
+	assert.NoError(t, errs[1], "other channel must not see the failure")
+	restored, err := newPersistRestorer(db.Database, channel.GlobalAppRegistry()).RestoreChannel(ctx, ok.ID())
+	require.NoError(t, err)
+	assert.Equal(t, ok.State(), restored.CurrentTXV.State)
+
+	assert.Error(t, pr.Flush(), "the failed staging writes are reported by Flush")
+	require.NoError(t, pr.Close())
+}
+
+var errFailingDatabase = errors.New("failing database")
+
+// failingDatabase fails to apply batches that write a key with a given prefix.
+type failingDatabase struct {
+	sortedkv.Database
+	mu     sync.Mutex
+	prefix string
+}
+
+func (d *failingDatabase) failPrefix(prefix string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prefix = prefix
+}
+
+func (d *failingDatabase) fails(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.prefix != "" && strings.HasPrefix(key, d.prefix)
+}
+
+func (d *failingDatabase) NewBatch() sortedkv.Batch {
+	return &failingBatch{Batch: d.Database.NewBatch(), db: d}
+}
+
+type failingBatch struct {
+	sortedkv.Batch
+	db   *failingDatabase
+	fail bool
+}
+
+func (b *failingBatch) Put(key, value string) error {
+	return b.PutBytes(key, []byte(value))
+}
+
+func (b *failingBatch) PutBytes(key string, value []byte) error {
+	b.fail = b.fail || b.db.fails(key)
+	return b.Batch.PutBytes(key, value)
+}
+
+func (b *failingBatch) Delete(key string) error {
+	b.fail = b.fail || b.db.fails(key)
+	return b.Batch.Delete(key)
+}
+
+func (b *failingBatch) Apply() error {
+	if b.fail {
+		return errFailingDatabase
+	}
+	return b.Batch.Apply()
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyvalue
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/channel/persistence"
+	"perun.network/go-perun/pkg/sortedkv"
+	"perun.network/go-perun/pkg/sortedkv/leveldb"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+// BenchmarkPersistRestorer_Update measures channel updates on many channels in
+// parallel, persisted to a synced LevelDB database, either directly or in
+// group commits by an AsyncPersistRestorer.
+func BenchmarkPersistRestorer_Update(b *testing.B) {
+	b.Run("direct", func(b *testing.B) {
+		benchmarkUpdate(b, func(db sortedkv.Database) (persistence.PersistRestorer, error) {
+			return OpenPersistRestorer(db)
+		})
+	})
+	b.Run("groupcommit", func(b *testing.B) {
+		benchmarkUpdate(b, func(db sortedkv.Database) (persistence.PersistRestorer, error) {
+			return OpenAsyncPersistRestorer(db, 0)
+		})
+	})
+}
+
+// benchSeed makes the seed of every benchmark goroutine unique, so that no
+// account is added to the wallet twice.
+var benchSeed int64
+
+func benchmarkUpdate(b *testing.B, open func(sortedkv.Database) (persistence.PersistRestorer, error)) {
+	dir, err := ioutil.TempDir("", "perun-bench-kvpersistrestorer-db-*")
+	require.NoError(b, err)
+	defer os.RemoveAll(dir) // nolint:errcheck
+	lvldb, err := leveldb.LoadDatabase(dir)
+	require.NoError(b, err)
+	lvldb.Sync = true
+	pr, err := open(lvldb)
+	require.NoError(b, err)
+	defer pr.Close() // nolint:errcheck
+
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := context.Background()
+		rng := pkgtest.Prng(b, atomic.AddInt64(&benchSeed, 1))
+		sm, remotes := newTestChannel(b, rng, pr)
+		for pb.Next() {
+			state := sm.State().Clone()
+			state.Version++
+			if err := sm.Update(ctx, state, sm.Idx()); err != nil {
+				b.Error(err)
+				return
+			}
+			if err := signAll(ctx, sm, remotes); err != nil {
+				b.Error(err)
+				return
+			}
+			if err := sm.EnableUpdate(ctx); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
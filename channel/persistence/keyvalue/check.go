@@ -0,0 +1,342 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyvalue
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	perunio "perun.network/go-perun/pkg/io"
+	"perun.network/go-perun/pkg/sortedkv"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+)
+
+type (
+	// CheckReport is the result of Check.
+	CheckReport struct {
+		Channels int       // Channels is the number of checked channels.
+		Problems []Problem // Problems are the found inconsistencies.
+	}
+
+	// Problem is an inconsistency in the database that was found by Check.
+	Problem struct {
+		Channel  channel.ID // Channel is the affected channel.
+		Desc     string     // Desc describes the inconsistency.
+		Repaired bool       // Repaired is whether the inconsistency was repaired.
+
+		repairable bool
+	}
+)
+
+func (p Problem) String() string {
+	s := fmt.Sprintf("channel %x: %s", p.Channel, p.Desc)
+	if p.Repaired {
+		s += " (repaired)"
+	}
+	return s
+}
+
+// OK returns whether no problems were found.
+func (r *CheckReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Check checks the consistency of all channels in a database written by a
+// PersistRestorer. For every channel, it checks that all keys exist and decode,
+// that the states belong to the channel, that all signatures are valid, that
+// the parent channel exists and that the peer table agrees with the channel's
+// peers. Apps are resolved with the global app registry.
+//
+// If repair is true, the following problems are repaired: entries of the peer
+// table are added or removed to match the channels' peers, missing withdrawal
+// receivers are reset and unknown channel keys are removed. Other problems are
+// only reported. The peer table entries of channels whose peers cannot be
+// decoded are kept.
+//
+// A database with an old schema version is checked like a current one, e.g.,
+// the missing withdrawal receivers of a version 1 database are reported. It is
+// only migrated if repair is true, after the repairs are applied. An error is
+// returned for a database that cannot be read or has a newer schema version
+// than supported.
+func Check(db sortedkv.Database, repair bool) (*CheckReport, error) {
+	v, err := DatabaseSchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if v > SchemaVersion {
+		return nil, errors.WithMessagef(ErrUnsupportedSchema,
+			"database has version %d, newest supported version is %d", v, SchemaVersion)
+	}
+
+	c := &checker{
+		pr:     &PersistRestorer{db: db, appReg: channel.GlobalAppRegistry()},
+		report: new(CheckReport),
+		repair: db.NewBatch(),
+	}
+	chans, err := c.readChannels()
+	if err != nil {
+		return nil, err
+	}
+	c.report.Channels = len(chans)
+
+	ids := make([]channel.ID, 0, len(chans))
+	for id := range chans {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i][:], ids[j][:]) < 0 })
+	// Channels whose peers cannot be decoded are left out of peers, so that
+	// their peer table entries are neither checked nor repaired.
+	peers := make(map[channel.ID][]wire.Address, len(chans))
+	for _, id := range ids {
+		if ps, ok := c.checkChannel(id, chans[id], chans); ok {
+			peers[id] = ps
+		}
+	}
+	if err := c.checkPeerTable(peers, chans); err != nil {
+		return nil, err
+	}
+
+	if repair && c.numRepairs > 0 {
+		if err := c.repair.Apply(); err != nil {
+			return nil, errors.WithMessage(err, "applying repairs")
+		}
+		for i := range c.report.Problems {
+			c.report.Problems[i].Repaired = c.report.Problems[i].repairable
+		}
+	}
+	if repair && v != 0 && v < SchemaVersion {
+		if err := Migrate(db); err != nil {
+			return nil, errors.WithMessage(err, "migrating repaired database")
+		}
+	}
+	return c.report, nil
+}
+
+type checker struct {
+	pr         *PersistRestorer
+	report     *CheckReport
+	repair     sortedkv.Batch
+	numRepairs int
+}
+
+// problem adds a problem to the report. If fix is not nil, it is called to
+// write the repair to the repair batch.
+func (c *checker) problem(id channel.ID, fix func() error, format string, args ...interface{}) {
+	p := Problem{Channel: id, Desc: fmt.Sprintf(format, args...)}
+	if fix != nil {
+		if err := fix(); err == nil {
+			p.repairable = true
+			c.numRepairs++
+		}
+	}
+	c.report.Problems = append(c.report.Problems, p)
+}
+
+// readChannels reads the raw values of all channels, grouped by channel ID.
+func (c *checker) readChannels() (map[channel.ID]map[string][]byte, error) {
+	chans := make(map[channel.ID]map[string][]byte)
+	it := sortedkv.NewTable(c.pr.db, prefix.ChannelDB).NewIterator()
+	for it.Next() {
+		key := it.Key()
+		var id channel.ID
+		if len(key) < len(id)+1 || key[len(id)] != ':' {
+			it.Close() // nolint:errcheck
+			return nil, errors.Errorf("malformed channel key %x", key)
+		}
+		copy(id[:], key)
+		if chans[id] == nil {
+			chans[id] = make(map[string][]byte)
+		}
+		chans[id][key[len(id)+1:]] = append([]byte(nil), it.ValueBytes()...)
+	}
+	return chans, errors.WithMessage(it.Close(), "iterating channels")
+}
+
+// checkChannel checks a single channel and returns its decoded peers and
+// whether they could be decoded.
+func (c *checker) checkChannel(id channel.ID, fields map[string][]byte, chans map[channel.ID]map[string][]byte) ([]wire.Address, bool) {
+	db := channelBatch(c.repair, id)
+	decode := func(key string, v interface{}) bool {
+		b, ok := fields[key]
+		if !ok {
+			c.problem(id, nil, "missing key %q", key)
+			return false
+		}
+		buf := bytes.NewBuffer(b)
+		if err := perunio.Decode(c.pr.decoder(buf), v); err != nil {
+			c.problem(id, nil, "decoding %q: %v", key, err)
+			return false
+		} else if buf.Len() != 0 {
+			c.problem(id, nil, "decoding %q: %d bytes left", key, buf.Len())
+			return false
+		}
+		return true
+	}
+
+	var peers wire.AddressesWithLen
+	peersOK := decode(prefix.Peers, &peers)
+	var parent *channel.ID
+	if decode("parent", optChannelIDDec{&parent}) && parent != nil {
+		if _, ok := chans[*parent]; !ok {
+			c.problem(id, nil, "parent channel %x does not exist", *parent)
+		}
+	}
+	var receiver wallet.Address
+	if _, ok := fields["receiver"]; !ok {
+		c.problem(id, func() error {
+			return dbPut(db, "receiver", optAddressEnc{})
+		}, "missing key %q", "receiver")
+	} else {
+		decode("receiver", optAddressDec{&receiver})
+	}
+	var phase channel.Phase
+	if decode("phase", &phase) && phase > channel.LastPhase {
+		c.problem(id, nil, "invalid phase %d", phase)
+	}
+
+	params := new(channel.Params)
+	if !decode("params", params) {
+		return peers, peersOK
+	}
+	if params.ID() != id {
+		c.problem(id, nil, "params belong to channel %x", params.ID())
+	}
+	numParts := len(params.Parts)
+	var idx channel.Index
+	if decode("index", &idx) && int(idx) >= numParts {
+		c.problem(id, nil, "index %d out of range", idx)
+	}
+
+	var current channel.Transaction
+	if decode("current", &current) && current.State != nil {
+		c.checkSigs(id, "current", params, current.State, current.Sigs, true)
+	}
+	var staging *channel.State
+	if _, ok := fields["staging:state"]; !ok {
+		c.problem(id, nil, "missing key %q", "staging:state")
+	} else if len(fields["staging:state"]) != 0 {
+		decode("staging:state", &PersistedState{&staging})
+	}
+	sigs := make([]wallet.Sig, numParts)
+	for i, key := range sigKeys(numParts) {
+		if b, ok := fields[key]; !ok {
+			c.problem(id, nil, "missing key %q", key)
+		} else if len(b) != 0 {
+			decode(key, wallet.SigDec{Sig: &sigs[i]})
+		}
+	}
+	if staging != nil {
+		c.checkSigs(id, "staging", params, staging, sigs, false)
+	}
+
+	// Unknown keys, e.g., signatures of a different number of participants.
+	known := map[string]bool{"current": true, "index": true, "params": true, "parent": true,
+		prefix.Peers: true, "phase": true, "receiver": true, "staging:state": true}
+	for _, key := range sigKeys(numParts) {
+		known[key] = true
+	}
+	for key := range fields {
+		if !known[key] {
+			key := key
+			c.problem(id, func() error { return db.Delete(key) }, "unknown key %q", key)
+		}
+	}
+	return peers, peersOK
+}
+
+// checkSigs checks that the state belongs to the channel and that all
+// signatures are valid. If complete is true, all signatures must be present.
+func (c *checker) checkSigs(id channel.ID, name string, params *channel.Params, state *channel.State, sigs []wallet.Sig, complete bool) {
+	if state.ID != params.ID() {
+		c.problem(id, nil, "%s state belongs to channel %x", name, state.ID)
+		return
+	}
+	if len(sigs) != len(params.Parts) {
+		c.problem(id, nil, "%s state has %d signatures for %d participants", name, len(sigs), len(params.Parts))
+		return
+	}
+	for i, sig := range sigs {
+		if sig == nil {
+			if complete {
+				c.problem(id, nil, "%s state misses signature of participant %d", name, i)
+			}
+			continue
+		}
+		if ok, err := channel.Verify(params.Parts[i], params, state, sig); err != nil {
+			c.problem(id, nil, "%s state: verifying signature of participant %d: %v", name, i, err)
+		} else if !ok {
+			c.problem(id, nil, "%s state has invalid signature of participant %d", name, i)
+		}
+	}
+}
+
+// checkPeerTable checks that the peer table contains exactly the peers of all
+// channels in peers. Entries of existing channels that are not in peers are
+// skipped, since their peers are unknown.
+func (c *checker) checkPeerTable(peers map[channel.ID][]wire.Address, chans map[channel.ID]map[string][]byte) error {
+	expected := make(map[string]channel.ID)
+	for id, ps := range peers {
+		for _, p := range ps {
+			key, err := peerChannelKey(p, id)
+			if err != nil {
+				return err
+			}
+			expected[key] = id
+		}
+	}
+
+	peerdb := sortedkv.NewTableBatch(c.repair, prefix.PeerDB)
+	it := sortedkv.NewTable(c.pr.db, prefix.PeerDB).NewIterator()
+	for it.Next() {
+		key := it.Key()
+		if _, ok := expected[key]; ok {
+			delete(expected, key)
+			continue
+		}
+		p, id, err := decodePeerChanID(key)
+		desc := fmt.Sprintf("peer table entry for peer %v does not match channel", p)
+		if err != nil {
+			desc = fmt.Sprintf("malformed peer table entry %x", key)
+		} else if _, ok := peers[id]; !ok {
+			if _, exists := chans[id]; exists {
+				continue
+			}
+			desc = "peer table entry for non-existing channel"
+		}
+		c.problem(id, func() error { return peerdb.Delete(key) }, "%s", desc)
+	}
+	if err := it.Close(); err != nil {
+		return errors.WithMessage(err, "iterating peer table")
+	}
+
+	missing := make([]string, 0, len(expected))
+	for key := range expected {
+		missing = append(missing, key)
+	}
+	sort.Strings(missing)
+	for _, key := range missing {
+		key := key
+		peer := strings.SplitN(key, ":channel:", 2)[0]
+		c.problem(expected[key], func() error { return peerdb.Put(key, "") },
+			"missing peer table entry for peer %x", peer)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyvalue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/pkg/sortedkv"
+	"perun.network/go-perun/pkg/sortedkv/memorydb"
+	pkgtest "perun.network/go-perun/pkg/test"
+	wtest "perun.network/go-perun/wallet/test"
+)
+
+func TestCheck_Consistent(t *testing.T) {
+	db := memorydb.NewDatabase()
+	require.NoError(t, crashScenario(t, pkgtest.Prng(t), db))
+
+	report, err := Check(db, false)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), report.Problems)
+	assert.Equal(t, 2, report.Channels) // The withdrawn channel was removed.
+}
+
+func TestCheck_Empty(t *testing.T) {
+	report, err := Check(memorydb.NewDatabase(), true)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Zero(t, report.Channels)
+}
+
+func TestCheck_NewerSchema(t *testing.T) {
+	db := memorydb.NewDatabase()
+	require.NoError(t, putSchemaVersion(db, SchemaVersion+1))
+	_, err := Check(db, true)
+	assert.True(t, errors.Is(err, ErrUnsupportedSchema))
+}
+
+func TestCheck_Repair(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	tests := []struct {
+		name    string
+		corrupt func(*PersistRestorer, channel.ID) error
+	}{
+		{"missing peer entry", func(pr *PersistRestorer, id channel.ID) error {
+			peers, err := pr.channelPeers(id)
+			if err != nil {
+				return err
+			}
+			key, err := peerChannelKey(peers[0], id)
+			if err != nil {
+				return err
+			}
+			return pr.db.Delete(prefix.PeerDB + key)
+		}},
+		{"stale peer entry", func(pr *PersistRestorer, _ channel.ID) error {
+			key, err := peerChannelKey(wtest.NewRandomAddress(rng), channel.ID{0xab})
+			if err != nil {
+				return err
+			}
+			return pr.db.Put(prefix.PeerDB+key, "")
+		}},
+		{"missing receiver", func(pr *PersistRestorer, id channel.ID) error {
+			return pr.channelDB(id).Delete("receiver")
+		}},
+		{"unknown key", func(pr *PersistRestorer, id channel.ID) error {
+			return pr.channelDB(id).Put("unknown", "value")
+		}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := memorydb.NewDatabase()
+			pr, id := checkSetup(t, db)
+			require.NoError(t, tt.corrupt(pr, id))
+
+			report, err := Check(db, false)
+			require.NoError(t, err)
+			require.Len(t, report.Problems, 1)
+			assert.False(t, report.Problems[0].Repaired)
+
+			report, err = Check(db, true)
+			require.NoError(t, err)
+			require.Len(t, report.Problems, 1)
+			assert.True(t, report.Problems[0].Repaired, report.Problems[0].String())
+
+			report, err = Check(db, false)
+			require.NoError(t, err)
+			assert.True(t, report.OK(), report.Problems)
+			requireConsistent(t, pr)
+		})
+	}
+}
+
+func TestCheck_OldSchema(t *testing.T) {
+	db := memorydb.NewDatabase()
+	pr, id := checkSetup(t, db)
+	// Downgrade the database to version 1, which has no receivers.
+	require.NoError(t, pr.channelDB(id).Delete("receiver"))
+	require.NoError(t, db.Delete(prefix.MetaDB+versionKey))
+
+	report, err := Check(db, false)
+	require.NoError(t, err)
+	require.Len(t, report.Problems, 1)
+	assert.Equal(t, id, report.Problems[0].Channel)
+	assert.False(t, report.Problems[0].Repaired)
+	v, err := DatabaseSchemaVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), v, "checking must not migrate")
+	has, err := pr.channelDB(id).Has("receiver")
+	require.NoError(t, err)
+	assert.False(t, has, "checking must not write")
+
+	report, err = Check(db, true)
+	require.NoError(t, err)
+	require.Len(t, report.Problems, 1)
+	assert.True(t, report.Problems[0].Repaired)
+	v, err = DatabaseSchemaVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, SchemaVersion, v, "repairing must migrate")
+
+	report, err = Check(db, false)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), report.Problems)
+	requireConsistent(t, pr)
+}
+
+func TestCheck_UndecodablePeers(t *testing.T) {
+	db := memorydb.NewDatabase()
+	pr, id := checkSetup(t, db)
+	peers, err := pr.channelPeers(id)
+	require.NoError(t, err)
+	require.NoError(t, pr.channelDB(id).Put(prefix.Peers, "garbage"))
+
+	report, err := Check(db, true)
+	require.NoError(t, err)
+	require.Len(t, report.Problems, 1)
+	assert.False(t, report.Problems[0].Repaired)
+
+	// The peer table entries must be kept.
+	for _, peer := range peers {
+		key, err := peerChannelKey(peer, id)
+		require.NoError(t, err)
+		ok, err := db.Has(prefix.PeerDB + key)
+		require.NoError(t, err)
+		assert.True(t, ok, "peer table entry deleted")
+	}
+}
+
+func TestCheck_InvalidSig(t *testing.T) {
+	ctx := context.Background()
+	db := memorydb.NewDatabase()
+	pr, id := checkSetup(t, db)
+
+	ch, err := pr.RestoreChannel(ctx, id)
+	require.NoError(t, err)
+	tx := ch.CurrentTXV
+	tx.Sigs[0], tx.Sigs[1] = tx.Sigs[1], tx.Sigs[0]
+	require.NoError(t, dbPut(pr.channelDB(id), "current", tx))
+
+	report, err := Check(db, true)
+	require.NoError(t, err)
+	require.Len(t, report.Problems, 2)
+	for _, p := range report.Problems {
+		assert.Equal(t, id, p.Channel)
+		assert.False(t, p.Repaired)
+	}
+}
+
+// checkSetup persists some channels in the database and returns a
+// PersistRestorer on it together with the ID of a persisted channel.
+func checkSetup(t *testing.T, db sortedkv.Database) (*PersistRestorer, channel.ID) {
+	require.NoError(t, crashScenario(t, pkgtest.Prng(t), db))
+	pr, err := OpenPersistRestorer(db)
+	require.NoError(t, err)
+
+	it, err := pr.RestoreAll()
+	require.NoError(t, err)
+	require.True(t, it.Next(context.Background()))
+	id := it.Channel().ID()
+	require.NoError(t, it.Close())
+	return pr, id
+}
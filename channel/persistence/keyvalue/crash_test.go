@@ -0,0 +1,160 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyvalue
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	ctest "perun.network/go-perun/channel/test"
+	"perun.network/go-perun/pkg/sortedkv"
+	"perun.network/go-perun/pkg/sortedkv/memorydb"
+	pkgtest "perun.network/go-perun/pkg/test"
+	"perun.network/go-perun/wallet"
+	wtest "perun.network/go-perun/wallet/test"
+	"perun.network/go-perun/wire"
+)
+
+// TestPersistRestorer_Crash simulates a crash at every single write of a
+// persistence scenario and checks that the database can be restored to a
+// consistent state afterwards.
+func TestPersistRestorer_Crash(t *testing.T) {
+	db := memorydb.NewCrashingDatabase(0)
+	require.NoError(t, crashScenario(t, pkgtest.Prng(t), db))
+	numWrites := db.Writes()
+	require.NotZero(t, numWrites)
+
+	for crashAt := 1; crashAt <= numWrites; crashAt++ {
+		db := memorydb.NewCrashingDatabase(crashAt)
+		err := crashScenario(t, pkgtest.Prng(t, crashAt), db)
+		require.Truef(t, errors.Is(err, memorydb.ErrCrashed),
+			"crash at write %d: unexpected error: %v", crashAt, err)
+
+		pr, err := OpenPersistRestorer(db.Recover())
+		require.NoError(t, err)
+		requireConsistent(t, pr)
+	}
+}
+
+// crashScenario creates a PersistRestorer on the database, persists several
+// channels and walks them through their lifecycle. The number of writes does
+// not depend on the randomness. It returns the first persistence error.
+func crashScenario(t *testing.T, rng *rand.Rand, db sortedkv.Database) error {
+	const numChans = 3
+	ctx := context.Background()
+	pr, err := OpenPersistRestorer(db)
+	if err != nil {
+		return err
+	}
+	peers := []wire.Address{wtest.NewRandomAddress(rng), wtest.NewRandomAddress(rng)}
+
+	chans := make([]*persistence.StateMachine, numChans)
+	for i := range chans {
+		accs, parts := wtest.NewRandomAccounts(rng, len(peers))
+		params := ctest.NewRandomParams(rng, ctest.WithParts(parts...))
+		csm, err := channel.NewStateMachine(accs[0], *params)
+		require.NoError(t, err)
+		sm := persistence.FromStateMachine(csm, pr)
+		chans[i] = &sm
+
+		if err := pr.ChannelCreated(ctx, &sm, peers, nil); err != nil {
+			return err
+		}
+		if err := crashScenarioOpen(ctx, rng, &sm, accs[1:]); err != nil {
+			return err
+		}
+		if err := pr.ReceiverChanged(ctx, sm.ID(), wtest.NewRandomAddress(rng)); err != nil {
+			return err
+		}
+	}
+
+	if err := chans[0].SetRegistering(ctx); err != nil {
+		return err
+	}
+	if err := chans[0].SetRegistered(ctx); err != nil {
+		return err
+	}
+	if err := chans[0].SetWithdrawing(ctx); err != nil {
+		return err
+	}
+	return chans[0].SetWithdrawn(ctx)
+}
+
+// crashScenarioOpen initializes, signs and funds the channel. The channel's
+// own account is at index 0, the remote accounts follow.
+func crashScenarioOpen(ctx context.Context, rng *rand.Rand, sm *persistence.StateMachine, remotes []wallet.Account) error {
+	alloc := ctest.NewRandomAllocation(rng, ctest.WithNumParts(len(remotes)+1))
+	if err := sm.Init(ctx, *alloc, channel.NewMockOp(channel.OpValid)); err != nil {
+		return err
+	}
+	if _, err := sm.Sig(ctx); err != nil {
+		return err
+	}
+	for i, acc := range remotes {
+		sig, err := channel.Sign(acc, sm.Params(), sm.StagingState())
+		if err != nil {
+			return err
+		}
+		if err := sm.AddSig(ctx, channel.Index(i+1), sig); err != nil {
+			return err
+		}
+	}
+	if err := sm.EnableInit(ctx); err != nil {
+		return err
+	}
+	return sm.SetFunded(ctx)
+}
+
+// requireConsistent checks that all channels can be restored and that the
+// peer index matches the channel table.
+func requireConsistent(t *testing.T, pr *PersistRestorer) {
+	ctx := context.Background()
+
+	all := make(map[channel.ID]*persistence.Channel)
+	it, err := pr.RestoreAll()
+	require.NoError(t, err)
+	for it.Next(ctx) {
+		ch := it.Channel()
+		all[ch.ID()] = ch
+	}
+	require.NoError(t, it.Close())
+
+	peers, err := pr.ActivePeers(ctx)
+	require.NoError(t, err)
+	indexed := make(map[channel.ID]int)
+	for _, peer := range peers {
+		it, err := pr.RestorePeer(peer)
+		require.NoError(t, err)
+		for it.Next(ctx) {
+			indexed[it.Channel().ID()]++
+		}
+		require.NoError(t, it.Close())
+	}
+
+	require.Len(t, indexed, len(all))
+	for id, ch := range all {
+		assert.Equalf(t, len(ch.PeersV), indexed[id], "channel %x not indexed for all peers", id)
+		restored, err := pr.RestoreChannel(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, id, restored.ID())
+	}
+}
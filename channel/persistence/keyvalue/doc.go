@@ -14,4 +14,13 @@
 
 // Package keyvalue contains an implementation of the channel persister
 // interface using a keyvalue database interface.
+//
+// A PersistRestorer should be created with OpenPersistRestorer, which migrates
+// the database to the current SchemaVersion and refuses databases of newer
+// versions. The deprecated NewPersistRestorer does neither, so it may
+// misinterpret or corrupt databases written by other versions of this package.
+//
+// Many channels that are updated concurrently can share the cost of syncing
+// their writes to disk by using an AsyncPersistRestorer, which only waits for
+// the sync when an update is enabled.
 package keyvalue // import "perun.network/go-perun/channel/persistence/keyvalue"
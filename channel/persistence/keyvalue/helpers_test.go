@@ -0,0 +1,61 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyvalue
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	ctest "perun.network/go-perun/channel/test"
+	"perun.network/go-perun/wallet"
+	wtest "perun.network/go-perun/wallet/test"
+	"perun.network/go-perun/wire"
+)
+
+// newTestChannel persists a new funded channel and returns it together with
+// the remote participants' accounts.
+func newTestChannel(t testing.TB, rng *rand.Rand, pr persistence.PersistRestorer) (*persistence.StateMachine, []wallet.Account) {
+	ctx := context.Background()
+	accs, parts := wtest.NewRandomAccounts(rng, 2)
+	params := ctest.NewRandomParams(rng, ctest.WithParts(parts...))
+	csm, err := channel.NewStateMachine(accs[0], *params)
+	require.NoError(t, err)
+	sm := persistence.FromStateMachine(csm, pr)
+	require.NoError(t, pr.ChannelCreated(ctx, &sm, []wire.Address{parts[1]}, nil))
+	require.NoError(t, crashScenarioOpen(ctx, rng, &sm, accs[1:]))
+	return &sm, accs[1:]
+}
+
+// signAll signs the staging state locally and by all remote accounts.
+func signAll(ctx context.Context, sm *persistence.StateMachine, remotes []wallet.Account) error {
+	if _, err := sm.Sig(ctx); err != nil {
+		return err
+	}
+	for i, acc := range remotes {
+		sig, err := channel.Sign(acc, sm.Params(), sm.StagingState())
+		if err != nil {
+			return err
+		}
+		if err := sm.AddSig(ctx, channel.Index(i+1), sig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -19,6 +19,7 @@ import (
 
 	"perun.network/go-perun/channel"
 	perunio "perun.network/go-perun/pkg/io"
+	"perun.network/go-perun/wallet"
 )
 
 var _ perunio.Encoder = PersistedState{}
@@ -70,3 +71,29 @@ func (id optChannelIDDec) Decode(r io.Reader) error {
 	*id.ID = nil
 	return nil
 }
+
+type optAddressEnc struct {
+	Addr wallet.Address
+}
+type optAddressDec struct {
+	Addr *wallet.Address
+}
+
+func (a optAddressEnc) Encode(w io.Writer) error {
+	if a.Addr != nil {
+		return perunio.Encode(w, true, a.Addr)
+	}
+	return perunio.Encode(w, false)
+}
+
+func (a optAddressDec) Decode(r io.Reader) error {
+	var exists bool
+	if err := perunio.Decode(r, &exists); err != nil {
+		return err
+	}
+	if exists {
+		return perunio.Decode(r, wallet.AddressDec{Addr: a.Addr})
+	}
+	*a.Addr = nil
+	return nil
+}
@@ -26,14 +26,51 @@ import (
 	"github.com/pkg/errors"
 
 	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
 	perunio "perun.network/go-perun/pkg/io"
 	"perun.network/go-perun/pkg/sortedkv"
+	"perun.network/go-perun/wallet"
 	"perun.network/go-perun/wire"
 )
 
-// ChannelCreated inserts a channel into the database.
+// ChannelCreated inserts a channel into the database. The channel and peer
+// tables are written in a single batch.
 func (pr *PersistRestorer) ChannelCreated(_ context.Context, s channel.Source, peers []wire.Address, parent *channel.ID) error {
-	db := pr.channelDB(s.ID()).NewBatch()
+	batch := pr.db.NewBatch()
+	if err := putChannel(batch, s, peers, parent, nil); err != nil {
+		return err
+	}
+	return errors.WithMessage(batch.Apply(), "applying batch")
+}
+
+// ReplaceChannel atomically replaces a persisted channel with ch, including its
+// peers, parent and receiver. If the channel is not persisted yet, it is
+// inserted. The channel and peer tables are written in a single batch.
+func (pr *PersistRestorer) ReplaceChannel(_ context.Context, ch *persistence.Channel) error {
+	batch := pr.db.NewBatch()
+	if ok, err := pr.channelDB(ch.ID()).Has("peers"); err != nil {
+		return errors.WithMessage(err, "checking for persisted channel")
+	} else if ok {
+		// The channel keys are overwritten, only the peer table entries of
+		// the old peers must be deleted.
+		peers, err := pr.channelPeers(ch.ID())
+		if err != nil {
+			return errors.WithMessage(err, "retrieving peers for channel")
+		}
+		if err := deletePeerChannels(batch, peers, ch.ID()); err != nil {
+			return err
+		}
+	}
+	if err := putChannel(batch, ch, ch.PeersV, ch.Parent, ch.Receiver); err != nil {
+		return err
+	}
+	return errors.WithMessage(batch.Apply(), "applying batch")
+}
+
+// putChannel writes all keys of a channel and its peer table entries into the
+// batch.
+func putChannel(batch sortedkv.Batch, s channel.Source, peers []wire.Address, parent *channel.ID, receiver wallet.Address) error {
+	db := channelBatch(batch, s.ID())
 	// Write the channel data in the "Channel" table.
 	numParts := len(s.Params().Parts)
 	keys := append([]string{"current", "index", "params", "phase", "staging:state"},
@@ -46,13 +83,17 @@ func (pr *PersistRestorer) ChannelCreated(_ context.Context, s channel.Source, p
 		return errors.WithMessage(err, "putting parent ID")
 	}
 
+	if err := dbPut(db, "receiver", optAddressEnc{receiver}); err != nil {
+		return errors.WithMessage(err, "putting receiver")
+	}
+
 	// Write peers in the "Channel" table.
 	if err := dbPut(db, prefix.Peers, wire.AddressesWithLen(peers)); err != nil {
 		return errors.WithMessage(err, "putting peers into channel table")
 	}
 
 	// Register the channel in the "Peer" table.
-	peerdb := sortedkv.NewTable(pr.db, prefix.PeerDB).NewBatch()
+	peerdb := sortedkv.NewTableBatch(batch, prefix.PeerDB)
 	for _, peer := range peers {
 		key, err := peerChannelKey(peer, s.ID())
 		if err != nil {
@@ -62,11 +103,7 @@ func (pr *PersistRestorer) ChannelCreated(_ context.Context, s channel.Source, p
 			return errors.WithMessage(err, "putting peer channel")
 		}
 	}
-
-	if err := db.Apply(); err != nil {
-		return errors.WithMessage(err, "applying channel batch")
-	}
-	return errors.WithMessage(peerdb.Apply(), "applying peer batch")
+	return nil
 }
 
 // sigKey creates a key for given idx and number of channel
@@ -76,16 +113,17 @@ func sigKey(idx, numParts int) string {
 	return fmt.Sprintf("%s%0*d", prefix.SigKey, width, idx)
 }
 
-// ChannelRemoved deletes a channel from the database.
+// ChannelRemoved deletes all keys of a channel from the database. The channel
+// and peer tables are written in a single batch.
 func (pr *PersistRestorer) ChannelRemoved(ctx context.Context, id channel.ID) error {
-	db := pr.channelDB(id).NewBatch()
-	peerdb := sortedkv.NewTable(pr.db, prefix.PeerDB).NewBatch()
+	batch := pr.db.NewBatch()
+	db := channelBatch(batch, id)
 	// All keys a channel has.
 	params, err := pr.getParamsForChan(id)
 	if err != nil {
 		return err
 	}
-	keys := append([]string{"current", "index", "params", "peers", "phase", "staging:state"},
+	keys := append([]string{"current", "index", "params", "parent", "peers", "phase", "receiver", "staging:state"},
 		sigKeys(len(params.Parts))...)
 
 	for _, key := range keys {
@@ -98,6 +136,16 @@ func (pr *PersistRestorer) ChannelRemoved(ctx context.Context, id channel.ID) er
 	if err != nil {
 		return errors.WithMessage(err, "retrieving peers for channel")
 	}
+	if err := deletePeerChannels(batch, peers, id); err != nil {
+		return err
+	}
+
+	return errors.WithMessage(batch.Apply(), "applying batch")
+}
+
+// deletePeerChannels deletes the peer table entries of a channel in the batch.
+func deletePeerChannels(batch sortedkv.Batch, peers []wire.Address, id channel.ID) error {
+	peerdb := sortedkv.NewTableBatch(batch, prefix.PeerDB)
 	for _, peer := range peers {
 		key, err := peerChannelKey(peer, id)
 		if err != nil {
@@ -107,11 +155,7 @@ func (pr *PersistRestorer) ChannelRemoved(ctx context.Context, id channel.ID) er
 			return errors.WithMessage(err, "deleting peer channel")
 		}
 	}
-
-	if err := db.Apply(); err != nil {
-		return errors.WithMessage(err, "applying channel batch")
-	}
-	return errors.WithMessage(peerdb.Apply(), "applying peer batch")
+	return nil
 }
 
 // getParamsForChan returns the channel parameters for a given channel id from
@@ -122,7 +166,7 @@ func (pr *PersistRestorer) getParamsForChan(id channel.ID) (channel.Params, erro
 	if err != nil {
 		return params, errors.WithMessage(err, "unable to retrieve params from db")
 	}
-	return params, errors.WithMessage(perunio.Decode(bytes.NewBuffer(b), &params),
+	return params, errors.WithMessage(perunio.Decode(pr.decoder(bytes.NewBuffer(b)), &params),
 		"unable to decode channel parameters")
 }
 
@@ -164,20 +208,29 @@ func (pr *PersistRestorer) SigAdded(_ context.Context, s channel.Source, idx cha
 // Enabled persists the channel's staging and current transaction, and phase.
 func (pr *PersistRestorer) Enabled(_ context.Context, s channel.Source) error {
 	db := pr.channelDB(s.ID()).NewBatch()
-
-	numParts := len(s.Params().Parts)
-	keys := append([]string{"staging:state", "current", "phase"}, sigKeys(numParts)...)
-	if err := dbPutSource(db, s, keys...); err != nil {
+	if err := putEnabled(db, s); err != nil {
 		return err
 	}
 	return errors.WithMessage(db.Apply(), "applying batch")
 }
 
+// putEnabled writes the fields that Enabled persists to the channel's batch.
+func putEnabled(db sortedkv.Writer, s channel.Source) error {
+	numParts := len(s.Params().Parts)
+	keys := append([]string{"staging:state", "current", "phase"}, sigKeys(numParts)...)
+	return dbPutSource(db, s, keys...)
+}
+
 // PhaseChanged persists the channel's phase.
 func (pr *PersistRestorer) PhaseChanged(_ context.Context, s channel.Source) error {
 	return dbPut(pr.channelDB(s.ID()), "phase", s.Phase())
 }
 
+// ReceiverChanged persists the channel's withdrawal receiver.
+func (pr *PersistRestorer) ReceiverChanged(_ context.Context, id channel.ID, receiver wallet.Address) error {
+	return dbPut(pr.channelDB(id), "receiver", optAddressEnc{receiver})
+}
+
 func dbPutSource(db sortedkv.Writer, s channel.Source, keys ...string) error {
 	for _, key := range keys {
 		if err := dbPutSourceField(db, s, key); err != nil {
@@ -259,5 +312,15 @@ func peerChannelKey(p wire.Address, ch channel.ID) (string, error) {
 
 // channelDB creates a prefixed database for persisting a channel's data.
 func (pr *PersistRestorer) channelDB(id channel.ID) sortedkv.Database {
-	return sortedkv.NewTable(pr.db, prefix.ChannelDB+string(id[:])+":")
+	return sortedkv.NewTable(pr.db, channelPrefix(id))
+}
+
+// channelBatch creates a prefixed view on the given batch for persisting a
+// channel's data.
+func channelBatch(b sortedkv.Batch, id channel.ID) sortedkv.Batch {
+	return sortedkv.NewTableBatch(b, channelPrefix(id))
+}
+
+func channelPrefix(id channel.ID) string {
+	return prefix.ChannelDB + string(id[:]) + ":"
 }
@@ -15,16 +15,25 @@
 package keyvalue
 
 import (
+	"io"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
 	"perun.network/go-perun/channel/persistence"
 	"perun.network/go-perun/pkg/sortedkv"
 )
 
-var _ persistence.PersistRestorer = (*PersistRestorer)(nil)
+var (
+	_ persistence.PersistRestorer   = (*PersistRestorer)(nil)
+	_ persistence.AppRegistryScoper = (*PersistRestorer)(nil)
+)
 
 // PersistRestorer implements both the persister and the restorer interface
 // using a sorted key-value store.
 type PersistRestorer struct {
-	db sortedkv.Database
+	db     sortedkv.Database
+	appReg *channel.AppRegistry
 }
 
 // Close closes the PersistRestorer and releases all resources it holds.
@@ -36,15 +45,59 @@ func (pr *PersistRestorer) Close() error {
 }
 
 // NewPersistRestorer creates a new PersistRestorer for the supplied database.
+// Apps of restored channels are resolved with the global app registry.
+// The schema version of the database is neither checked nor migrated.
+//
+// Deprecated: Use OpenPersistRestorer, which migrates the database first.
 func NewPersistRestorer(db sortedkv.Database) *PersistRestorer {
+	return newPersistRestorer(db, channel.GlobalAppRegistry())
+}
+
+// NewPersistRestorerWithAppRegistry creates a new PersistRestorer for the
+// supplied database that resolves the apps of restored channels with the given
+// app registry. Like NewPersistRestorer, it does not migrate the database.
+//
+// Deprecated: Use OpenPersistRestorer and PersistRestorer.WithAppRegistry.
+func NewPersistRestorerWithAppRegistry(db sortedkv.Database, reg *channel.AppRegistry) *PersistRestorer {
+	return newPersistRestorer(db, reg)
+}
+
+func newPersistRestorer(db sortedkv.Database, reg *channel.AppRegistry) *PersistRestorer {
 	return &PersistRestorer{
-		db: db,
+		db:     db,
+		appReg: reg,
+	}
+}
+
+// OpenPersistRestorer migrates the supplied database to the current
+// SchemaVersion, see Migrate, and creates a new PersistRestorer for it. It
+// fails with ErrUnsupportedSchema if the database is newer than SchemaVersion.
+// Apps of restored channels are resolved with the global app registry.
+func OpenPersistRestorer(db sortedkv.Database) (*PersistRestorer, error) {
+	if err := Migrate(db); err != nil {
+		return nil, errors.WithMessage(err, "migrating database")
 	}
+	return newPersistRestorer(db, channel.GlobalAppRegistry()), nil
+}
+
+// WithAppRegistry returns a PersistRestorer on the same database that resolves
+// the apps of restored channels with the given app registry.
+func (pr *PersistRestorer) WithAppRegistry(reg *channel.AppRegistry) persistence.PersistRestorer {
+	scoped := *pr
+	scoped.appReg = reg
+	return &scoped
+}
+
+// decoder returns a reader for decoding from r that resolves apps with the
+// app registry of the PersistRestorer.
+func (pr *PersistRestorer) decoder(r io.Reader) io.Reader {
+	return channel.WithAppRegistry(r, pr.appReg)
 }
 
-var prefix = struct{ ChannelDB, PeerDB, SigKey, Peers string }{
+var prefix = struct{ ChannelDB, PeerDB, MetaDB, SigKey, Peers string }{
 	ChannelDB: "Chan:",
 	PeerDB:    "Peer:",
+	MetaDB:    "Meta:",
 	SigKey:    "staging:sig:",
 	Peers:     "peers",
 }
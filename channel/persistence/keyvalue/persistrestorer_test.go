@@ -28,6 +28,7 @@ import (
 	"perun.network/go-perun/pkg/sortedkv/leveldb"
 	"perun.network/go-perun/pkg/sortedkv/memorydb"
 	pkgtest "perun.network/go-perun/pkg/test"
+	wtest "perun.network/go-perun/wallet/test"
 )
 
 func TestPersistRestorer_Generic(t *testing.T) {
@@ -44,7 +45,8 @@ func TestPersistRestorer_Generic(t *testing.T) {
 	for i, db := range dbs {
 		func(i int64) {
 			defer func() { require.NoError(t, db.Close()) }()
-			pr := NewPersistRestorer(db)
+			pr, err := OpenPersistRestorer(db)
+			require.NoError(t, err)
 			rng := pkgtest.Prng(t, i)
 			test.GenericPersistRestorerTest(
 				context.Background(),
@@ -57,6 +59,38 @@ func TestPersistRestorer_Generic(t *testing.T) {
 	}
 }
 
+func TestChannelIterator_MissingReceiver(t *testing.T) {
+	ctx := context.Background()
+	rng := pkgtest.Prng(t)
+	pr, err := OpenPersistRestorer(memorydb.NewDatabase())
+	require.NoError(t, err)
+	c := test.NewClient(ctx, t, rng, pr)
+	peer := wtest.NewRandomAddress(rng)
+	chs := []*test.Channel{c.NewChannel(t, peer, nil), c.NewChannel(t, peer, nil)}
+	recv := wtest.NewRandomAddress(rng)
+	for _, ch := range chs {
+		ch.Init(t, rng)
+		ch.SetReceiver(t, recv)
+	}
+	// Channels persisted before receivers were introduced have no receiver.
+	require.NoError(t, pr.channelDB(chs[0].ID()).Delete("receiver"))
+
+	restored, err := pr.RestoreChannel(ctx, chs[0].ID())
+	require.NoError(t, err)
+	assert.Nil(t, restored.Receiver)
+	it, err := pr.RestorePeer(peer)
+	require.NoError(t, err)
+	for it.Next(ctx) {
+		ch := it.Channel()
+		if ch.ID() == chs[0].ID() {
+			assert.Nil(t, ch.Receiver)
+		} else {
+			assert.True(t, ch.Receiver.Equals(recv))
+		}
+	}
+	require.NoError(t, it.Close())
+}
+
 func TestChannelIterator_Next_Empty(t *testing.T) {
 	var it ChannelIterator
 	var success bool
@@ -34,9 +34,10 @@ var _ persistence.ChannelIterator = (*ChannelIterator)(nil)
 
 // ChannelIterator implements the persistence.ChannelIterator interface.
 type ChannelIterator struct {
-	err error
-	ch  *persistence.Channel
-	its []sortedkv.Iterator
+	err    error
+	ch     *persistence.Channel
+	its    []sortedkv.Iterator
+	peeked bool // whether the current entry of its[0] was not decoded yet
 
 	restorer *PersistRestorer
 }
@@ -134,7 +135,6 @@ func (pr *PersistRestorer) RestoreChannel(ctx context.Context, id channel.ID) (*
 }
 
 // decodePeerChanID decodes the channel.ID and peer.Address from a key.
-// nolint: deadcode, unused
 func decodePeerChanID(key string) (wire.Address, channel.ID, error) {
 	buf := bytes.NewBufferString(key)
 	addr, err := wire.DecodeAddress(buf)
@@ -152,7 +152,6 @@ func decodePeerChanID(key string) (wire.Address, channel.ID, error) {
 
 // eatExpect consumes bytes from a Reader and asserts that they are equal to
 // the expected string.
-// nolint: deadcode, unused
 func eatExpect(r io.Reader, tok string) error {
 	buf := make([]byte, len(tok))
 	if _, err := io.ReadFull(r, buf); err != nil {
@@ -170,6 +169,7 @@ const (
 	noOpts   decOpts = 0
 	allowEnd decOpts = 1 << iota
 	allowEmpty
+	allowMissing
 )
 
 // isSetIn masks the given opts with the current opt and returns
@@ -190,7 +190,8 @@ func (i *ChannelIterator) Next(context.Context) bool {
 		!i.decodeNext("params", i.ch.ParamsV, noOpts) ||
 		!i.decodeNext("parent", optChannelIDDec{&i.ch.Parent}, noOpts) ||
 		!i.decodeNext("peers", (*wire.AddressesWithLen)(&i.ch.PeersV), noOpts) ||
-		!i.decodeNext("phase", &i.ch.PhaseV, noOpts) {
+		!i.decodeNext("phase", &i.ch.PhaseV, noOpts) ||
+		!i.decodeNext("receiver", optAddressDec{&i.ch.Receiver}, allowMissing) {
 		return false
 	}
 	i.ch.StagingTXV.Sigs = make([]wallet.Sig, len(i.ch.ParamsV.Parts))
@@ -222,13 +223,19 @@ func (i *ChannelIterator) recoverFromEmptyIterator(key string, allowedToEnd decO
 
 // decodeNext reduces code duplication for decoding a value from an iterator. If
 // an iterator ends in the middle of decoding a channel, then the channel
-// iterator's error is set. Returns whether a value was decoded without error.
+// iterator's error is set. If allowMissing is set and the next entry does not
+// have the given key, nothing is decoded and the entry is kept for the next
+// call. Returns whether a value was decoded without error.
 func (i *ChannelIterator) decodeNext(key string, v interface{}, opts decOpts) bool {
-	for !i.its[0].Next() {
+	for !i.peeked && !i.its[0].Next() {
 		if !i.recoverFromEmptyIterator(key, opts) {
 			return false
 		}
 	}
+	i.peeked = allowMissing.isSetIn(opts) && !strings.HasSuffix(i.its[0].Key(), ":"+key)
+	if i.peeked {
+		return true
+	}
 
 	buf := bytes.NewBuffer(i.its[0].ValueBytes())
 	if buf.Len() == 0 {
@@ -239,7 +246,7 @@ func (i *ChannelIterator) decodeNext(key string, v interface{}, opts decOpts) bo
 		return false
 	}
 
-	i.err = errors.WithMessage(perunio.Decode(buf, v), "decoding "+key)
+	i.err = errors.WithMessage(perunio.Decode(i.restorer.decoder(buf), v), "decoding "+key)
 	if i.err != nil {
 		return false
 	}
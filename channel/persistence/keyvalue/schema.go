@@ -0,0 +1,152 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyvalue
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	perunio "perun.network/go-perun/pkg/io"
+	"perun.network/go-perun/pkg/sortedkv"
+)
+
+// SchemaVersion is the version of the database layout written by this
+// package. Databases with an older version are migrated when a PersistRestorer
+// is created on them.
+//
+// Versions:
+//  1: Initial layout without a version marker.
+//  2: Channels have a withdrawal "receiver" key.
+const SchemaVersion uint32 = 2
+
+// ErrUnsupportedSchema is returned when opening a database whose schema
+// version is newer than SchemaVersion.
+var ErrUnsupportedSchema = errors.New("unsupported database schema version")
+
+// migrations contains the migration from version i+1 to version i+2 at index
+// i. A migration must only write to the given batch, which is applied together
+// with the version update.
+var migrations = []func(db sortedkv.Database, b sortedkv.Batch) error{
+	migrateReceiver,
+}
+
+const versionKey = "version"
+
+// DatabaseSchemaVersion reads the schema version of the given database. An
+// empty database has version 0 and a database written before the introduction
+// of versioning has version 1.
+func DatabaseSchemaVersion(db sortedkv.Database) (uint32, error) {
+	meta := sortedkv.NewTable(db, prefix.MetaDB)
+	if has, err := meta.Has(versionKey); err != nil {
+		return 0, errors.WithMessage(err, "checking schema version")
+	} else if !has {
+		empty, err := isEmpty(db)
+		if err != nil || empty {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	b, err := meta.GetBytes(versionKey)
+	if err != nil {
+		return 0, errors.WithMessage(err, "getting schema version")
+	}
+	var v uint32
+	return v, errors.WithMessage(perunio.Decode(bytes.NewReader(b), &v), "decoding schema version")
+}
+
+// isEmpty returns whether the database contains no channel or peer data.
+func isEmpty(db sortedkv.Database) (bool, error) {
+	for _, p := range []string{prefix.ChannelDB, prefix.PeerDB} {
+		it := db.NewIteratorWithPrefix(p)
+		has := it.Next()
+		if err := it.Close(); err != nil {
+			return false, errors.Wrap(err, "closing iterator")
+		}
+		if has {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Migrate upgrades the database to SchemaVersion in place. Each migration step
+// is applied atomically together with its version update, so an interrupted
+// migration can be resumed. An empty database is marked with the current
+// version. It fails with ErrUnsupportedSchema if the database is newer than
+// SchemaVersion.
+func Migrate(db sortedkv.Database) error {
+	v, err := DatabaseSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if v > SchemaVersion {
+		return errors.WithMessagef(ErrUnsupportedSchema,
+			"database has version %d, newest supported version is %d", v, SchemaVersion)
+	}
+	if v == 0 {
+		return putSchemaVersion(db, SchemaVersion)
+	}
+
+	for ; v < SchemaVersion; v++ {
+		b := db.NewBatch()
+		if err := migrations[v-1](db, b); err != nil {
+			return errors.WithMessagef(err, "migrating from version %d", v)
+		}
+		if err := putSchemaVersion(b, v+1); err != nil {
+			return err
+		}
+		if err := b.Apply(); err != nil {
+			return errors.WithMessagef(err, "applying migration from version %d", v)
+		}
+	}
+	return nil
+}
+
+func putSchemaVersion(db sortedkv.Writer, v uint32) error {
+	return dbPut(db, prefix.MetaDB+versionKey, v)
+}
+
+// migrateReceiver adds an empty withdrawal receiver to all channels that do
+// not have a receiver yet. Databases written before versioning was introduced
+// may already contain receivers, which are kept.
+func migrateReceiver(db sortedkv.Database, b sortedkv.Batch) error {
+	it := sortedkv.NewTable(db, prefix.ChannelDB).NewIterator()
+	var last string
+	for it.Next() {
+		if len(it.Key()) < len(channel.ID{}) {
+			continue
+		}
+		id := it.Key()[:len(channel.ID{})]
+		if id == last {
+			continue
+		}
+		last = id
+		chdb := sortedkv.NewTable(db, prefix.ChannelDB+id+":")
+		if has, err := chdb.Has("receiver"); err != nil {
+			it.Close() // nolint: errcheck
+			return errors.WithMessage(err, "checking receiver")
+		} else if has {
+			continue
+		}
+		if err := dbPut(sortedkv.NewTableBatch(b, prefix.ChannelDB+id+":"), "receiver", optAddressEnc{}); err != nil {
+			it.Close() // nolint: errcheck
+			return err
+		}
+	}
+	return errors.Wrap(it.Close(), "closing iterator")
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyvalue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/channel/persistence/test"
+	"perun.network/go-perun/pkg/sortedkv/memorydb"
+	pkgtest "perun.network/go-perun/pkg/test"
+	wtest "perun.network/go-perun/wallet/test"
+	"perun.network/go-perun/wire"
+)
+
+func TestMigrate_Empty(t *testing.T) {
+	db := memorydb.NewDatabase()
+	v, err := DatabaseSchemaVersion(db)
+	require.NoError(t, err)
+	assert.Zero(t, v)
+
+	_, err = OpenPersistRestorer(db)
+	require.NoError(t, err)
+	v, err = DatabaseSchemaVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, SchemaVersion, v)
+}
+
+func TestMigrate_Legacy(t *testing.T) {
+	ctx := context.Background()
+	rng := pkgtest.Prng(t)
+	db := memorydb.NewDatabase()
+	pr, err := OpenPersistRestorer(db)
+	require.NoError(t, err)
+
+	peers := []wire.Address{wtest.NewRandomAddress(rng), wtest.NewRandomAddress(rng)}
+	ch := test.NewRandomChannel(ctx, t, pr, 0, peers, nil, rng)
+	// Unversioned databases may already contain receivers.
+	withRecv := test.NewRandomChannel(ctx, t, pr, 0, peers, nil, rng)
+	withRecv.SetReceiver(t, wtest.NewRandomAddress(rng))
+
+	// Downgrade the database to the unversioned layout.
+	require.NoError(t, pr.channelDB(ch.ID()).Delete("receiver"))
+	require.NoError(t, db.Delete(prefix.MetaDB+versionKey))
+	v, err := DatabaseSchemaVersion(db)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), v)
+	restored, err := pr.RestoreChannel(ctx, ch.ID())
+	require.NoError(t, err, "restoring unmigrated channel")
+	assert.Nil(t, restored.Receiver)
+
+	pr, err = OpenPersistRestorer(db)
+	require.NoError(t, err)
+	v, err = DatabaseSchemaVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, SchemaVersion, v)
+	ch.AssertPersisted(ctx, t)
+	withRecv.AssertPersisted(ctx, t)
+
+	restored, err = pr.RestoreChannel(ctx, ch.ID())
+	require.NoError(t, err)
+	assert.Nil(t, restored.Receiver)
+}
+
+func TestMigrate_Newer(t *testing.T) {
+	db := memorydb.NewDatabase()
+	require.NoError(t, putSchemaVersion(db, SchemaVersion+1))
+
+	_, err := OpenPersistRestorer(db)
+	assert.True(t, errors.Is(err, ErrUnsupportedSchema))
+}
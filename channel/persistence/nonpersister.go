@@ -20,6 +20,7 @@ import (
 	"github.com/pkg/errors"
 
 	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
 	"perun.network/go-perun/wire"
 )
 
@@ -42,6 +43,10 @@ func (nonPersistRestorer) Enabled(context.Context, channel.Source) error
 func (nonPersistRestorer) PhaseChanged(context.Context, channel.Source) error            { return nil }
 func (nonPersistRestorer) Close() error                                                  { return nil }
 
+func (nonPersistRestorer) ReceiverChanged(context.Context, channel.ID, wallet.Address) error {
+	return nil
+}
+
 // Restorer implementation
 
 func (nonPersistRestorer) ActivePeers(context.Context) ([]wire.Address, error) { return nil, nil }
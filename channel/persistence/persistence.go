@@ -19,6 +19,7 @@ import (
 	"io"
 
 	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
 	"perun.network/go-perun/wire"
 )
 
@@ -61,6 +62,12 @@ type (
 		// the current or staging transaction. Only the phase needs to be persisted.
 		PhaseChanged(context.Context, channel.Source) error
 
+		// ReceiverChanged is called when the withdrawal receiver of the channel
+		// with the given ID got set. A nil receiver means that the funds are
+		// withdrawn to the adjudicator's default receiver. Only the receiver
+		// needs to be persisted.
+		ReceiverChanged(ctx context.Context, id channel.ID, receiver wallet.Address) error
+
 		// Close is called by the client when it shuts down. No more persistence
 		// requests will be made after this call and the Persister should free up
 		// all possible resources.
@@ -89,6 +96,16 @@ type (
 		Restorer
 	}
 
+	// An AppRegistryScoper is a PersistRestorer whose restored channels can
+	// resolve their apps with a specific AppRegistry. A client that has its own
+	// AppRegistry uses it to restore channels with that registry.
+	AppRegistryScoper interface {
+		// WithAppRegistry should return a PersistRestorer on the same data
+		// source and sink that resolves the apps of restored channels with the
+		// given registry. Closing either of them closes the data source.
+		WithAppRegistry(*channel.AppRegistry) PersistRestorer
+	}
+
 	// A ChannelIterator is an iterator over Channels, i.e., channel data that is
 	// necessary for restoring a channel machine. It needs to be implemented by a
 	// persistence backend to allow the framework to restore channels.
@@ -122,11 +139,14 @@ type (
 
 	// Channel holds all data that is necessary to restore a channel controller
 	// and additionally the related peers for this channel. If the channel is a
-	// sub-channel, also holds the parent channel's ID.
+	// sub-channel, also holds the parent channel's ID. Receiver is the
+	// withdrawal receiver of the channel or nil if the adjudicator's default
+	// is used.
 	Channel struct {
 		chSource
-		PeersV []wire.Address
-		Parent *channel.ID
+		PeersV   []wire.Address
+		Parent   *channel.ID
+		Receiver wallet.Address
 	}
 )
 
@@ -151,11 +171,13 @@ func NewChannel() *Channel {
 		chSource{ParamsV: new(channel.Params)},
 		nil,
 		nil,
+		nil,
 	}
 }
 
 // FromSource creates a new Channel object from given `channel.Source`, the
-// channel's network peers, and the parent channel ID, if it exists.
+// channel's network peers, and the parent channel ID, if it exists. The
+// receiver is unset.
 func FromSource(s channel.Source, ps []wire.Address, parent *channel.ID) *Channel {
 	return &Channel{
 		chSource{
@@ -167,6 +189,7 @@ func FromSource(s channel.Source, ps []wire.Address, parent *channel.ID) *Channe
 		},
 		ps,
 		parent,
+		nil,
 	}
 }
 
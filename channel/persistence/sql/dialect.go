@@ -0,0 +1,133 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// Dialect describes the differences between the SQL dialects of the supported
+// databases.
+type Dialect struct {
+	// Key is the column type of binary keys, like channel IDs and encoded peer
+	// addresses, which are used in primary keys.
+	Key string
+	// Blob is the column type of encoded values of arbitrary length.
+	Blob string
+	// Placeholder returns the placeholder of the n-th statement argument,
+	// starting at 1.
+	Placeholder func(n int) string
+}
+
+// Supported dialects.
+var (
+	// SQLite is the dialect of SQLite.
+	SQLite = &Dialect{Key: "BLOB", Blob: "BLOB", Placeholder: questionMark}
+	// MySQL is the dialect of MySQL and MariaDB. Binary keys must not be
+	// longer than 255 bytes.
+	MySQL = &Dialect{Key: "VARBINARY(255)", Blob: "LONGBLOB", Placeholder: questionMark}
+	// PostgreSQL is the dialect of PostgreSQL.
+	PostgreSQL = &Dialect{Key: "BYTEA", Blob: "BYTEA", Placeholder: dollarNumber}
+)
+
+func questionMark(int) string { return "?" }
+
+func dollarNumber(n int) string { return "$" + strconv.Itoa(n) }
+
+// rebind replaces the "?" placeholders of a statement by the placeholders of
+// the dialect. The statements of this package contain no other question marks.
+func (d *Dialect) rebind(stmt string) string {
+	if d.Placeholder(1) == "?" {
+		return stmt
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range stmt {
+		if c != '?' {
+			b.WriteRune(c)
+			continue
+		}
+		n++
+		b.WriteString(d.Placeholder(n))
+	}
+	return b.String()
+}
+
+// schema returns the statements that create all tables.
+func (d *Dialect) schema() []string {
+	r := strings.NewReplacer("KEY_TYPE", d.Key, "BLOB_TYPE", d.Blob)
+	stmts := make([]string, len(schema))
+	for i, stmt := range schema {
+		stmts[i] = r.Replace(stmt)
+	}
+	return stmts
+}
+
+// database is a database that translates the placeholders of all statements
+// to its dialect.
+type database struct {
+	db      *sql.DB
+	dialect *Dialect
+}
+
+// QueryContext runs a query with the dialect's placeholders.
+func (d *database) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.db.QueryContext(ctx, d.dialect.rebind(query), args...)
+}
+
+// QueryRowContext runs a query with the dialect's placeholders.
+func (d *database) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.db.QueryRowContext(ctx, d.dialect.rebind(query), args...)
+}
+
+// BeginTx starts a transaction that translates the placeholders of all
+// statements to the dialect.
+func (d *database) BeginTx(ctx context.Context, opts *sql.TxOptions) (*dbTx, error) {
+	tx, err := d.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &dbTx{tx: tx, dialect: d.dialect}, nil
+}
+
+// Close closes the database.
+func (d *database) Close() error {
+	return d.db.Close()
+}
+
+// dbTx is a transaction that translates the placeholders of all statements to
+// its dialect.
+type dbTx struct {
+	tx      *sql.Tx
+	dialect *Dialect
+}
+
+// ExecContext executes a statement with the dialect's placeholders.
+func (t *dbTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, t.dialect.rebind(query), args...)
+}
+
+// Commit commits the transaction.
+func (t *dbTx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback aborts the transaction.
+func (t *dbTx) Rollback() error {
+	return t.tx.Rollback()
+}
@@ -0,0 +1,41 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialect_Rebind(t *testing.T) {
+	const stmt = `UPDATE channels SET receiver = ? WHERE id = ?`
+	assert.Equal(t, stmt, SQLite.rebind(stmt))
+	assert.Equal(t, stmt, MySQL.rebind(stmt))
+	assert.Equal(t, `UPDATE channels SET receiver = $1 WHERE id = $2`, PostgreSQL.rebind(stmt))
+}
+
+func TestDialect_Schema(t *testing.T) {
+	for _, d := range []*Dialect{SQLite, MySQL, PostgreSQL} {
+		stmts := d.schema()
+		assert.Len(t, stmts, len(schema))
+		for _, stmt := range stmts {
+			assert.False(t, strings.Contains(stmt, "_TYPE"), "column type not replaced: %s", stmt)
+		}
+	}
+	assert.Contains(t, MySQL.schema()[0], "id       VARBINARY(255) PRIMARY KEY")
+	assert.Contains(t, PostgreSQL.schema()[0], "params   BYTEA NOT NULL")
+}
@@ -0,0 +1,28 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sql contains an implementation of the channel persister and restorer
+// interfaces using a relational database via database/sql.
+//
+// The channel data is stored in the tables channels, transactions, signatures,
+// peers and parents, which are created if they do not exist. Encoded values,
+// like channel parameters and states, are stored as blobs, while columns like
+// the phase and the state version are stored as integers so that they can be
+// queried with standard tooling.
+//
+// The column types and statement placeholders depend on the SQL dialect of the
+// database. The dialects SQLite, MySQL (and MariaDB) and PostgreSQL are
+// supported, and other dialects can be described by a Dialect. Only SQLite is
+// covered by the tests of this package.
+package sql // import "perun.network/go-perun/channel/persistence/sql"
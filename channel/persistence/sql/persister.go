@@ -0,0 +1,255 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	perunio "perun.network/go-perun/pkg/io"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// ChannelCreated inserts a channel with its transactions, peers and parent
+// into the database.
+func (pr *PersistRestorer) ChannelCreated(ctx context.Context, s channel.Source, peers []wire.Address, parent *channel.ID) error {
+	return pr.update(ctx, func(ctx context.Context, tx *dbTx) error {
+		return insertChannel(ctx, tx, s, peers, parent)
+	})
+}
+
+// ChannelRemoved deletes a channel and all its data from the database.
+func (pr *PersistRestorer) ChannelRemoved(ctx context.Context, id channel.ID) error {
+	return pr.update(ctx, func(ctx context.Context, tx *dbTx) error {
+		if ok, err := deleteChannel(ctx, tx, id); err != nil {
+			return err
+		} else if !ok {
+			return errors.Errorf("channel doesn't exist: %x", id)
+		}
+		return nil
+	})
+}
+
+// ReplaceChannel atomically replaces a persisted channel with ch, including its
+// peers, parent and receiver. If the channel is not persisted yet, it is
+// inserted.
+func (pr *PersistRestorer) ReplaceChannel(ctx context.Context, ch *persistence.Channel) error {
+	return pr.update(ctx, func(ctx context.Context, tx *dbTx) error {
+		if _, err := deleteChannel(ctx, tx, ch.ID()); err != nil {
+			return err
+		}
+		if err := insertChannel(ctx, tx, ch, ch.PeersV, ch.Parent); err != nil {
+			return err
+		}
+		return updateReceiver(ctx, tx, ch.ID(), ch.Receiver)
+	})
+}
+
+// insertChannel inserts a channel with its transactions, peers and parent.
+func insertChannel(ctx context.Context, tx *dbTx, s channel.Source, peers []wire.Address, parent *channel.ID) error {
+	id := s.ID()
+	params, err := encode(s.Params())
+	if err != nil {
+		return errors.WithMessage(err, "encoding params")
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO channels (id, idx, params, phase) VALUES (?, ?, ?, ?)`,
+		id[:], s.Idx(), params, s.Phase()); err != nil {
+		return errors.Wrap(err, "inserting channel")
+	}
+	for _, t := range []struct {
+		kind string
+		tx   channel.Transaction
+	}{{kindCurrent, s.CurrentTX()}, {kindStaging, s.StagingTX()}} {
+		if err := insertTX(ctx, tx, id, t.kind, t.tx); err != nil {
+			return err
+		}
+	}
+
+	for _, peer := range peers {
+		p, err := encode(peer)
+		if err != nil {
+			return errors.WithMessage(err, "encoding peer")
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO peers (peer, channel_id) VALUES (?, ?)`, p, id[:]); err != nil {
+			return errors.Wrap(err, "inserting peer")
+		}
+	}
+
+	if parent != nil {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO parents (channel_id, parent_id) VALUES (?, ?)`, id[:], parent[:]); err != nil {
+			return errors.Wrap(err, "inserting parent")
+		}
+	}
+	return nil
+}
+
+// deleteChannel deletes a channel and all its data. It returns whether the
+// channel existed.
+func deleteChannel(ctx context.Context, tx *dbTx, id channel.ID) (bool, error) {
+	res, err := tx.ExecContext(ctx, `DELETE FROM channels WHERE id = ?`, id[:])
+	if err != nil {
+		return false, errors.Wrap(err, "deleting channel")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "deleting channel")
+	}
+
+	for _, table := range []string{"transactions", "signatures", "peers", "parents"} {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM `+table+` WHERE channel_id = ?`, id[:]); err != nil {
+			return false, errors.Wrapf(err, "deleting from %s", table)
+		}
+	}
+	return n > 0, nil
+}
+
+// Staged persists the staging transaction as well as the channel's phase.
+func (pr *PersistRestorer) Staged(ctx context.Context, s channel.Source) error {
+	return pr.update(ctx, func(ctx context.Context, tx *dbTx) error {
+		if err := replaceTX(ctx, tx, s.ID(), kindStaging, s.StagingTX()); err != nil {
+			return err
+		}
+		return updatePhase(ctx, tx, s)
+	})
+}
+
+// SigAdded persists the signature of the given participant on the staging
+// transaction.
+func (pr *PersistRestorer) SigAdded(ctx context.Context, s channel.Source, idx channel.Index) error {
+	id := s.ID()
+	return pr.update(ctx, func(ctx context.Context, tx *dbTx) error {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM signatures WHERE channel_id = ? AND kind = ? AND idx = ?`,
+			id[:], kindStaging, idx); err != nil {
+			return errors.Wrap(err, "deleting signature")
+		}
+		sigs := s.StagingTX().Sigs
+		if int(idx) >= len(sigs) || sigs[idx] == nil {
+			return nil
+		}
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO signatures (channel_id, kind, idx, sig) VALUES (?, ?, ?, ?)`,
+			id[:], kindStaging, idx, []byte(sigs[idx]))
+		return errors.Wrap(err, "inserting signature")
+	})
+}
+
+// Enabled persists the channel's staging and current transaction, and phase.
+func (pr *PersistRestorer) Enabled(ctx context.Context, s channel.Source) error {
+	return pr.update(ctx, func(ctx context.Context, tx *dbTx) error {
+		if err := replaceTX(ctx, tx, s.ID(), kindCurrent, s.CurrentTX()); err != nil {
+			return err
+		}
+		if err := replaceTX(ctx, tx, s.ID(), kindStaging, s.StagingTX()); err != nil {
+			return err
+		}
+		return updatePhase(ctx, tx, s)
+	})
+}
+
+// PhaseChanged persists the channel's phase.
+func (pr *PersistRestorer) PhaseChanged(ctx context.Context, s channel.Source) error {
+	return pr.update(ctx, func(ctx context.Context, tx *dbTx) error {
+		return updatePhase(ctx, tx, s)
+	})
+}
+
+// ReceiverChanged persists the channel's withdrawal receiver.
+func (pr *PersistRestorer) ReceiverChanged(ctx context.Context, id channel.ID, receiver wallet.Address) error {
+	return pr.update(ctx, func(ctx context.Context, tx *dbTx) error {
+		return updateReceiver(ctx, tx, id, receiver)
+	})
+}
+
+func updateReceiver(ctx context.Context, tx *dbTx, id channel.ID, receiver wallet.Address) error {
+	var rec []byte
+	if receiver != nil {
+		var err error
+		if rec, err = encode(receiver); err != nil {
+			return errors.WithMessage(err, "encoding receiver")
+		}
+	}
+	_, err := tx.ExecContext(ctx, `UPDATE channels SET receiver = ? WHERE id = ?`, rec, id[:])
+	return errors.Wrap(err, "updating receiver")
+}
+
+func updatePhase(ctx context.Context, tx *dbTx, s channel.Source) error {
+	id := s.ID()
+	_, err := tx.ExecContext(ctx, `UPDATE channels SET phase = ? WHERE id = ?`, s.Phase(), id[:])
+	return errors.Wrap(err, "updating phase")
+}
+
+// replaceTX replaces the transaction of the given kind and its signatures.
+func replaceTX(ctx context.Context, tx *dbTx, id channel.ID, kind string, t channel.Transaction) error {
+	for _, table := range []string{"transactions", "signatures"} {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM `+table+` WHERE channel_id = ? AND kind = ?`, id[:], kind); err != nil {
+			return errors.Wrapf(err, "deleting from %s", table)
+		}
+	}
+	return insertTX(ctx, tx, id, kind, t)
+}
+
+// insertTX inserts the transaction of the given kind and its signatures.
+func insertTX(ctx context.Context, tx *dbTx, id channel.ID, kind string, t channel.Transaction) error {
+	var (
+		version *uint64
+		state   []byte
+	)
+	if t.State != nil {
+		var err error
+		if state, err = encode(t.State); err != nil {
+			return errors.WithMessagef(err, "encoding %s state", kind)
+		}
+		version = &t.State.Version
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO transactions (channel_id, kind, version, state) VALUES (?, ?, ?, ?)`,
+		id[:], kind, version, state); err != nil {
+		return errors.Wrapf(err, "inserting %s transaction", kind)
+	}
+
+	if t.State == nil {
+		return nil
+	}
+	for idx, sig := range t.Sigs {
+		if sig == nil {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO signatures (channel_id, kind, idx, sig) VALUES (?, ?, ?, ?)`,
+			id[:], kind, idx, []byte(sig)); err != nil {
+			return errors.Wrapf(err, "inserting %s signature", kind)
+		}
+	}
+	return nil
+}
+
+// encode encodes v into a byte slice.
+func encode(v perunio.Encoder) ([]byte, error) {
+	var buf bytes.Buffer
+	err := v.Encode(&buf)
+	return buf.Bytes(), err
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+)
+
+var (
+	_ persistence.PersistRestorer   = (*PersistRestorer)(nil)
+	_ persistence.AppRegistryScoper = (*PersistRestorer)(nil)
+)
+
+// PersistRestorer implements both the persister and the restorer interface
+// using a relational database.
+type PersistRestorer struct {
+	db     *database
+	appReg *channel.AppRegistry
+}
+
+// schema contains the statements that create all tables. KEY_TYPE and
+// BLOB_TYPE are replaced by the column types of the dialect.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS channels (
+		id       KEY_TYPE PRIMARY KEY,
+		idx      INTEGER NOT NULL,
+		params   BLOB_TYPE NOT NULL,
+		phase    INTEGER NOT NULL,
+		receiver BLOB_TYPE
+	)`,
+	`CREATE TABLE IF NOT EXISTS transactions (
+		channel_id KEY_TYPE NOT NULL,
+		kind       VARCHAR(16) NOT NULL,
+		version    BIGINT,
+		state      BLOB_TYPE,
+		PRIMARY KEY (channel_id, kind)
+	)`,
+	`CREATE TABLE IF NOT EXISTS signatures (
+		channel_id KEY_TYPE NOT NULL,
+		kind       VARCHAR(16) NOT NULL,
+		idx        INTEGER NOT NULL,
+		sig        BLOB_TYPE NOT NULL,
+		PRIMARY KEY (channel_id, kind, idx)
+	)`,
+	`CREATE TABLE IF NOT EXISTS peers (
+		peer       KEY_TYPE NOT NULL,
+		channel_id KEY_TYPE NOT NULL,
+		PRIMARY KEY (peer, channel_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS parents (
+		channel_id KEY_TYPE PRIMARY KEY,
+		parent_id  KEY_TYPE NOT NULL
+	)`,
+}
+
+// Kinds of transactions in the transactions and signatures tables.
+const (
+	kindCurrent = "current"
+	kindStaging = "staging"
+)
+
+// NewPersistRestorer creates a new PersistRestorer for the supplied SQLite
+// database and creates the tables if they do not exist. Apps of restored
+// channels are resolved with the global app registry.
+func NewPersistRestorer(db *sql.DB) (*PersistRestorer, error) {
+	return NewPersistRestorerWithDialect(db, SQLite, channel.GlobalAppRegistry())
+}
+
+// NewPersistRestorerWithAppRegistry creates a new PersistRestorer for the
+// supplied SQLite database that resolves the apps of restored channels with
+// the given app registry. The tables are created if they do not exist.
+func NewPersistRestorerWithAppRegistry(db *sql.DB, reg *channel.AppRegistry) (*PersistRestorer, error) {
+	return NewPersistRestorerWithDialect(db, SQLite, reg)
+}
+
+// NewPersistRestorerWithDialect creates a new PersistRestorer for the supplied
+// database of the given dialect that resolves the apps of restored channels
+// with the given app registry. The tables are created if they do not exist.
+func NewPersistRestorerWithDialect(db *sql.DB, dialect *Dialect, reg *channel.AppRegistry) (*PersistRestorer, error) {
+	for _, stmt := range dialect.schema() {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, errors.Wrap(err, "creating tables")
+		}
+	}
+	return &PersistRestorer{
+		db:     &database{db: db, dialect: dialect},
+		appReg: reg,
+	}, nil
+}
+
+// Close closes the PersistRestorer and the underlying database.
+func (pr *PersistRestorer) Close() error {
+	return errors.Wrap(pr.db.Close(), "closing database")
+}
+
+// WithAppRegistry returns a PersistRestorer on the same database that resolves
+// the apps of restored channels with the given app registry.
+func (pr *PersistRestorer) WithAppRegistry(reg *channel.AppRegistry) persistence.PersistRestorer {
+	scoped := *pr
+	scoped.appReg = reg
+	return &scoped
+}
+
+// decoder returns a reader for decoding from r that resolves apps with the
+// app registry of the PersistRestorer.
+func (pr *PersistRestorer) decoder(r io.Reader) io.Reader {
+	return channel.WithAppRegistry(r, pr.appReg)
+}
+
+// update runs fn in a database transaction, which is committed if fn returns
+// no error and rolled back otherwise. A nil context is replaced by the
+// background context before being passed to fn.
+func (pr *PersistRestorer) update(ctx context.Context, fn func(context.Context, *dbTx) error) error {
+	ctx = nonNil(ctx)
+	tx, err := pr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+	if err := fn(ctx, tx); err != nil {
+		tx.Rollback() // nolint:errcheck,gosec
+		return err
+	}
+	return errors.Wrap(tx.Commit(), "committing transaction")
+}
+
+// nonNil returns ctx or the background context if ctx is nil. The channel
+// state machine may call the persister with a nil context.
+func nonNil(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	_ "perun.network/go-perun/backend/sim"
+	"perun.network/go-perun/channel/persistence/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+	wtest "perun.network/go-perun/wallet/test"
+)
+
+func TestPersistRestorer_Generic(t *testing.T) {
+	pr := newPersistRestorer(t)
+	defer func() { require.NoError(t, pr.Close()) }()
+	test.GenericPersistRestorerTest(
+		context.Background(),
+		t,
+		pkgtest.Prng(t),
+		pr,
+		4,
+		16)
+}
+
+func TestPersistRestorer_Reopen(t *testing.T) {
+	ctx := context.Background()
+	rng := pkgtest.Prng(t)
+	path := filepath.Join(t.TempDir(), "channels.db")
+	pr := openPersistRestorer(t, path)
+
+	ch := test.NewClient(ctx, t, rng, pr).NewChannel(t, wtest.NewRandomAddress(rng), nil)
+	ch.Init(t, rng)
+	ch.SignAll(t)
+	ch.EnableInit(t)
+	ch.SetFunded(t)
+	receiver := wtest.NewRandomAddress(rng)
+	ch.SetReceiver(t, receiver)
+	require.NoError(t, pr.Close())
+
+	pr = openPersistRestorer(t, path)
+	defer func() { require.NoError(t, pr.Close()) }()
+	restored, err := pr.RestoreChannel(ctx, ch.ID())
+	require.NoError(t, err)
+	ch.RequireEqual(t, restored)
+	assert.True(t, receiver.Equals(restored.Receiver))
+}
+
+// newPersistRestorer creates a PersistRestorer on a new SQLite database.
+func newPersistRestorer(t *testing.T) *PersistRestorer {
+	return openPersistRestorer(t, filepath.Join(t.TempDir(), "channels.db"))
+}
+
+func openPersistRestorer(t *testing.T, path string) *PersistRestorer {
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	// SQLite does not support concurrent writers.
+	db.SetMaxOpenConns(1)
+	pr, err := NewPersistRestorer(db)
+	require.NoError(t, err)
+	return pr
+}
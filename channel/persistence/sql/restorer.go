@@ -0,0 +1,250 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	perunio "perun.network/go-perun/pkg/io"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+)
+
+var _ persistence.ChannelIterator = (*ChannelIterator)(nil)
+
+// ChannelIterator implements the persistence.ChannelIterator interface. The
+// channels are read from the database one by one when advancing the iterator.
+type ChannelIterator struct {
+	err error
+	ch  *persistence.Channel
+	ids []channel.ID
+
+	restorer *PersistRestorer
+}
+
+// ActivePeers returns a list of all peers with which a channel is persisted.
+func (pr *PersistRestorer) ActivePeers(ctx context.Context) ([]wire.Address, error) {
+	rows, err := pr.db.QueryContext(nonNil(ctx), `SELECT DISTINCT peer FROM peers`)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying peers")
+	}
+	defer rows.Close() // nolint:errcheck
+
+	var peers []wire.Address
+	for rows.Next() {
+		var p []byte
+		if err := rows.Scan(&p); err != nil {
+			return nil, errors.Wrap(err, "scanning peer")
+		}
+		addr, err := wire.DecodeAddress(bytes.NewReader(p))
+		if err != nil {
+			return nil, errors.WithMessagef(err, "decoding peer (%x)", p)
+		}
+		peers = append(peers, addr)
+	}
+	return peers, errors.Wrap(rows.Err(), "querying peers")
+}
+
+// RestorePeer returns an iterator over all persisted channels which the given
+// peer is a part of.
+func (pr *PersistRestorer) RestorePeer(addr wire.Address) (persistence.ChannelIterator, error) {
+	p, err := encode(addr)
+	if err != nil {
+		return nil, errors.WithMessage(err, "encoding peer")
+	}
+	rows, err := pr.db.QueryContext(context.Background(), `SELECT channel_id FROM peers WHERE peer = ? ORDER BY channel_id`, p)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying peer channels")
+	}
+	defer rows.Close() // nolint:errcheck
+
+	it := &ChannelIterator{restorer: pr}
+	for rows.Next() {
+		var id []byte
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.Wrap(err, "scanning channel ID")
+		}
+		it.ids = append(it.ids, toID(id))
+	}
+	return it, errors.Wrap(rows.Err(), "querying peer channels")
+}
+
+// RestoreChannel restores a single channel.
+func (pr *PersistRestorer) RestoreChannel(ctx context.Context, id channel.ID) (*persistence.Channel, error) {
+	ch, err := pr.restoreChannel(nonNil(ctx), id)
+	if err == sql.ErrNoRows {
+		return nil, errors.Errorf("could not find channel %x", id)
+	} else if err != nil {
+		return nil, errors.WithMessagef(err, "error restoring channel %x", id)
+	}
+	return ch, nil
+}
+
+// restoreChannel reads the channel with the given ID from the database. It
+// returns sql.ErrNoRows if the channel does not exist.
+func (pr *PersistRestorer) restoreChannel(ctx context.Context, id channel.ID) (*persistence.Channel, error) {
+	ch := persistence.NewChannel()
+	var params, receiver []byte
+	if err := pr.db.QueryRowContext(ctx,
+		`SELECT idx, params, phase, receiver FROM channels WHERE id = ?`, id[:]).
+		Scan(&ch.IdxV, &params, &ch.PhaseV, &receiver); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "querying channel")
+	}
+	if err := perunio.Decode(pr.decoder(bytes.NewReader(params)), ch.ParamsV); err != nil {
+		return nil, errors.WithMessage(err, "decoding params")
+	}
+	if receiver != nil {
+		var err error
+		if ch.Receiver, err = wallet.DecodeAddress(bytes.NewReader(receiver)); err != nil {
+			return nil, errors.WithMessage(err, "decoding receiver")
+		}
+	}
+
+	var err error
+	numParts := len(ch.ParamsV.Parts)
+	if ch.CurrentTXV, err = pr.restoreTX(ctx, id, kindCurrent, numParts); err != nil {
+		return nil, err
+	}
+	if ch.StagingTXV, err = pr.restoreTX(ctx, id, kindStaging, numParts); err != nil {
+		return nil, err
+	}
+	if ch.StagingTXV.Sigs == nil {
+		ch.StagingTXV.Sigs = make([]wallet.Sig, numParts)
+	}
+	if ch.PeersV, err = pr.restorePeers(ctx, id); err != nil {
+		return nil, err
+	}
+	if ch.Parent, err = pr.restoreParent(ctx, id); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// restoreTX reads the transaction of the given kind and its signatures.
+func (pr *PersistRestorer) restoreTX(ctx context.Context, id channel.ID, kind string, numParts int) (t channel.Transaction, err error) {
+	var state []byte
+	if err := pr.db.QueryRowContext(ctx,
+		`SELECT state FROM transactions WHERE channel_id = ? AND kind = ?`, id[:], kind).
+		Scan(&state); err != nil {
+		return t, errors.Wrapf(err, "querying %s transaction", kind)
+	}
+	if state == nil {
+		return t, nil
+	}
+	t.State = new(channel.State)
+	if err := perunio.Decode(pr.decoder(bytes.NewReader(state)), t.State); err != nil {
+		return t, errors.WithMessagef(err, "decoding %s state", kind)
+	}
+
+	t.Sigs = make([]wallet.Sig, numParts)
+	rows, err := pr.db.QueryContext(ctx,
+		`SELECT idx, sig FROM signatures WHERE channel_id = ? AND kind = ?`, id[:], kind)
+	if err != nil {
+		return t, errors.Wrapf(err, "querying %s signatures", kind)
+	}
+	defer rows.Close() // nolint:errcheck
+	for rows.Next() {
+		var (
+			idx int
+			sig []byte
+		)
+		if err := rows.Scan(&idx, &sig); err != nil {
+			return t, errors.Wrapf(err, "scanning %s signature", kind)
+		}
+		if idx < 0 || idx >= numParts {
+			return t, errors.Errorf("%s signature index %d out of range", kind, idx)
+		}
+		t.Sigs[idx] = sig
+	}
+	return t, errors.Wrapf(rows.Err(), "querying %s signatures", kind)
+}
+
+// restorePeers reads the peers of the channel.
+func (pr *PersistRestorer) restorePeers(ctx context.Context, id channel.ID) ([]wire.Address, error) {
+	rows, err := pr.db.QueryContext(ctx, `SELECT peer FROM peers WHERE channel_id = ?`, id[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "querying peers")
+	}
+	defer rows.Close() // nolint:errcheck
+
+	var peers []wire.Address
+	for rows.Next() {
+		var p []byte
+		if err := rows.Scan(&p); err != nil {
+			return nil, errors.Wrap(err, "scanning peer")
+		}
+		addr, err := wire.DecodeAddress(bytes.NewReader(p))
+		if err != nil {
+			return nil, errors.WithMessage(err, "decoding peer")
+		}
+		peers = append(peers, addr)
+	}
+	return peers, errors.Wrap(rows.Err(), "querying peers")
+}
+
+// restoreParent reads the parent ID of the channel, which is nil for ledger
+// channels.
+func (pr *PersistRestorer) restoreParent(ctx context.Context, id channel.ID) (*channel.ID, error) {
+	var parent []byte
+	err := pr.db.QueryRowContext(ctx, `SELECT parent_id FROM parents WHERE channel_id = ?`, id[:]).
+		Scan(&parent)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "querying parent")
+	}
+	pid := toID(parent)
+	return &pid, nil
+}
+
+func toID(b []byte) (id channel.ID) {
+	copy(id[:], b)
+	return
+}
+
+// Next advances the iterator and returns whether there is another channel.
+func (i *ChannelIterator) Next(ctx context.Context) bool {
+	if i.err != nil || len(i.ids) == 0 {
+		return false
+	}
+	id := i.ids[0]
+	i.ids = i.ids[1:]
+	i.ch, i.err = i.restorer.restoreChannel(nonNil(ctx), id)
+	if i.err != nil {
+		i.err = errors.WithMessagef(i.err, "restoring channel %x", id)
+	}
+	return i.err == nil
+}
+
+// Channel returns the iterator's current channel.
+func (i *ChannelIterator) Channel() *persistence.Channel {
+	return i.ch
+}
+
+// Close closes the iterator and returns the last error that occurred when
+// advancing the iterator.
+func (i *ChannelIterator) Close() error {
+	i.ids = nil
+	return i.err
+}
@@ -34,6 +34,7 @@ type Channel struct {
 	accounts []wallet.Account
 	peers    []wire.Address
 	parent   *channel.ID
+	receiver wallet.Address
 	*persistence.StateMachine
 
 	pr  persistence.PersistRestorer
@@ -103,6 +104,16 @@ func (c *Channel) AssertPersisted(ctx context.Context, t require.TestingT) {
 	c.RequireEqual(t, ch)
 	requireEqualPeers(t, c.peers, ch.PeersV)
 	require.Equal(t, c.parent, ch.Parent)
+	requireEqualReceiver(t, c.receiver, ch.Receiver)
+}
+
+func requireEqualReceiver(t require.TestingT, expected, actual wallet.Address) {
+	if expected == nil || actual == nil {
+		require.Nil(t, actual, "Receiver")
+		require.Nil(t, expected, "Receiver")
+		return
+	}
+	require.True(t, expected.Equals(actual), "Receiver")
 }
 
 // RequireEqual asserts that the channel is equal to the provided channel state.
@@ -180,6 +191,14 @@ func (c *Channel) SignAll(t require.TestingT) {
 	}
 }
 
+// SetReceiver persists the given withdrawal receiver and then checks the
+// persistence.
+func (c *Channel) SetReceiver(t require.TestingT, receiver wallet.Address) {
+	require.NoError(t, c.pr.ReceiverChanged(c.ctx, c.ID(), receiver))
+	c.receiver = receiver
+	c.AssertPersisted(c.ctx, t)
+}
+
 // SetFunded calls SetFunded on the state machine and then checks the persistence.
 func (c *Channel) SetFunded(t require.TestingT) {
 	require.NoError(t, c.StateMachine.SetFunded(c.ctx))
@@ -27,6 +27,7 @@ import (
 
 	"perun.network/go-perun/channel"
 	"perun.network/go-perun/channel/persistence"
+	"perun.network/go-perun/wallet"
 	"perun.network/go-perun/wire"
 )
 
@@ -136,6 +137,17 @@ func (pr *PersistRestorer) PhaseChanged(_ context.Context, s channel.Source) err
 	return nil
 }
 
+// ReceiverChanged only persists the withdrawal receiver.
+func (pr *PersistRestorer) ReceiverChanged(_ context.Context, id channel.ID, receiver wallet.Address) error {
+	ch, ok := pr.get(id)
+	if !ok {
+		return errors.Errorf("channel doesn't exist: %x", id)
+	}
+
+	ch.Receiver = receiver
+	return nil
+}
+
 // Close resets the persister's memory, i.e., all internally persisted channel
 // data is deleted. It can be reused afterwards.
 func (pr *PersistRestorer) Close() error {
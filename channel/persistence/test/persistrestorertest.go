@@ -127,6 +127,11 @@ func GenericPersistRestorerTest(
 
 				ch.SetFunded(t)
 
+				// Set and reset withdrawal receiver
+				ch.SetReceiver(t, wtest.NewRandomAddress(rng))
+				ch.SetReceiver(t, nil)
+				ch.SetReceiver(t, wtest.NewRandomAddress(rng))
+
 				// Update state
 				state1 := ch.State().Clone()
 				state1.Version++
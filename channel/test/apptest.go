@@ -0,0 +1,332 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/channel"
+	perunio "perun.network/go-perun/pkg/io"
+	pkgbig "perun.network/go-perun/pkg/math/big"
+	"perun.network/go-perun/wallet"
+)
+
+type (
+	// A StateGenerator generates random channel parameters and a matching
+	// valid initial state for the app under test.
+	StateGenerator func(*rand.Rand) (*channel.Params, *channel.State)
+
+	// A TransitionGenerator generates a random transition of the app under
+	// test from the given state. It returns the new state, with its version
+	// increased, and the index of the acting participant. It must not modify
+	// the passed state.
+	TransitionGenerator func(rng *rand.Rand, params *channel.Params, from *channel.State) (*channel.State, channel.Index)
+
+	// AppSetup provides all objects needed for the generic app tests.
+	AppSetup struct {
+		// App is the app under test.
+		App channel.App
+		// NewState generates random initial states of App.
+		NewState StateGenerator
+		// NewTransition generates random valid transitions of App. It is only
+		// used if App is a StateApp.
+		NewTransition TransitionGenerator
+		// NewInvalidTransition optionally generates random transitions that
+		// App must reject with a StateTransitionError. It is only used if App
+		// is a StateApp. Invariants that the channel machine checks before
+		// calling the app, like the preservation of the channel ID and the
+		// allocation sums, need not be covered; GenericAppTest checks them by
+		// mutating the valid transitions.
+		NewInvalidTransition TransitionGenerator
+		// ShallowFields are the names of the fields of the app data that
+		// Data.Clone does not copy deeply, e.g., because they are immutable.
+		// Nested fields are given as dot-separated paths.
+		ShallowFields []string
+		// NumRuns is the number of random initial states to test. Defaults
+		// to 10.
+		NumRuns int
+		// NumTransitions is the number of consecutive transitions to test per
+		// initial state. Defaults to 10. The transitions stop early at a final
+		// state.
+		NumTransitions int
+	}
+)
+
+const defaultNumAppRuns = 10
+
+// GenericAppTest tests the consistency of the passed app's implementation of
+// the channel.App interface with randomly generated states and transitions.
+// It checks that
+//   - app data survives an encoding round-trip through App.DecodeData,
+//   - Data.Clone produces deep copies,
+//   - generated initial states and transitions are valid,
+//   - generated transitions preserve the channel ID, the number of assets and
+//     the allocation sums and pass the checks of a channel.StateMachine,
+//   - generated transitions that are mutated to change the channel ID, an
+//     allocation sum or the number of assets are rejected by the StateMachine,
+//   - generated invalid transitions are rejected with a StateTransitionError,
+//   - ValidInit and ValidTransition do not modify the passed states.
+func GenericAppTest(t *testing.T, rng *rand.Rand, s *AppSetup) {
+	require.NotNil(t, s.App, "App must not be nil")
+	require.NotNil(t, s.NewState, "NewState must not be nil")
+	stateApp, isStateApp := s.App.(channel.StateApp)
+	if isStateApp {
+		require.NotNil(t, s.NewTransition, "NewTransition must not be nil for StateApps")
+	}
+
+	for run := 0; run < orDefault(s.NumRuns, defaultNumAppRuns); run++ {
+		params, state := s.NewState(rng)
+		require.Equal(t, params.ID(), state.ID, "generated state must match generated params")
+
+		t.Run("DecodeData", func(t *testing.T) { genericDecodeDataTest(t, s.App, state.Data) })
+		t.Run("Clone", func(t *testing.T) { genericDataCloneTest(t, state.Data, s.ShallowFields) })
+		if !isStateApp {
+			continue
+		}
+
+		t.Run("ValidInit", func(t *testing.T) {
+			assertUnmodified(t, state, func() {
+				assert.NoError(t, stateApp.ValidInit(params, state), "generated initial state must be valid")
+			})
+		})
+		t.Run("ValidTransition", func(t *testing.T) {
+			from := state
+			// The channel machine does not advance final states.
+			for i := 0; i < orDefault(s.NumTransitions, defaultNumAppRuns) && !from.IsFinal; i++ {
+				if s.NewInvalidTransition != nil {
+					to, actor := s.NewInvalidTransition(rng, params, from)
+					genericInvalidTransitionTest(t, stateApp, params, from, to, actor)
+				}
+				to, actor := s.NewTransition(rng, params, from)
+				genericTransitionTest(t, stateApp, params, from, to, actor)
+				genericMachineTest(t, rng, params, from, to, actor)
+				genericDecodeDataTest(t, s.App, to.Data)
+				genericDataCloneTest(t, to.Data, s.ShallowFields)
+				from = to
+			}
+		})
+	}
+}
+
+// genericDecodeDataTest checks that the data is decoded by the app to data of
+// the same type and encoding.
+func genericDecodeDataTest(t *testing.T, app channel.App, data channel.Data) {
+	var buf bytes.Buffer
+	require.NoError(t, perunio.Encode(&buf, data), "encoding data")
+	encoded := buf.Bytes()
+
+	decoded, err := app.DecodeData(bytes.NewReader(encoded))
+	require.NoError(t, err, "decoding data")
+	assert.IsType(t, data, decoded, "decoded data must have the same type")
+
+	var reencoded bytes.Buffer
+	require.NoError(t, perunio.Encode(&reencoded, decoded), "re-encoding decoded data")
+	assert.Equal(t, encoded, reencoded.Bytes(), "decoded data must have the same encoding")
+
+	_, err = app.DecodeData(bytes.NewReader(encoded[:len(encoded)/2]))
+	if len(encoded) > 0 {
+		assert.Error(t, err, "decoding truncated data must fail")
+	}
+}
+
+// genericDataCloneTest checks that data clones have the same type and encoding
+// and do not share any references with the original, except in the given
+// shallow fields.
+func genericDataCloneTest(t *testing.T, data channel.Data, shallow []string) {
+	clone := data.Clone()
+	require.IsType(t, data, clone, "clone must have the same type")
+	eq, err := perunio.EqualEncoding(data, clone)
+	require.NoError(t, err)
+	assert.True(t, eq, "clone must have the same encoding")
+	skip := make(map[string]bool, len(shallow))
+	for _, f := range shallow {
+		skip[f] = true
+	}
+	assert.NoError(t, checkDeepCopy(reflect.ValueOf(data), reflect.ValueOf(clone), "", skip))
+}
+
+// genericTransitionTest checks that the generated transition is valid.
+func genericTransitionTest(t *testing.T, app channel.StateApp, params *channel.Params, from, to *channel.State, actor channel.Index) {
+	assertUnmodified(t, from, func() {
+		assertUnmodified(t, to, func() {
+			assert.NoError(t, app.ValidTransition(params, from, to, actor), "generated transition must be valid")
+		})
+	})
+}
+
+// genericMachineTest checks that the transition preserves the invariants of
+// the channel machine and that a channel.StateMachine accepts it, but rejects
+// it if the channel ID, an allocation sum or the number of assets is changed.
+func genericMachineTest(t *testing.T, rng *rand.Rand, params *channel.Params, from, to *channel.State, actor channel.Index) {
+	assert.Equal(t, from.ID, to.ID, "generated transition must not change the channel ID")
+	assert.Equal(t, len(from.Assets), len(to.Assets), "generated transition must not change the number of assets")
+	eq, err := pkgbig.EqualSum(from.Allocation, to.Allocation)
+	assert.NoError(t, err, "summing allocations")
+	assert.True(t, eq, "generated transition must preserve the allocation sums")
+
+	sm, err := channel.RestoreStateMachine(
+		&appTestAccount{params.Parts[0]},
+		&appTestSource{params: params, tx: channel.Transaction{State: from}})
+	require.NoError(t, err, "restoring state machine")
+	for _, m := range []struct {
+		name   string
+		mutate func(*channel.State)
+	}{
+		{"another channel ID", func(s *channel.State) { s.ID = NewRandomChannelID(rng) }},
+		{"a changed allocation sum", func(s *channel.State) {
+			s.Balances[0][0] = new(big.Int).Add(s.Balances[0][0], big.NewInt(1))
+		}},
+		{"an additional asset", func(s *channel.State) {
+			s.Assets = append(s.Assets, NewRandomAsset(rng))
+			bals := make([]channel.Bal, len(params.Parts))
+			for i := range bals {
+				bals[i] = new(big.Int)
+			}
+			s.Balances = append(s.Balances, bals)
+			for i := range s.Locked {
+				s.Locked[i].Bals = append(s.Locked[i].Bals, new(big.Int))
+			}
+		}},
+	} {
+		mutated := to.Clone()
+		m.mutate(mutated)
+		assert.Errorf(t, sm.Update(mutated, actor), "transition with %s must be rejected", m.name)
+	}
+	assert.NoError(t, sm.Update(to.Clone(), actor), "generated transition must pass the checks of the channel machine")
+}
+
+// genericInvalidTransitionTest checks that the generated invalid transition is
+// rejected with a StateTransitionError.
+func genericInvalidTransitionTest(t *testing.T, app channel.StateApp, params *channel.Params, from, to *channel.State, actor channel.Index) {
+	assertUnmodified(t, from, func() {
+		assertUnmodified(t, to, func() {
+			err := app.ValidTransition(params, from, to, actor)
+			assert.Truef(t, channel.IsStateTransitionError(err),
+				"generated invalid transition must be rejected with a StateTransitionError, got: %v", err)
+		})
+	})
+}
+
+type (
+	// appTestAccount is a participant account that cannot sign. It is used to
+	// restore a StateMachine that only checks transitions.
+	appTestAccount struct{ addr wallet.Address }
+
+	// appTestSource is the Source of a StateMachine in the Acting phase with
+	// the given current transaction.
+	appTestSource struct {
+		params *channel.Params
+		tx     channel.Transaction
+	}
+)
+
+func (a *appTestAccount) Address() wallet.Address { return a.addr }
+
+func (a *appTestAccount) SignData([]byte) ([]byte, error) {
+	return nil, errors.New("app test account cannot sign")
+}
+
+func (s *appTestSource) ID() channel.ID                 { return s.params.ID() }
+func (s *appTestSource) Idx() channel.Index             { return 0 }
+func (s *appTestSource) Params() *channel.Params        { return s.params }
+func (s *appTestSource) StagingTX() channel.Transaction { return channel.Transaction{} }
+func (s *appTestSource) CurrentTX() channel.Transaction { return s.tx }
+func (s *appTestSource) Phase() channel.Phase           { return channel.Acting }
+
+// assertUnmodified asserts that f does not modify the state.
+func assertUnmodified(t *testing.T, s *channel.State, f func()) {
+	var before, after bytes.Buffer
+	require.NoError(t, s.Encode(&before))
+	f()
+	require.NoError(t, s.Encode(&after))
+	assert.Equal(t, before.Bytes(), after.Bytes(), "state must not be modified")
+}
+
+// checkDeepCopy checks that the deeply equal values v and w do not share any
+// pointers or slice arrays. Struct fields whose path is in skip are skipped.
+func checkDeepCopy(v, w reflect.Value, path string, skip map[string]bool) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || w.IsNil() {
+			return nil
+		}
+		if v.Pointer() == w.Pointer() && v.Type().Elem().Size() != 0 {
+			return errors.Errorf("Data%s: shared pointer", dotted(path))
+		}
+		return checkDeepCopy(v.Elem(), w.Elem(), path, skip)
+	case reflect.Interface:
+		if v.IsNil() || w.IsNil() {
+			return nil
+		}
+		return checkDeepCopy(v.Elem(), w.Elem(), path, skip)
+	case reflect.Slice:
+		if v.Cap() > 0 && w.Cap() > 0 && v.Pointer() == w.Pointer() && v.Type().Elem().Size() != 0 {
+			return errors.Errorf("Data%s: shared slice", dotted(path))
+		}
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < v.Len() && i < w.Len(); i++ {
+			if err := checkDeepCopy(v.Index(i), w.Index(i), path+"[]", skip); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if !v.IsNil() && v.Pointer() == w.Pointer() {
+			return errors.Errorf("Data%s: shared map", dotted(path))
+		}
+		for _, key := range v.MapKeys() {
+			if err := checkDeepCopy(v.MapIndex(key), w.MapIndex(key), path+"[]", skip); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			fpath := v.Type().Field(i).Name
+			if path != "" {
+				fpath = path + "." + fpath
+			}
+			if skip[fpath] {
+				continue
+			}
+			if err := checkDeepCopy(v.Field(i), w.Field(i), fpath, skip); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dotted returns the path prefixed with a dot if it is not empty.
+func dotted(path string) string {
+	if path == "" {
+		return ""
+	}
+	return "." + path
+}
+
+func orDefault(n, def int) int {
+	if n <= 0 {
+		return def
+	}
+	return n
+}
@@ -40,6 +40,20 @@ func (t Transaction) Clone() Transaction {
 	}
 }
 
+// IsFullySigned returns whether the transaction has a state and the signatures
+// of all participants. The signatures are not verified.
+func (t Transaction) IsFullySigned() bool {
+	if t.State == nil || len(t.Sigs) == 0 {
+		return false
+	}
+	for _, sig := range t.Sigs {
+		if sig == nil {
+			return false
+		}
+	}
+	return true
+}
+
 // Encode encodes a transaction into an `io.Writer` or returns an `error`.
 func (t Transaction) Encode(w io.Writer) error {
 	// Encode stateSet == 0
@@ -17,6 +17,8 @@ package channel_test
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
 	"perun.network/go-perun/channel"
 	"perun.network/go-perun/channel/test"
 	iotest "perun.network/go-perun/pkg/io/test"
@@ -46,6 +48,15 @@ func TestTransactionSerialization(t *testing.T) {
 	iotest.GenericSerializerTest(t, tx)
 }
 
+func TestTransaction_IsFullySigned(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	assert.False(t, channel.Transaction{}.IsFullySigned())
+	assert.True(t, test.NewRandomTransaction(rng, newUniformBoolSlice(3, true)).IsFullySigned())
+	for i := 0; i < 3; i++ {
+		assert.False(t, test.NewRandomTransaction(rng, newAlmostUniformBoolSlice(i, 3, false)).IsFullySigned())
+	}
+}
+
 // newUniformBoolSlice generates a slice long size with all the elements set to choice.
 func newUniformBoolSlice(size int, choice bool) []bool {
 	uniform := make([]bool, size)
@@ -41,10 +41,13 @@ type Channel struct {
 	onUpdate    func(from, to *channel.State)
 	adjudicator channel.Adjudicator
 	wallet      wallet.Wallet
+	pr          persistence.Persister
+	receiver    wallet.Address // withdrawal receiver, adjudicator default if nil
 
 	parent                *Channel            // must be nil for ledger channel
 	subChannelFundings    *updateInterceptors // awaited subchannel funding updates
 	subChannelWithdrawals *updateInterceptors // awaited subchannel settlement updates
+	invoicePayments       *updateInterceptors // awaited invoice payment updates, by invoice ID
 }
 
 // newChannel is internally used by the Client to create a new channel
@@ -98,11 +101,42 @@ func (c *Client) channelFromMachine(machine *channel.StateMachine, parent *Chann
 		machine:               pmachine,
 		adjudicator:           c.adjudicator,
 		wallet:                c.wallet,
+		pr:                    c.pr,
 		subChannelFundings:    newUpdateInterceptors(),
 		subChannelWithdrawals: newUpdateInterceptors(),
+		invoicePayments:       newUpdateInterceptors(),
 	}, nil
 }
 
+// WithdrawalReceiver returns the address that the channel's funds are
+// withdrawn to or nil if the adjudicator's default receiver is used.
+func (c *Channel) WithdrawalReceiver() wallet.Address {
+	c.machMtx.Lock()
+	defer c.machMtx.Unlock()
+	return c.receiver
+}
+
+// SetWithdrawalReceiver persists and sets the address that the channel's funds
+// are withdrawn to. If receiver is nil, the adjudicator's default receiver is
+// used. The receiver is used when the channel is settled and when its funding
+// is recovered. Sub-channels cannot have a receiver because they are withdrawn
+// into their parent channel.
+func (c *Channel) SetWithdrawalReceiver(ctx context.Context, receiver wallet.Address) error {
+	if !c.IsLedgerChannel() {
+		return errors.New("only ledger channels have a withdrawal receiver")
+	}
+	if !c.machMtx.TryLockCtx(ctx) {
+		return errors.WithMessage(ctx.Err(), "locking machine")
+	}
+	defer c.machMtx.Unlock()
+
+	if err := c.pr.ReceiverChanged(ctx, c.ID(), receiver); err != nil {
+		return errors.WithMessage(err, "persisting receiver")
+	}
+	c.receiver = receiver
+	return nil
+}
+
 // Close closes the channel and all associated peer subscriptions.
 func (c *Channel) Close() error {
 	return c.conn.Close()
@@ -58,12 +58,14 @@ func newChannelConn(id channel.ID, peers []wire.Address, idx channel.Index, sub
 		}
 	}()
 
-	isUpdateRes := func(e *wire.Envelope) bool {
-		ok := e.Msg.Type() == wire.ChannelUpdateAcc || e.Msg.Type() == wire.ChannelUpdateRej
+	isChannelRes := func(e *wire.Envelope) bool {
+		ok := e.Msg.Type() == wire.ChannelUpdateAcc ||
+			e.Msg.Type() == wire.ChannelUpdateRej ||
+			e.Msg.Type() == wire.PaymentRequestRej
 		return ok && e.Msg.(ChannelMsg).ID() == id
 	}
 
-	if err = sub.Subscribe(relay, isUpdateRes); err != nil {
+	if err = sub.Subscribe(relay, isChannelRes); err != nil {
 		return nil, errors.WithMessagef(err, "subscribing relay")
 	}
 
@@ -135,6 +137,25 @@ func (c *channelConn) NewUpdateResRecv(version uint64) (*channelMsgRecv, error)
 	}, nil
 }
 
+// NewPaymentRequestRejRecv creates a new receiver for rejections of the invoice
+// with the given ID. The receiver should be closed after the invoice is paid or
+// rejected. The receiver is also closed when the channel connection is closed.
+func (c *channelConn) NewPaymentRequestRejRecv(id InvoiceID) (*channelMsgRecv, error) {
+	recv := wire.NewReceiver()
+	if err := c.r.Subscribe(recv, func(e *wire.Envelope) bool {
+		rej, ok := e.Msg.(*msgPaymentRequestRej)
+		return ok && rej.InvoiceID == id
+	}); err != nil {
+		return nil, errors.WithMessagef(err, "subscribing payment request rejection receiver")
+	}
+
+	return &channelMsgRecv{
+		Receiver: recv,
+		peers:    c.peers,
+		log:      c.log.WithField("invoice", id),
+	}, nil
+}
+
 type (
 	// A channelMsgRecv is a receiver of channel messages. Messages are received
 	// with Next(), which returns the peer's channel index and the message.
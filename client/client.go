@@ -41,8 +41,12 @@ type Client struct {
 	adjudicator channel.Adjudicator
 	wallet      wallet.Wallet
 	pr          persistence.PersistRestorer
+	appRegistry *channel.AppRegistry
 	log         log.Logger // structured logger for this client
 
+	invoiceHandler InvoiceHandler
+	recoverFunding bool // whether to recover funding after funding timeouts
+
 	sync.Closer
 }
 
@@ -61,6 +65,9 @@ type Client struct {
 // The wallet is used to resolve addresses to accounts when creating or
 // restoring channels.
 //
+// Options like WithAppRegistry or WithFundingRecovery can be passed to further
+// configure the client.
+//
 // If any argument is nil, New panics.
 func New(
 	address wire.Address,
@@ -68,6 +75,7 @@ func New(
 	funder channel.Funder,
 	adjudicator channel.Adjudicator,
 	wallet wallet.Wallet,
+	opts ...Option,
 ) (c *Client, err error) {
 	if address == nil {
 		log.Panic("address must not be nil")
@@ -89,7 +97,7 @@ func New(
 		return nil, errors.WithMessage(err, "setting up client connection")
 	}
 
-	return &Client{
+	c = &Client{
 		address:     address,
 		conn:        conn,
 		channels:    makeChanRegistry(),
@@ -97,8 +105,37 @@ func New(
 		adjudicator: adjudicator,
 		wallet:      wallet,
 		pr:          persistence.NonPersistRestorer,
+		appRegistry: channel.GlobalAppRegistry(),
 		log:         log,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// An Option configures a Client on creation, see New.
+type Option func(*Client)
+
+// WithAppRegistry makes the client resolve the apps of channel proposals with
+// the given registry instead of the global one. Only proposals for apps that
+// are known to the registry are accepted or sent. Channels are restored with
+// the registry if the PersistRestorer is a persistence.AppRegistryScoper.
+//
+// Messages received over a networked bus are decoded before they reach the
+// client. To resolve the apps in them with the registry too, the connections
+// of the bus have to decode in the scope of the registry, e.g., by passing
+// simple.WithReaderScope(reg.Scope) to the dialer and listener of the bus.
+func WithAppRegistry(reg *channel.AppRegistry) Option {
+	if reg == nil {
+		log.Panic("nil AppRegistry")
+	}
+	return func(c *Client) { c.appRegistry = reg }
+}
+
+// AppRegistry returns the app registry that is used by this client.
+func (c *Client) AppRegistry() *channel.AppRegistry {
+	return c.appRegistry
 }
 
 // Close closes this state channel client.
@@ -129,6 +166,9 @@ func (c *Client) OnNewChannel(handler func(*Channel)) {
 //
 // The PersistRestorer is not closed when the Client is closed.
 func (c *Client) EnablePersistence(pr persistence.PersistRestorer) {
+	if s, ok := pr.(persistence.AppRegistryScoper); ok && c.appRegistry != channel.GlobalAppRegistry() {
+		pr = s.WithAppRegistry(c.appRegistry)
+	}
 	c.pr = pr
 }
 
@@ -140,8 +180,8 @@ func (c *Client) Channel(id channel.ID) (*Channel, error) {
 	return nil, errors.New("unknown channel ID")
 }
 
-// Handle is the incoming request handler routine. It handles channel proposals
-// and channel update requests. It must be started exactly once by the user,
+// Handle is the incoming request handler routine. It handles channel proposals,
+// channel update requests and payment requests, see SetInvoiceHandler. It must be started exactly once by the user,
 // during the setup of the Client. Incoming requests are handled by the passed
 // respecive handlers.
 func (c *Client) Handle(ph ProposalHandler, uh UpdateHandler) {
@@ -164,8 +204,12 @@ func (c *Client) Handle(ph ProposalHandler, uh UpdateHandler) {
 			go c.handleChannelProposal(ph, env.Sender, msg.(*SubChannelProposal))
 		case wire.ChannelUpdate:
 			go c.handleChannelUpdate(uh, env.Sender, msg.(*msgChannelUpdate))
+		case wire.InvoicePayment:
+			go c.handleChannelUpdate(uh, env.Sender, &msg.(*msgInvoicePayment).msgChannelUpdate)
 		case wire.ChannelSync:
 			go c.handleSyncMsg(env.Sender, msg.(*msgChannelSync))
+		case wire.PaymentRequest:
+			go c.handlePaymentRequest(env.Sender, msg.(*msgPaymentRequest))
 		default:
 			c.log.Error("Unexpected %T message received in request loop")
 		}
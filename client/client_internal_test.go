@@ -19,6 +19,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
 	channeltest "perun.network/go-perun/channel/test"
 	"perun.network/go-perun/pkg/test"
 	wallettest "perun.network/go-perun/wallet/test"
@@ -49,3 +51,29 @@ func TestClient_Channel(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+// scopedPersistRestorer is a persistence.AppRegistryScoper that records the
+// registry it was scoped with.
+type scopedPersistRestorer struct {
+	persistence.PersistRestorer
+	reg *channel.AppRegistry
+}
+
+func (pr *scopedPersistRestorer) WithAppRegistry(reg *channel.AppRegistry) persistence.PersistRestorer {
+	return &scopedPersistRestorer{pr.PersistRestorer, reg}
+}
+
+func TestClient_EnablePersistence(t *testing.T) {
+	pr := &scopedPersistRestorer{PersistRestorer: persistence.NonPersistRestorer}
+
+	c := &Client{appRegistry: channel.GlobalAppRegistry()}
+	c.EnablePersistence(pr)
+	assert.Same(t, pr, c.pr, "global registry should keep the PersistRestorer")
+
+	reg := channel.NewAppRegistry("scoped")
+	c = &Client{appRegistry: reg}
+	c.EnablePersistence(pr)
+	assert.Same(t, reg, c.pr.(*scopedPersistRestorer).reg, "PersistRestorer should use the client's registry")
+	c.EnablePersistence(persistence.NonPersistRestorer)
+	assert.Equal(t, persistence.NonPersistRestorer, c.pr)
+}
@@ -64,7 +64,9 @@ func isReqMsg(m *wire.Envelope) bool {
 	return m.Msg.Type() == wire.LedgerChannelProposal ||
 		m.Msg.Type() == wire.SubChannelProposal ||
 		m.Msg.Type() == wire.ChannelUpdate ||
-		m.Msg.Type() == wire.ChannelSync
+		m.Msg.Type() == wire.InvoicePayment ||
+		m.Msg.Type() == wire.ChannelSync ||
+		m.Msg.Type() == wire.PaymentRequest
 }
 
 func (c clientConn) nextReq(ctx context.Context) (*wire.Envelope, error) {
@@ -0,0 +1,124 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+)
+
+// WithFundingRecovery makes the client call Channel.RecoverFunding on ledger
+// channels whose funding timed out, before returning the FundingTimeoutError
+// from proposing or accepting the channel. The context passed to
+// ProposeChannel or Accept must then not expire before the recovery is done,
+// which takes at least the ChallengeDuration.
+func WithFundingRecovery() Option {
+	return func(c *Client) { c.recoverFunding = true }
+}
+
+// RecoverFunding recovers the deposits of a ledger channel whose funding timed
+// out, see channel.FundingTimeoutError. It registers the initial state,
+// waits for the dispute timeout, concludes the channel and withdraws the
+// deposits of this participant.
+//
+// As long as not all participants deposited, the asset holders do not
+// redistribute the deposits when the channel is concluded. Hence, every
+// participant that did deposit gets their deposits back by calling
+// RecoverFunding.
+//
+// If RecoverFunding fails, e.g., because the context expired, it can be called
+// again.
+func (c *Channel) RecoverFunding(ctx context.Context) error {
+	if !c.IsLedgerChannel() {
+		return errors.New("can only recover funding of ledger channels")
+	}
+	if !c.machMtx.TryLockCtx(ctx) {
+		return errors.WithMessage(ctx.Err(), "locking machine")
+	}
+	defer c.machMtx.Unlock()
+
+	switch c.machine.Phase() {
+	case channel.Funding, channel.Registering, channel.Registered, channel.Withdrawing:
+	default:
+		return errors.Errorf("cannot recover funding in phase %v", c.machine.Phase())
+	}
+	if v := c.machine.State().Version; v != 0 {
+		return errors.Errorf("cannot recover funding of state version %d", v)
+	}
+
+	sub, err := c.adjudicator.Subscribe(ctx, c.Params())
+	if err != nil {
+		return errors.WithMessage(err, "subscribing to adjudicator events")
+	}
+	// nolint:errcheck
+	defer sub.Close()
+
+	if p := c.machine.Phase(); p == channel.Funding || p == channel.Registering {
+		if err := c.register(ctx); err != nil {
+			return errors.WithMessage(err, "registering initial state")
+		}
+	}
+	if err := waitConcludable(ctx, sub); err != nil {
+		return err
+	}
+
+	if err := c.machine.SetWithdrawing(ctx); err != nil {
+		return errors.WithMessage(err, "setting machine to withdrawing phase")
+	}
+	req := c.machine.AdjudicatorReq()
+	req.Receiver = c.receiver
+	if err := c.adjudicator.Withdraw(ctx, req, nil); err != nil {
+		return errors.WithMessage(err, "calling Withdraw")
+	}
+	if err := c.machine.SetWithdrawn(ctx); err != nil {
+		return errors.WithMessage(err, "setting machine phase")
+	}
+
+	c.Log().Info("Funding recovered.")
+	return nil
+}
+
+// waitConcludable waits until the registered state of a channel can be
+// concluded on the adjudicator, i.e., until the timeout of the Registered
+// event elapsed or the channel is already concluded.
+func waitConcludable(ctx context.Context, sub channel.AdjudicatorSubscription) error {
+	for e := sub.Next(); e != nil; e = sub.Next() {
+		switch e := e.(type) {
+		case *channel.RegisteredEvent:
+			return errors.WithMessage(e.Timeout().Wait(ctx), "waiting for timeout")
+		case *channel.ConcludedEvent:
+			return nil
+		}
+	}
+	if err := sub.Err(); err != nil {
+		return errors.WithMessage(err, "subscription closed")
+	}
+	return errors.WithMessage(ctx.Err(), "subscription closed")
+}
+
+// recoverFundingAfter recovers the funding of the channel after the given
+// funding timeout error occurred. The returned error always has the funding
+// timeout error as its cause.
+func (c *Client) recoverFundingAfter(ctx context.Context, ch *Channel, timeoutErr error) error {
+	ch.Log().Warn("Funding timed out, recovering funding.")
+	if err := ch.RecoverFunding(ctx); err != nil {
+		ch.Log().Errorf("Recovering funding: %v", err)
+		return errors.WithMessagef(timeoutErr, "waiting for peer funding (recovering funding failed: %v)", err)
+	}
+	return errors.WithMessage(timeoutErr, "waiting for peer funding (funding recovered)")
+}
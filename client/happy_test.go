@@ -50,6 +50,7 @@ func TestHappyAliceBob(t *testing.T) {
 				app,
 			),
 			NumPayments: [2]int{2, 2},
+			NumRequests: [2]int{1, 1},
 			TxAmounts:   [2]*big.Int{big.NewInt(5), big.NewInt(3)},
 		}
 
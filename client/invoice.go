@@ -0,0 +1,309 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/rand"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/log"
+	"perun.network/go-perun/pkg/sync/atomic"
+	"perun.network/go-perun/wire"
+)
+
+// InvoiceID uniquely identifies an invoice within a channel.
+type InvoiceID = [32]byte
+
+type (
+	// An Invoice is a request of a channel participant to be paid an amount by
+	// the other channel participant.
+	Invoice struct {
+		// ChannelID is the ID of the channel in which the payment is requested.
+		ChannelID channel.ID
+		// ID is the random invoice ID chosen by the requester.
+		ID InvoiceID
+		// Amount is the requested amount per asset of the channel.
+		Amount []channel.Bal
+		// Memo is a free-form description of the invoice.
+		Memo string
+	}
+
+	// An InvoiceHandler decides how to handle incoming invoices from other
+	// channel participants.
+	InvoiceHandler interface {
+		// HandleInvoice is the user callback called by the channel controller on
+		// an incoming payment request.
+		HandleInvoice(Invoice, *InvoiceResponder)
+	}
+
+	// InvoiceHandlerFunc is an adapter type to allow the use of functions as
+	// invoice handlers. InvoiceHandlerFunc(f) is an InvoiceHandler that calls
+	// f when HandleInvoice is called.
+	InvoiceHandlerFunc func(Invoice, *InvoiceResponder)
+
+	// The InvoiceResponder allows the user to react to an incoming invoice. If
+	// the user wants to pay the invoice, Accept() should be called, otherwise
+	// Reject(), possibly giving a reason for the rejection.
+	// Only a single function must be called and every further call causes a
+	// panic.
+	InvoiceResponder struct {
+		channel *Channel
+		invoice Invoice
+		called  atomic.Bool
+	}
+)
+
+// HandleInvoice calls the invoice handler function.
+func (f InvoiceHandlerFunc) HandleInvoice(i Invoice, r *InvoiceResponder) { f(i, r) }
+
+// Accept lets the user signal that they want to pay the invoice. The requested
+// amount is transferred to the requester with a channel update that references
+// the invoice.
+//
+// Returns nil if the requester accepts the channel update.
+func (r *InvoiceResponder) Accept(ctx context.Context) error {
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	if !r.called.TrySet() {
+		log.Panic("multiple calls on invoice responder")
+	}
+
+	return r.channel.payInvoice(ctx, r.invoice)
+}
+
+// Reject lets the user signal that they reject the invoice.
+func (r *InvoiceResponder) Reject(ctx context.Context, reason string) error {
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	if !r.called.TrySet() {
+		log.Panic("multiple calls on invoice responder")
+	}
+
+	return r.channel.rejectInvoice(ctx, r.invoice, reason)
+}
+
+// SetInvoiceHandler sets the handler for incoming invoices. If no handler is
+// set, all incoming invoices are rejected. This method is expected to be called
+// once during the setup of the client, before Handle is started, and is hence
+// not thread-safe.
+func (c *Client) SetInvoiceHandler(ih InvoiceHandler) {
+	c.invoiceHandler = ih
+}
+
+// handlePaymentRequest forwards incoming invoices to the invoice handler. If
+// the channel is unknown or the invoice invalid, an error is logged.
+//
+// This handler is dispatched from the Client.Handle routine.
+func (c *Client) handlePaymentRequest(p wire.Address, m *msgPaymentRequest) {
+	ch, ok := c.channels.Get(m.ID())
+	if !ok {
+		c.logChan(m.ID()).WithField("peer", p).Error("received payment request for unknown channel")
+		return
+	}
+	payee := ch.Idx() ^ 1
+	if !ch.Peers()[payee].Equals(p) {
+		ch.logPeer(payee).Errorf("received payment request from non-participant %v", p)
+		return
+	}
+
+	responder := &InvoiceResponder{channel: ch, invoice: m.Invoice}
+	var reason string
+	ch.machMtx.Lock()
+	err := ch.validInvoice(m.Invoice, ch.Idx(), payee)
+	ch.machMtx.Unlock()
+	if err != nil {
+		reason = err.Error()
+	} else if c.invoiceHandler == nil {
+		reason = "no invoice handler"
+	} else {
+		c.invoiceHandler.HandleInvoice(m.Invoice, responder)
+		return
+	}
+
+	ch.logPeer(payee).Warnf("rejecting invoice: %s", reason)
+	if err := responder.Reject(ch.Ctx(), reason); err != nil {
+		ch.logPeer(payee).Errorf("error rejecting invoice: %v", err)
+	}
+}
+
+// RequestPayment requests the other channel participant to pay the given
+// amount per asset to us. The payer's InvoiceHandler is called with the
+// invoice. If the payer pays the invoice, the corresponding channel update is
+// accepted automatically.
+//
+// Returns nil if the invoice was paid. If the payer rejects the invoice or any
+// runtime error occurs, an error is returned.
+func (c *Channel) RequestPayment(ctx context.Context, amount []channel.Bal, memo string) error {
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+
+	inv := Invoice{ChannelID: c.ID(), Amount: amount, Memo: memo}
+	if _, err := rand.Read(inv.ID[:]); err != nil {
+		return errors.Wrap(err, "generating invoice ID")
+	}
+	payer := c.Idx() ^ 1
+	if !c.machMtx.TryLockCtx(ctx) {
+		return errors.Errorf("locking machine mutex in time: %v", ctx.Err())
+	}
+	err := c.validInvoice(inv, payer, c.Idx())
+	c.machMtx.Unlock()
+	if err != nil {
+		return err
+	}
+
+	rejRecv, err := c.conn.NewPaymentRequestRejRecv(inv.ID)
+	if err != nil {
+		return errors.WithMessage(err, "creating payment request rejection receiver")
+	}
+	// nolint:errcheck
+	defer rejRecv.Close()
+
+	c.registerInvoicePayment(inv, payer)
+	defer c.invoicePayments.Release(inv.ID)
+
+	if err := c.conn.Send(ctx, &msgPaymentRequest{Invoice: inv}); err != nil {
+		return errors.WithMessage(err, "sending payment request")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	rejected := make(chan *msgPaymentRequestRej, 1)
+	go func() {
+		if _, rej, err := rejRecv.Next(ctx); err == nil {
+			rejected <- rej.(*msgPaymentRequestRej) // safe by predicate of the receiver
+			cancel()
+		}
+	}()
+
+	ui, _ := c.invoicePayments.Get(inv.ID)
+	err = ui.Accept(ctx)
+	select {
+	case rej := <-rejected:
+		return errors.Errorf("invoice rejected: %s", rej.Reason)
+	default:
+		return errors.WithMessage(err, "accepting invoice payment")
+	}
+}
+
+// registerInvoicePayment registers an update interceptor for the channel
+// update that pays the given invoice. Updates that reference the invoice but
+// change more than the payment are left to the UpdateHandler.
+func (c *Channel) registerInvoicePayment(inv Invoice, payer channel.Index) {
+	filter := func(cu ChannelUpdate) bool {
+		if cu.InvoiceID != inv.ID {
+			return false
+		}
+		if err := checkInvoicePayment(c.machine.State(), cu, inv, payer, c.Idx()); err != nil {
+			c.logPeer(payer).Warnf("update does not pay invoice: %v", err)
+			return false
+		}
+		return true
+	}
+	c.invoicePayments.Register(inv.ID, newUpdateInterceptor(filter))
+}
+
+// payInvoice transfers the invoice's amount to the requester with a channel
+// update that references the invoice.
+func (c *Channel) payInvoice(ctx context.Context, inv Invoice) error {
+	// Lock machine while update is in progress.
+	if !c.machMtx.TryLockCtx(ctx) {
+		return errors.Errorf("locking machine mutex in time: %v", ctx.Err())
+	}
+	defer c.machMtx.Unlock()
+
+	payee := c.Idx() ^ 1
+	if err := c.validInvoice(inv, c.Idx(), payee); err != nil {
+		return err
+	}
+
+	state := c.machine.State().Clone()
+	transferInvoiceAmount(state.Balances, inv.Amount, c.Idx(), payee)
+	state.Version++
+
+	up := makeChannelUpdate(state, c.machine.Idx())
+	up.InvoiceID = inv.ID
+	if err := c.validTwoPartyUpdate(up, c.machine.Idx()); err != nil {
+		return err
+	}
+	return c.proposeUpdate(ctx, up)
+}
+
+func (c *Channel) rejectInvoice(ctx context.Context, inv Invoice, reason string) error {
+	msgRej := &msgPaymentRequestRej{
+		ChannelID: c.ID(),
+		InvoiceID: inv.ID,
+		Reason:    reason,
+	}
+	return errors.WithMessage(c.conn.Send(ctx, msgRej), "sending payment request rejection")
+}
+
+// validInvoice checks that the invoice matches the channel's assets and that
+// the payer can cover the requested amount.
+func (c *Channel) validInvoice(inv Invoice, payer, payee channel.Index) error {
+	return checkInvoice(c.machine.State().Balances, inv, payer, payee)
+}
+
+// checkInvoice checks that the invoice matches the assets of the balances and
+// that the payer can cover the requested amount.
+func checkInvoice(bals channel.Balances, inv Invoice, payer, payee channel.Index) error {
+	if len(inv.Amount) != len(bals) {
+		return errors.Errorf("invoice has %d amounts, channel has %d assets", len(inv.Amount), len(bals))
+	}
+	for a, amount := range inv.Amount {
+		if amount == nil || amount.Sign() < 0 {
+			return errors.Errorf("invalid amount for asset %d", a)
+		}
+		if bals[a][payer].Cmp(amount) < 0 {
+			return errors.Errorf("insufficient balance of asset %d", a)
+		}
+	}
+	if payer == payee {
+		return errors.New("payer and payee must differ")
+	}
+	return nil
+}
+
+// checkInvoicePayment checks that the update is proposed by the payer and that
+// it only transfers the invoice's amount from the payer to the payee, i.e., the
+// proposed state equals the next version of the current state with only this
+// transfer applied and IsFinal unset.
+func checkInvoicePayment(current *channel.State, cu ChannelUpdate, inv Invoice, payer, payee channel.Index) error {
+	if cu.ActorIdx != payer {
+		return errors.Errorf("proposed by participant %d, not the payer", cu.ActorIdx)
+	}
+	if err := checkInvoice(current.Balances, inv, payer, payee); err != nil {
+		return err
+	}
+	expected := current.Clone()
+	expected.Version++
+	expected.IsFinal = false
+	transferInvoiceAmount(expected.Balances, inv.Amount, payer, payee)
+	return errors.WithMessage(expected.Equal(cu.State), "unexpected state")
+}
+
+// transferInvoiceAmount transfers the amount of every asset from the payer to
+// the payee in the given balances.
+func transferInvoiceAmount(bals channel.Balances, amount []channel.Bal, payer, payee channel.Index) {
+	for a, x := range amount {
+		bals[a][payer].Sub(bals[a][payer], x)
+		bals[a][payee].Add(bals[a][payee], x)
+	}
+}
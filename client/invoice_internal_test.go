@@ -0,0 +1,104 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"perun.network/go-perun/channel"
+	channeltest "perun.network/go-perun/channel/test"
+	"perun.network/go-perun/pkg/test"
+)
+
+func TestCheckInvoicePayment(t *testing.T) {
+	rng := test.Prng(t)
+	const payer, payee = 0, 1
+	current := channeltest.NewRandomState(rng,
+		channeltest.WithNumParts(2),
+		channeltest.WithNumLocked(0),
+		channeltest.WithIsFinal(false))
+	inv := Invoice{ChannelID: current.ID, Amount: make([]channel.Bal, len(current.Balances))}
+	for a, bals := range current.Balances {
+		inv.Amount[a] = new(big.Int).Rsh(bals[payer], 1)
+	}
+	rng.Read(inv.ID[:])
+
+	// payment returns the update that pays inv and applies mutate to it.
+	payment := func(mutate func(*channel.State)) ChannelUpdate {
+		next := current.Clone()
+		next.Version++
+		transferInvoiceAmount(next.Balances, inv.Amount, payer, payee)
+		mutate(next)
+		up := makeChannelUpdate(next, payer)
+		up.InvoiceID = inv.ID
+		return up
+	}
+	assert.NoError(t, checkInvoicePayment(current, payment(func(*channel.State) {}), inv, payer, payee))
+
+	t.Run("tampered update", func(t *testing.T) {
+		for name, mutate := range map[string]func(*channel.State){
+			"final": func(s *channel.State) { s.IsFinal = true },
+			"data":  func(s *channel.State) { s.Data = channeltest.NewRandomData(rng) },
+			"locked": func(s *channel.State) {
+				s.Locked = []channel.SubAlloc{*channeltest.NewRandomSubAlloc(rng, channeltest.WithNumAssets(len(s.Assets)))}
+			},
+			"version": func(s *channel.State) { s.Version++ },
+			"balances": func(s *channel.State) {
+				s.Balances[0][payee].Add(s.Balances[0][payee], big.NewInt(1))
+				s.Balances[0][payer].Sub(s.Balances[0][payer], big.NewInt(1))
+			},
+			"id": func(s *channel.State) { s.ID = channeltest.NewRandomChannelID(rng) },
+		} {
+			assert.Errorf(t, checkInvoicePayment(current, payment(mutate), inv, payer, payee), "tampered %s", name)
+		}
+
+		up := payment(func(*channel.State) {})
+		up.ActorIdx = payee
+		assert.Error(t, checkInvoicePayment(current, up, inv, payer, payee), "proposed by payee")
+	})
+
+	t.Run("invalid invoice", func(t *testing.T) {
+		invalid := func(mutate func(*Invoice)) Invoice {
+			i := inv
+			i.Amount = make([]channel.Bal, len(inv.Amount))
+			for a, amount := range inv.Amount {
+				i.Amount[a] = new(big.Int).Set(amount)
+			}
+			mutate(&i)
+			return i
+		}
+		for name, inv := range map[string]Invoice{
+			"negative":     invalid(func(i *Invoice) { i.Amount[0].SetInt64(-1) }),
+			"insufficient": invalid(func(i *Invoice) { i.Amount[0].Add(current.Balances[0][payer], big.NewInt(1)) }),
+			"assets":       invalid(func(i *Invoice) { i.Amount = append(i.Amount, big.NewInt(0)) }),
+		} {
+			// The update matches the transfer of the invalid invoice.
+			next := current.Clone()
+			next.Version++
+			for a := range next.Balances {
+				if a < len(inv.Amount) {
+					next.Balances[a][payer].Sub(next.Balances[a][payer], inv.Amount[a])
+					next.Balances[a][payee].Add(next.Balances[a][payee], inv.Amount[a])
+				}
+			}
+			up := makeChannelUpdate(next, payer)
+			up.InvoiceID = inv.ID
+			assert.Errorf(t, checkInvoicePayment(current, up, inv, payer, payee), "%s invoice", name)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	perunio "perun.network/go-perun/pkg/io"
+	"perun.network/go-perun/wire"
+)
+
+func init() {
+	wire.RegisterDecoder(wire.PaymentRequest,
+		func(r io.Reader) (wire.Msg, error) {
+			var m msgPaymentRequest
+			return &m, m.Decode(r)
+		})
+	wire.RegisterDecoder(wire.PaymentRequestRej,
+		func(r io.Reader) (wire.Msg, error) {
+			var m msgPaymentRequestRej
+			return &m, m.Decode(r)
+		})
+	wire.RegisterDecoder(wire.InvoicePayment,
+		func(r io.Reader) (wire.Msg, error) {
+			var m msgInvoicePayment
+			return &m, m.Decode(r)
+		})
+}
+
+type (
+	// msgPaymentRequest is the wire message of an invoice. It requests the
+	// receiver to pay the invoice's amount to the sender.
+	msgPaymentRequest struct {
+		Invoice Invoice
+	}
+
+	// msgPaymentRequestRej is the wire message sent as a negative reply to a
+	// PaymentRequest. It references the channel ID and invoice ID and states a
+	// reason for the rejection.
+	msgPaymentRequestRej struct {
+		// ChannelID is the channel ID.
+		ChannelID channel.ID
+		// InvoiceID is the ID of the rejected invoice.
+		InvoiceID InvoiceID
+		// Reason states why the sender rejects the invoice.
+		Reason string
+	}
+
+	// msgInvoicePayment is the wire message of a channel update proposal that
+	// pays an invoice. It is a msgChannelUpdate that additionally encodes the
+	// InvoiceID, so that the format of other updates is unchanged.
+	msgInvoicePayment struct {
+		msgChannelUpdate
+	}
+)
+
+var (
+	_ ChannelMsg = (*msgPaymentRequest)(nil)
+	_ ChannelMsg = (*msgPaymentRequestRej)(nil)
+	_ ChannelMsg = (*msgInvoicePayment)(nil)
+)
+
+// Type returns this message's type: PaymentRequest.
+func (*msgPaymentRequest) Type() wire.Type {
+	return wire.PaymentRequest
+}
+
+// Type returns this message's type: PaymentRequestRej.
+func (*msgPaymentRequestRej) Type() wire.Type {
+	return wire.PaymentRequestRej
+}
+
+// Type returns this message's type: InvoicePayment.
+func (*msgInvoicePayment) Type() wire.Type {
+	return wire.InvoicePayment
+}
+
+func (m msgPaymentRequest) Encode(w io.Writer) error {
+	if len(m.Invoice.Amount) > channel.MaxNumAssets {
+		return errors.Errorf("expected maximum number of assets %d, got %d", channel.MaxNumAssets, len(m.Invoice.Amount))
+	}
+	if err := perunio.Encode(w, m.Invoice.ChannelID, m.Invoice.ID, m.Invoice.Memo, channel.Index(len(m.Invoice.Amount))); err != nil {
+		return err
+	}
+	for i, amount := range m.Invoice.Amount {
+		if err := perunio.Encode(w, amount); err != nil {
+			return errors.WithMessagef(err, "encoding amount of asset %d", i)
+		}
+	}
+	return nil
+}
+
+func (m *msgPaymentRequest) Decode(r io.Reader) error {
+	var numAssets channel.Index
+	if err := perunio.Decode(r, &m.Invoice.ChannelID, &m.Invoice.ID, &m.Invoice.Memo, &numAssets); err != nil {
+		return err
+	}
+	if numAssets > channel.MaxNumAssets {
+		return errors.Errorf("expected maximum number of assets %d, got %d", channel.MaxNumAssets, numAssets)
+	}
+	m.Invoice.Amount = make([]channel.Bal, numAssets)
+	for i := range m.Invoice.Amount {
+		if err := perunio.Decode(r, &m.Invoice.Amount[i]); err != nil {
+			return errors.WithMessagef(err, "decoding amount of asset %d", i)
+		}
+	}
+	return nil
+}
+
+func (m msgPaymentRequestRej) Encode(w io.Writer) error {
+	return perunio.Encode(w, m.ChannelID, m.InvoiceID, m.Reason)
+}
+
+func (m *msgPaymentRequestRej) Decode(r io.Reader) error {
+	return perunio.Decode(r, &m.ChannelID, &m.InvoiceID, &m.Reason)
+}
+
+func (m msgInvoicePayment) Encode(w io.Writer) error {
+	if err := m.msgChannelUpdate.Encode(w); err != nil {
+		return err
+	}
+	return perunio.Encode(w, m.InvoiceID)
+}
+
+func (m *msgInvoicePayment) Decode(r io.Reader) error {
+	if err := m.msgChannelUpdate.Decode(r); err != nil {
+		return err
+	}
+	return perunio.Decode(r, &m.InvoiceID)
+}
+
+// ID returns the id of the channel this payment request refers to.
+func (m *msgPaymentRequest) ID() channel.ID {
+	return m.Invoice.ChannelID
+}
+
+// ID returns the id of the channel this payment request rejection refers to.
+func (m *msgPaymentRequestRej) ID() channel.ID {
+	return m.ChannelID
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+	"perun.network/go-perun/wire"
+)
+
+func TestPaymentRequestSerialization(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	for i := 0; i < 4; i++ {
+		m := &msgPaymentRequest{Invoice: Invoice{
+			ChannelID: test.NewRandomChannelID(rng),
+			Amount:    make([]channel.Bal, 1+rng.Intn(5)),
+			Memo:      newRandomString(rng, 0, 16),
+		}}
+		rng.Read(m.Invoice.ID[:])
+		for a := range m.Invoice.Amount {
+			m.Invoice.Amount[a] = big.NewInt(rng.Int63())
+		}
+		wire.TestMsg(t, m)
+	}
+}
+
+func TestPaymentRequestRejSerialization(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	for i := 0; i < 4; i++ {
+		m := &msgPaymentRequestRej{
+			ChannelID: test.NewRandomChannelID(rng),
+			Reason:    newRandomString(rng, 16, 16),
+		}
+		rng.Read(m.InvoiceID[:])
+		wire.TestMsg(t, m)
+	}
+}
+
+func TestInvoicePaymentSerialization(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	for i := 0; i < 4; i++ {
+		state := test.NewRandomState(rng)
+		m := &msgInvoicePayment{msgChannelUpdate{
+			ChannelUpdate: ChannelUpdate{
+				State:    state,
+				ActorIdx: channel.Index(rng.Intn(state.NumParts())),
+			},
+			Sig: newRandomSig(rng),
+		}}
+		rng.Read(m.InvoiceID[:])
+		wire.TestMsg(t, m)
+
+		// Other updates keep their encoding without the invoice ID.
+		var withID, withoutID bytes.Buffer
+		require.NoError(t, m.msgChannelUpdate.Encode(&withID))
+		m.InvoiceID = InvoiceID{}
+		require.NoError(t, m.msgChannelUpdate.Encode(&withoutID))
+		assert.Equal(t, withoutID.Bytes(), withID.Bytes())
+	}
+}
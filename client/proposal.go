@@ -284,6 +284,11 @@ func (c *Client) validTwoPartyProposal(
 	if err := base.Valid(); err != nil {
 		return err
 	}
+	if !channel.IsNoApp(base.App) {
+		if _, err := c.appRegistry.Resolve(base.App.Def()); err != nil {
+			return errors.WithMessage(err, "resolving app")
+		}
+	}
 
 	peers := c.proposalPeers(proposal)
 	if proposal.Base().NumPeers() != len(peers) {
@@ -507,6 +512,9 @@ func (c *Client) fundLedgerChannel(ctx context.Context, ch *Channel, agreement c
 			ch.machine.Idx(),
 			agreement,
 		)); channel.IsFundingTimeoutError(err) {
+		if c.recoverFunding {
+			return c.recoverFundingAfter(ctx, ch, err)
+		}
 		return errors.WithMessage(err, "waiting for peer funding")
 	} else if err != nil { // other runtime error
 		ch.Log().Warnf("error while funding channel: %v", err)
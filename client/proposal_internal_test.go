@@ -32,9 +32,10 @@ import (
 func TestClient_validTwoPartyProposal(t *testing.T) {
 	rng := pkgtest.Prng(t)
 
-	// dummy client that only has an id
+	// dummy client that only has an id and an app registry
 	c := &Client{
-		address: wallettest.NewRandomAddress(rng),
+		address:     wallettest.NewRandomAddress(rng),
+		appRegistry: channel.GlobalAppRegistry(),
 	}
 	validProp := NewRandomLedgerChannelProposal(rng, channeltest.WithNumParts(2))
 	validProp.Peers[0] = c.address // set us as the proposer
@@ -88,6 +89,19 @@ func TestClient_validTwoPartyProposal(t *testing.T) {
 			t.Errorf("[%d] Exptected proposal to be invalid", i)
 		}
 	}
+
+	t.Run("unknown app", func(t *testing.T) {
+		scoped := &Client{
+			address:     c.address,
+			appRegistry: channel.NewAppRegistry("scoped"),
+		}
+		err := scoped.validTwoPartyProposal(validProp, 0, peerAddr)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `app registry "scoped"`)
+
+		scoped.appRegistry.RegisterApp(validProp.App)
+		require.NoError(t, scoped.validTwoPartyProposal(validProp, 0, peerAddr))
+	})
 }
 
 func TestChannelProposal_assertValidNumParts(t *testing.T) {
@@ -35,7 +35,12 @@ func clientChannelFromSource(
 	parent *Channel,
 	peers ...wire.Address,
 ) (*Channel, error) {
-	return c.channelFromSource(ch, parent, peers...)
+	restored, err := c.channelFromSource(ch, parent, peers...)
+	if err != nil {
+		return nil, err
+	}
+	restored.receiver = ch.Receiver
+	return restored, nil
 }
 
 func (c *Client) reconstructChannel(
@@ -53,6 +53,10 @@ func (r *Alice) exec(_cfg ExecConfig, ch *paymentChannel) {
 	for i := 0; i < cfg.NumPayments[them]; i++ {
 		ch.recvTransfer(cfg.TxAmounts[them], fmt.Sprintf("Bob#%d", i))
 	}
+	// and pays some requests from Bob
+	for i := 0; i < cfg.NumRequests[them]; i++ {
+		ch.recvRequest(cfg.TxAmounts[them], fmt.Sprintf("Bob#%d", i))
+	}
 	// 2nd stage
 	r.waitStage()
 
@@ -60,6 +64,10 @@ func (r *Alice) exec(_cfg ExecConfig, ch *paymentChannel) {
 	for i := 0; i < cfg.NumPayments[we]; i++ {
 		ch.sendTransfer(cfg.TxAmounts[we], fmt.Sprintf("Alice#%d", i))
 	}
+	// and requests some payments from Bob
+	for i := 0; i < cfg.NumRequests[we]; i++ {
+		ch.sendRequest(cfg.TxAmounts[we], fmt.Sprintf("Alice#%d", i))
+	}
 	// 3rd stage
 	r.waitStage()
 
@@ -45,6 +45,10 @@ func (r *Bob) exec(_cfg ExecConfig, ch *paymentChannel, propHandler *acceptNextP
 	for i := 0; i < cfg.NumPayments[we]; i++ {
 		ch.sendTransfer(cfg.TxAmounts[we], fmt.Sprintf("Bob#%d", i))
 	}
+	// and requests some payments from Alice
+	for i := 0; i < cfg.NumRequests[we]; i++ {
+		ch.sendRequest(cfg.TxAmounts[we], fmt.Sprintf("Bob#%d", i))
+	}
 	// 2nd stage
 	r.waitStage()
 
@@ -52,6 +56,10 @@ func (r *Bob) exec(_cfg ExecConfig, ch *paymentChannel, propHandler *acceptNextP
 	for i := 0; i < cfg.NumPayments[them]; i++ {
 		ch.recvTransfer(cfg.TxAmounts[them], fmt.Sprintf("Alice#%d", i))
 	}
+	// and pays some requests from Alice
+	for i := 0; i < cfg.NumRequests[them]; i++ {
+		ch.recvRequest(cfg.TxAmounts[them], fmt.Sprintf("Alice#%d", i))
+	}
 	// 3rd stage
 	r.waitStage()
 
@@ -35,9 +35,11 @@ type (
 		*client.Channel
 		r *role // Reuse of timeout and testing obj
 
-		log     log.Logger
-		handler chan bool
-		res     chan handlerRes
+		log        log.Logger
+		handler    chan bool
+		res        chan handlerRes
+		invHandler chan bool
+		invRes     chan invoiceRes
 
 		bals []channel.Bal // independent tracking of channel balance for testing
 	}
@@ -47,16 +49,24 @@ type (
 		up  client.ChannelUpdate
 		err error
 	}
+
+	// An invoiceRes encapsulates the result of an invoice handling request.
+	invoiceRes struct {
+		inv client.Invoice
+		err error
+	}
 )
 
 func newPaymentChannel(ch *client.Channel, r *role) *paymentChannel {
 	return &paymentChannel{
-		Channel: ch,
-		r:       r,
-		log:     r.log.WithField("channel", ch.ID()),
-		handler: make(chan bool, 1),
-		res:     make(chan handlerRes),
-		bals:    channel.CloneBals(stateBals(ch.State())),
+		Channel:    ch,
+		r:          r,
+		log:        r.log.WithField("channel", ch.ID()),
+		handler:    make(chan bool, 1),
+		res:        make(chan handlerRes),
+		invHandler: make(chan bool, 1),
+		invRes:     make(chan invoiceRes),
+		bals:       channel.CloneBals(stateBals(ch.State())),
 	}
 }
 
@@ -144,6 +154,38 @@ func (ch *paymentChannel) recvTransfer(amount channel.Bal, desc string) {
 	} // else recvUpdate timed out
 }
 
+func (ch *paymentChannel) sendRequest(amount channel.Bal, desc string) {
+	ch.log.Debugf("Sending payment request: %s", desc)
+	ctx, cancel := context.WithTimeout(context.Background(), ch.r.timeout)
+	defer cancel()
+
+	err := ch.RequestPayment(ctx, []channel.Bal{amount}, desc)
+	ch.log.Infof("Sent payment request: %s, err: %v", desc, err)
+	if assert.NoError(ch.r.t, err) {
+		transferBal(ch.bals, ch.Idx()^1, amount)
+		ch.assertBals(ch.State())
+	}
+}
+
+func (ch *paymentChannel) recvRequest(amount channel.Bal, desc string) {
+	ch.log.Debugf("Receiving payment request: %s", desc)
+	ch.invHandler <- true
+
+	select {
+	case res := <-ch.invRes:
+		ch.log.Infof("Received payment request: %s, err: %v", desc, res.err)
+		assert := assert.New(ch.r.t)
+		assert.NoError(res.err)
+		assert.Equal(desc, res.inv.Memo)
+		if res.err == nil {
+			transferBal(ch.bals, ch.Idx(), amount)
+			ch.assertBals(ch.State())
+		}
+	case <-time.After(ch.r.timeout):
+		ch.r.t.Error("timeout: expected incoming payment request")
+	}
+}
+
 func (ch *paymentChannel) assertBals(state *channel.State) {
 	bals := stateBals(state)
 	ch.log.Infof(
@@ -217,6 +259,22 @@ func (ch *paymentChannel) Handle(up client.ChannelUpdate, res *client.UpdateResp
 	}
 }
 
+// The payment channel is its own invoice handler.
+func (ch *paymentChannel) HandleInvoice(inv client.Invoice, res *client.InvoiceResponder) {
+	ch.log.Infof("Incoming invoice: %v", inv)
+	ctx, cancel := context.WithTimeout(context.Background(), ch.r.timeout)
+	defer cancel()
+
+	accept := <-ch.invHandler
+	if accept {
+		ch.log.Debug("Paying...")
+		ch.invRes <- invoiceRes{inv, res.Accept(ctx)}
+	} else {
+		ch.log.Debug("Rejecting...")
+		ch.invRes <- invoiceRes{inv, res.Reject(ctx, "Rejection")}
+	}
+}
+
 func transferBal(bals []channel.Bal, ourIdx channel.Index, amount *big.Int) {
 	a := new(big.Int).Set(amount) // local copy because we mutate it
 	otherIdx := ourIdx ^ 1
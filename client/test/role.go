@@ -186,6 +186,7 @@ func (r *role) setClient(cl *client.Client) {
 	if r.setup.PR != nil {
 		cl.EnablePersistence(r.setup.PR)
 	}
+	cl.SetInvoiceHandler(r.InvoiceHandler())
 	cl.OnNewChannel(func(_ch *client.Channel) {
 		ch := newPaymentChannel(_ch, r)
 		r.chans.add(ch)
@@ -434,3 +435,21 @@ func (h *roleUpdateHandler) HandleUpdate(up client.ChannelUpdate, res *client.Up
 
 	ch.Handle(up, res)
 }
+
+type roleInvoiceHandler role
+
+func (r *role) InvoiceHandler() *roleInvoiceHandler { return (*roleInvoiceHandler)(r) }
+
+// HandleInvoice implements the Role as its own InvoiceHandler.
+func (h *roleInvoiceHandler) HandleInvoice(inv client.Invoice, res *client.InvoiceResponder) {
+	ch, ok := h.chans.get(inv.ChannelID)
+	if !ok {
+		h.t.Errorf("unknown channel: %v", inv.ChannelID)
+		ctx, cancel := context.WithTimeout(context.Background(), h.setup.Timeout)
+		defer cancel()
+		res.Reject(ctx, "unknown channel")
+		return
+	}
+
+	ch.HandleInvoice(inv, res)
+}
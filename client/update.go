@@ -49,6 +49,9 @@ type (
 		// ActorIdx is the actor causing the new state. It does not need to
 		// coincide with the sender of the request.
 		ActorIdx channel.Index
+		// InvoiceID references the invoice that is paid by this update. It is
+		// zero if the update does not pay an invoice.
+		InvoiceID InvoiceID
 	}
 
 	// An UpdateHandler decides how to handle incoming channel update requests
@@ -129,7 +132,12 @@ func (c *Channel) Update(ctx context.Context, next *channel.State) (err error) {
 
 // Like Update, but assumes channel locked and update validated.
 func (c *Channel) update(ctx context.Context, next *channel.State) (err error) {
-	up := makeChannelUpdate(next, c.machine.Idx())
+	return c.proposeUpdate(ctx, makeChannelUpdate(next, c.machine.Idx()))
+}
+
+// proposeUpdate proposes the given channel update to all channel participants.
+// It assumes the channel locked and the update validated.
+func (c *Channel) proposeUpdate(ctx context.Context, up ChannelUpdate) (err error) {
 	if err = c.machine.Update(ctx, up.State, up.ActorIdx); err != nil {
 		return errors.WithMessage(err, "updating machine")
 	}
@@ -157,11 +165,15 @@ func (c *Channel) update(ctx context.Context, next *channel.State) (err error) {
 	// nolint:errcheck
 	defer resRecv.Close()
 
-	msgUpdate := &msgChannelUpdate{
+	msgUpdate := msgChannelUpdate{
 		ChannelUpdate: up,
 		Sig:           sig,
 	}
-	if err = c.conn.Send(ctx, msgUpdate); err != nil {
+	var msg wire.Msg = &msgUpdate
+	if up.InvoiceID != (InvoiceID{}) {
+		msg = &msgInvoicePayment{msgUpdate}
+	}
+	if err = c.conn.Send(ctx, msg); err != nil {
 		return errors.WithMessage(err, "sending update")
 	}
 
@@ -258,6 +270,11 @@ func (c *Channel) handleUpdateReq(
 		return
 	}
 
+	if ui, ok := c.invoicePayments.Filter(req.ChannelUpdate); ok {
+		ui.HandleUpdate(req.ChannelUpdate, responder)
+		return
+	}
+
 	uh.HandleUpdate(req.ChannelUpdate, responder)
 }
 
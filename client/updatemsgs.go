@@ -55,7 +55,9 @@ type (
 	}
 
 	// msgChannelUpdate is the wire message of a channel update proposal. It
-	// additionally holds the signature on the proposed state.
+	// additionally holds the signature on the proposed state. The InvoiceID of
+	// the ChannelUpdate is not encoded, invoice payments are sent as
+	// msgInvoicePayment.
 	msgChannelUpdate struct {
 		ChannelUpdate
 		// Sig is the signature on the proposed state by the peer sending the
@@ -20,6 +20,7 @@ import (
 	"github.com/pkg/errors"
 
 	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
 )
 
 // AdjudicatorEventHandler represents an interface for handling adjudicator events.
@@ -123,7 +124,8 @@ func (c *Channel) ProgressBy(ctx context.Context, update func(*channel.State)) e
 	return errors.WithMessage(c.adjudicator.Progress(ctx, *pr), "progressing")
 }
 
-// Settle concludes a channel and withdraws the funds.
+// Settle concludes a channel and withdraws the funds to the channel's
+// withdrawal receiver, see SetWithdrawalReceiver.
 func (c *Channel) Settle(ctx context.Context, secondary bool) error {
 	return c.SettleWithSubchannels(ctx, nil, secondary)
 }
@@ -139,6 +141,29 @@ func (c *Channel) SettleWithSubchannels(ctx context.Context, subStates channel.S
 	}
 	defer c.machMtx.Unlock()
 
+	return c.settle(ctx, subStates, c.receiver, secondary)
+}
+
+// SettleWithReceiver works like SettleWithSubchannels but withdraws the funds
+// of a ledger channel to the given receiver instead of the channel's
+// withdrawal receiver. The channel's withdrawal receiver is not changed.
+func (c *Channel) SettleWithReceiver(ctx context.Context, subStates channel.StateMap, receiver wallet.Address, secondary bool) error {
+	if !c.IsLedgerChannel() {
+		return errors.New("only ledger channels can be withdrawn to a receiver")
+	}
+	// Lock channel machine.
+	if !c.machMtx.TryLockCtx(ctx) {
+		return errors.WithMessage(ctx.Err(), "locking machine")
+	}
+	defer c.machMtx.Unlock()
+
+	return c.settle(ctx, subStates, receiver, secondary)
+}
+
+// settle concludes the channel and withdraws the funds to the given receiver.
+//
+// The caller is expected to have locked the channel mutex.
+func (c *Channel) settle(ctx context.Context, subStates channel.StateMap, receiver wallet.Address, secondary bool) error {
 	if err := c.machine.SetWithdrawing(ctx); err != nil {
 		return errors.WithMessage(err, "setting machine to withdrawing phase")
 	}
@@ -147,6 +172,7 @@ func (c *Channel) SettleWithSubchannels(ctx context.Context, subStates channel.S
 	case c.IsLedgerChannel():
 		req := c.machine.AdjudicatorReq()
 		req.Secondary = secondary
+		req.Receiver = receiver
 		if err := c.adjudicator.Withdraw(ctx, req, subStates); err != nil {
 			return errors.WithMessage(err, "calling Withdraw")
 		}
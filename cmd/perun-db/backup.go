@@ -0,0 +1,199 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+
+	ethwallet "perun.network/go-perun/backend/ethereum/wallet"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+	"perun.network/go-perun/channel/persistence/backup"
+	"perun.network/go-perun/channel/persistence/keyvalue"
+	"perun.network/go-perun/pkg/sortedkv/leveldb"
+)
+
+// backupFlags are the flags of the export and import commands.
+type backupFlags struct {
+	db      string
+	encKey  string
+	timeout time.Duration
+}
+
+func (f *backupFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.db, "db", "", "LevelDB directory of the channel persistence")
+	fs.StringVar(&f.encKey, "enckey", "", "file containing a hex-encoded 32 byte AES key to en-/decrypt the backup")
+	fs.DurationVar(&f.timeout, "timeout", defaultTimeout, "timeout of the whole command")
+}
+
+func (f *backupFlags) setup() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), f.timeout)
+}
+
+func runExport(args []string, out io.Writer) error {
+	var (
+		f       backupFlags
+		keyFile string
+		file    string
+	)
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(out)
+	f.register(fs)
+	fs.StringVar(&keyFile, "key", "", "file containing the hex-encoded private key that signs the backup")
+	fs.StringVar(&file, "out", "", "backup file to write")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if f.db == "" || keyFile == "" || file == "" {
+		return errors.New("need -db, -key and -out")
+	}
+
+	key, err := crypto.LoadECDSA(keyFile)
+	if err != nil {
+		return errors.Wrap(err, "loading key")
+	}
+	signer, err := simple.NewWallet(key).Unlock(ethwallet.AsWalletAddr(crypto.PubkeyToAddress(key.PublicKey)))
+	if err != nil {
+		return err
+	}
+	aead, err := loadCipher(f.encKey)
+	if err != nil {
+		return err
+	}
+	pr, err := openPersistRestorer(f.db, false)
+	if err != nil {
+		return err
+	}
+	defer pr.Close() // nolint:errcheck
+
+	ctx, cancel := f.setup()
+	defer cancel()
+	w, err := os.Create(file)
+	if err != nil {
+		return errors.Wrap(err, "creating backup file")
+	}
+	if err := backup.Export(ctx, w, pr, signer, aead); err != nil {
+		w.Close() // nolint:errcheck,gosec
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, "closing backup file")
+	}
+	fmt.Fprintf(out, "Wrote backup signed by %v to %s.\n", signer.Address(), file)
+	return nil
+}
+
+func runImport(args []string, out io.Writer) error {
+	var (
+		f      backupFlags
+		signer string
+		file   string
+	)
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(out)
+	f.register(fs)
+	fs.StringVar(&signer, "signer", "", "address of the account that signed the backup")
+	fs.StringVar(&file, "in", "", "backup file to read")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if f.db == "" || signer == "" || file == "" {
+		return errors.New("need -db, -signer and -in")
+	}
+	if !common.IsHexAddress(signer) {
+		return errors.Errorf("invalid signer address %q", signer)
+	}
+
+	aead, err := loadCipher(f.encKey)
+	if err != nil {
+		return err
+	}
+	pr, err := openPersistRestorer(f.db, true)
+	if err != nil {
+		return err
+	}
+	defer pr.Close() // nolint:errcheck
+
+	ctx, cancel := f.setup()
+	defer cancel()
+	r, err := os.Open(file)
+	if err != nil {
+		return errors.Wrap(err, "opening backup file")
+	}
+	defer r.Close() // nolint:errcheck
+	n, err := backup.Import(ctx, r, pr, ethwallet.AsWalletAddr(common.HexToAddress(signer)), aead)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Imported %d channels.\n", n)
+	return nil
+}
+
+// openPersistRestorer opens the LevelDB channel persistence at path. If create
+// is false, it fails if the database does not exist.
+func openPersistRestorer(path string, create bool) (*keyvalue.PersistRestorer, error) {
+	// LoadDatabase would create a missing database.
+	if _, err := os.Stat(path); err != nil && !(create && os.IsNotExist(err)) {
+		return nil, errors.Wrap(err, "opening database")
+	}
+	db, err := leveldb.LoadDatabase(path)
+	if err != nil {
+		return nil, errors.WithMessage(err, "opening database")
+	}
+	pr, err := keyvalue.OpenPersistRestorer(db)
+	if err != nil {
+		db.Close() // nolint:errcheck,gosec
+		return nil, err
+	}
+	return pr, nil
+}
+
+// loadCipher reads a hex-encoded AES key from file and creates an AES-GCM
+// cipher. It returns nil if file is empty.
+func loadCipher(file string) (cipher.AEAD, error) {
+	if file == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading encryption key")
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding encryption key")
+	}
+	if len(key) != 32 {
+		return nil, errors.Errorf("encryption key has %d bytes, expected 32", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	return aead, errors.Wrap(err, "creating cipher")
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "perun.network/go-perun/backend/ethereum"
+	"perun.network/go-perun/channel/persistence/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+	wtest "perun.network/go-perun/wallet/test"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	rng := pkgtest.Prng(t)
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+
+	pr, err := openPersistRestorer(src, true)
+	require.NoError(t, err)
+	ch := test.NewClient(ctx, t, rng, pr).NewChannel(t, wtest.NewRandomAddress(rng), nil)
+	ch.Init(t, rng)
+	ch.SignAll(t)
+	ch.EnableInit(t)
+	require.NoError(t, pr.Close())
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "key")
+	require.NoError(t, crypto.SaveECDSA(keyFile, key))
+	encKey := make([]byte, 32)
+	rng.Read(encKey)
+	encKeyFile := filepath.Join(dir, "enckey")
+	require.NoError(t, ioutil.WriteFile(encKeyFile, []byte(hex.EncodeToString(encKey)), 0600))
+	file := filepath.Join(dir, "backup")
+	signer := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	var out bytes.Buffer
+	assert.Error(t, run([]string{"export", "-db", src}, &out), "missing flags")
+	missing := filepath.Join(dir, "missing")
+	assert.Error(t, run([]string{"export", "-db", missing, "-key", keyFile, "-out", file}, &out), "missing database")
+	_, err = os.Stat(missing)
+	assert.True(t, os.IsNotExist(err), "export must not create the database")
+	require.NoError(t, run([]string{"export", "-db", src, "-key", keyFile, "-enckey", encKeyFile, "-out", file}, &out))
+
+	assert.Error(t, run([]string{"import", "-db", dst, "-signer", signer, "-in", file}, &out), "missing key")
+	assert.Error(t, run([]string{"import", "-db", dst, "-signer", common.Address{1}.Hex(), "-enckey", encKeyFile, "-in", file}, &out), "wrong signer")
+	out.Reset()
+	require.NoError(t, run([]string{"import", "-db", dst, "-signer", signer, "-enckey", encKeyFile, "-in", file}, &out))
+	assert.Equal(t, "Imported 1 channels.\n", out.String())
+
+	pr, err = openPersistRestorer(dst, false)
+	require.NoError(t, err)
+	defer pr.Close() // nolint:errcheck
+	restored, err := pr.RestoreChannel(ctx, ch.ID())
+	require.NoError(t, err)
+	ch.RequireEqual(t, restored)
+}
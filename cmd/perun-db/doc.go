@@ -0,0 +1,32 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command perun-db backs up and checks LevelDB channel persistences.
+//
+// Usage:
+//
+//	perun-db export -db dir -key file -out file [flags]
+//	perun-db import -db dir -signer address -in file [flags]
+//	perun-db fsck -db dir [-repair]
+//
+// The export command writes all channels of a LevelDB channel persistence into
+// a backup file that is signed with the given private key and optionally
+// encrypted with an AES key given by -enckey. The import command verifies such
+// a backup and imports its channels into a LevelDB channel persistence, without
+// overwriting channels that are already persisted in a newer version.
+//
+// The fsck command checks the consistency of a LevelDB channel persistence and
+// reports all found problems. With -repair, the problems that can be repaired
+// are repaired; the command fails if unrepaired problems remain.
+package main // import "perun.network/go-perun/cmd/perun-db"
@@ -0,0 +1,71 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel/persistence/keyvalue"
+	"perun.network/go-perun/pkg/sortedkv/leveldb"
+)
+
+func runFsck(args []string, out io.Writer) error {
+	var (
+		db     string
+		repair bool
+	)
+	fs := flag.NewFlagSet("fsck", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.StringVar(&db, "db", "", "LevelDB directory of the channel persistence")
+	fs.BoolVar(&repair, "repair", false, "repair the found problems where possible")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if db == "" {
+		return errors.New("missing -db")
+	}
+	// LoadDatabase would create a missing database.
+	if _, err := os.Stat(db); err != nil {
+		return errors.Wrap(err, "opening database")
+	}
+
+	ldb, err := leveldb.LoadDatabase(db)
+	if err != nil {
+		return errors.WithMessage(err, "opening database")
+	}
+	defer ldb.Close() // nolint:errcheck
+
+	report, err := keyvalue.Check(ldb, repair)
+	if err != nil {
+		return err
+	}
+	unrepaired := 0
+	for _, p := range report.Problems {
+		fmt.Fprintln(out, p)
+		if !p.Repaired {
+			unrepaired++
+		}
+	}
+	fmt.Fprintf(out, "Checked %d channels, found %d problems.\n", report.Channels, len(report.Problems))
+	if unrepaired > 0 {
+		return errors.Errorf("%d problems not repaired", unrepaired)
+	}
+	return nil
+}
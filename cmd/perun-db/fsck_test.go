@@ -0,0 +1,69 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/channel/persistence/test"
+	"perun.network/go-perun/pkg/sortedkv/leveldb"
+	pkgtest "perun.network/go-perun/pkg/test"
+	wtest "perun.network/go-perun/wallet/test"
+)
+
+func TestFsck(t *testing.T) {
+	ctx := context.Background()
+	rng := pkgtest.Prng(t)
+	dir := filepath.Join(t.TempDir(), "db")
+
+	var out bytes.Buffer
+	assert.Error(t, run([]string{"fsck"}, &out), "missing -db")
+	assert.Error(t, run([]string{"fsck", "-db", dir}, &out), "missing database")
+
+	pr, err := openPersistRestorer(dir, true)
+	require.NoError(t, err)
+	ch := test.NewClient(ctx, t, rng, pr).NewChannel(t, wtest.NewRandomAddress(rng), nil)
+	ch.Init(t, rng)
+	ch.SignAll(t)
+	ch.EnableInit(t)
+	require.NoError(t, pr.Close())
+
+	out.Reset()
+	require.NoError(t, run([]string{"fsck", "-db", dir}, &out))
+	assert.Equal(t, "Checked 1 channels, found 0 problems.\n", out.String())
+
+	// Remove the withdrawal receiver, which can be repaired.
+	db, err := leveldb.LoadDatabase(dir)
+	require.NoError(t, err)
+	id := ch.ID()
+	require.NoError(t, db.Delete("Chan:"+string(id[:])+":receiver"))
+	require.NoError(t, db.Close())
+
+	out.Reset()
+	assert.Error(t, run([]string{"fsck", "-db", dir}, &out), "unrepaired problem")
+	assert.Contains(t, out.String(), "found 1 problems")
+	out.Reset()
+	require.NoError(t, run([]string{"fsck", "-db", dir, "-repair"}, &out))
+	assert.Contains(t, out.String(), "(repaired)")
+	out.Reset()
+	require.NoError(t, run([]string{"fsck", "-db", dir}, &out))
+	assert.Equal(t, "Checked 1 channels, found 0 problems.\n", out.String())
+}
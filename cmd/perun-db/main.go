@@ -0,0 +1,50 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultTimeout = 5 * time.Minute
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// run executes the command given by args and writes its output to out.
+func run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("missing command, expected export, import or fsck")
+	}
+	switch args[0] {
+	case "export":
+		return runExport(args[1:], out)
+	case "import":
+		return runImport(args[1:], out)
+	case "fsck":
+		return runFsck(args[1:], out)
+	default:
+		return errors.Errorf("unknown command %q, expected export, import or fsck", args[0])
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command perun-eth deploys and validates the Perun Ethereum contracts.
+//
+// Usage:
+//
+//	perun-eth deploy [flags] [token...]
+//	perun-eth validate [flags]
+//
+// The deploy command deploys the Adjudicator, the ETH AssetHolder and an ERC20
+// AssetHolder for every given token address and writes the addresses of the
+// deployment to the config file. The deployer is either a hex-encoded private
+// key read from a file or an account of an external signer, like Clef. With
+// -sim, the contracts are deployed to an in-memory simulated chain, which is
+// useful as a dry run.
+//
+// The validate command reads a config file and checks the bytecodes of all
+// contracts of the deployment on the connected chain.
+package main // import "perun.network/go-perun/cmd/perun-eth"
@@ -0,0 +1,297 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	ethchannel "perun.network/go-perun/backend/ethereum/channel"
+	"perun.network/go-perun/backend/ethereum/wallet/external"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+	plogrus "perun.network/go-perun/log/logrus"
+)
+
+const (
+	defaultConfig  = "perun-eth.json"
+	defaultTimeout = 5 * time.Minute
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// run executes the command given by args and writes its output to out.
+func run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("missing command, expected deploy or validate")
+	}
+	switch args[0] {
+	case "deploy":
+		return runDeploy(args[1:], out)
+	case "validate":
+		return runValidate(args[1:], out)
+	default:
+		return errors.Errorf("unknown command %q, expected deploy or validate", args[0])
+	}
+}
+
+// commonFlags are the flags of all commands.
+type commonFlags struct {
+	node    string
+	config  string
+	timeout time.Duration
+	verbose bool
+}
+
+func (f *commonFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.node, "node", "", "Ethereum node endpoint (http, ws or IPC path)")
+	fs.StringVar(&f.config, "config", defaultConfig, "deployment config file")
+	fs.DurationVar(&f.timeout, "timeout", defaultTimeout, "timeout of the whole command")
+	fs.BoolVar(&f.verbose, "v", false, "log sent transactions")
+}
+
+func (f *commonFlags) setup() (context.Context, context.CancelFunc) {
+	if f.verbose {
+		plogrus.Set(logrus.InfoLevel, &logrus.TextFormatter{})
+	}
+	return context.WithTimeout(context.Background(), f.timeout)
+}
+
+func runDeploy(args []string, out io.Writer) error {
+	var (
+		f       commonFlags
+		sim     bool
+		keyFile string
+		signer  string
+		account string
+	)
+	fs := flag.NewFlagSet("deploy", flag.ContinueOnError)
+	fs.SetOutput(out)
+	f.register(fs)
+	fs.BoolVar(&sim, "sim", false, "deploy to an in-memory simulated chain instead of a node")
+	fs.StringVar(&keyFile, "key", "", "file containing the hex-encoded private key of the deployer")
+	fs.StringVar(&signer, "signer", "", "external signer endpoint, e.g., Clef's IPC path")
+	fs.StringVar(&account, "account", "", "deployer account of the external signer (default: first account)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	tokens := make([]common.Address, fs.NArg())
+	for i, arg := range fs.Args() {
+		if !common.IsHexAddress(arg) {
+			return errors.Errorf("invalid token address %q", arg)
+		}
+		tokens[i] = common.HexToAddress(arg)
+	}
+
+	ctx, cancel := f.setup()
+	defer cancel()
+
+	var (
+		cb       ethchannel.ContractBackend
+		deployer accounts.Account
+		err      error
+	)
+	if sim {
+		cb, deployer, err = newSimContractBackend()
+	} else {
+		cb, deployer, err = dialContractBackend(ctx, f.node, keyFile, signer, account)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Deploying contracts with account %v...\n", deployer.Address.Hex())
+	d, err := deploy(ctx, cb, deployer, tokens, f.config)
+	if err != nil {
+		return err
+	}
+	printDeployment(out, d)
+	fmt.Fprintf(out, "Wrote deployment to %s.\n", f.config)
+	return nil
+}
+
+// deploy deploys and validates a full set of contracts and writes the
+// deployment to the config file.
+func deploy(ctx context.Context, cb ethchannel.ContractBackend, deployer accounts.Account, tokens []common.Address, config string) (*ethchannel.Deployment, error) {
+	d, err := ethchannel.DeployContracts(ctx, cb, deployer, tokens)
+	if err != nil {
+		return nil, err
+	}
+	if err := ethchannel.ValidateDeployment(ctx, cb, d); err != nil {
+		return nil, errors.WithMessage(err, "validating new deployment")
+	}
+	return d, writeConfig(config, d)
+}
+
+func runValidate(args []string, out io.Writer) error {
+	var f commonFlags
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(out)
+	f.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if f.node == "" {
+		return errors.New("missing -node")
+	}
+
+	ctx, cancel := f.setup()
+	defer cancel()
+	client, err := ethclient.DialContext(ctx, f.node)
+	if err != nil {
+		return errors.Wrap(err, "connecting to node")
+	}
+	defer client.Close()
+
+	d, err := validate(ctx, client, f.config)
+	if err != nil {
+		return err
+	}
+	printDeployment(out, d)
+	fmt.Fprintln(out, "Deployment is valid.")
+	return nil
+}
+
+// chainIDReader is implemented by backends that know their chain ID.
+type chainIDReader interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// validate reads the deployment from the config file and validates it. If the
+// config contains a chain ID, it has to match the chain ID of the node.
+func validate(ctx context.Context, client ethchannel.ContractInterface, config string) (*ethchannel.Deployment, error) {
+	d, err := readConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if cr, ok := client.(chainIDReader); ok && d.ChainID != nil {
+		id, err := cr.ChainID(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "querying chain ID")
+		}
+		if id.Cmp(d.ChainID) != 0 {
+			return nil, errors.Errorf("deployment is for chain %v but node is on chain %v", d.ChainID, id)
+		}
+	}
+	return d, ethchannel.ValidateDeployment(ctx, client, d)
+}
+
+// dialContractBackend connects to the node and creates a ContractBackend that
+// signs with the key from keyFile or the external signer.
+func dialContractBackend(ctx context.Context, node, keyFile, signer, account string) (ethchannel.ContractBackend, accounts.Account, error) {
+	if node == "" {
+		return ethchannel.ContractBackend{}, accounts.Account{}, errors.New("missing -node or -sim")
+	}
+	if (keyFile == "") == (signer == "") {
+		return ethchannel.ContractBackend{}, accounts.Account{}, errors.New("need exactly one of -key or -signer")
+	}
+	client, err := ethclient.DialContext(ctx, node)
+	if err != nil {
+		return ethchannel.ContractBackend{}, accounts.Account{}, errors.Wrap(err, "connecting to node")
+	}
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return ethchannel.ContractBackend{}, accounts.Account{}, errors.Wrap(err, "querying chain ID")
+	}
+	ethSigner := types.NewEIP155Signer(chainID)
+
+	if keyFile != "" {
+		key, err := crypto.LoadECDSA(keyFile)
+		if err != nil {
+			return ethchannel.ContractBackend{}, accounts.Account{}, errors.Wrap(err, "loading key")
+		}
+		w := simple.NewWallet(key)
+		deployer := accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+		return ethchannel.NewContractBackend(client, simple.NewTransactor(w, ethSigner)), deployer, nil
+	}
+
+	w, err := external.NewWallet(signer)
+	if err != nil {
+		return ethchannel.ContractBackend{}, accounts.Account{}, err
+	}
+	deployer, err := signerAccount(w, account)
+	if err != nil {
+		return ethchannel.ContractBackend{}, accounts.Account{}, err
+	}
+	return ethchannel.NewContractBackend(client, external.NewTransactor(w, ethSigner)), deployer, nil
+}
+
+// signerAccount returns the given account or the first account of the
+// external signer if account is empty.
+func signerAccount(w *external.Wallet, account string) (accounts.Account, error) {
+	if account != "" {
+		if !common.IsHexAddress(account) {
+			return accounts.Account{}, errors.Errorf("invalid account address %q", account)
+		}
+		return accounts.Account{Address: common.HexToAddress(account)}, nil
+	}
+	addrs, err := w.Accounts()
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	if len(addrs) == 0 {
+		return accounts.Account{}, errors.New("external signer has no accounts")
+	}
+	return accounts.Account{Address: addrs[0]}, nil
+}
+
+func printDeployment(out io.Writer, d *ethchannel.Deployment) {
+	if d.ChainID != nil {
+		fmt.Fprintf(out, "Chain ID:          %v\n", d.ChainID)
+	}
+	fmt.Fprintf(out, "Adjudicator:       %v\n", d.Adjudicator.Hex())
+	fmt.Fprintf(out, "ETH AssetHolder:   %v\n", d.AssetHolderETH.Hex())
+	for _, erc20 := range d.ERC20 {
+		fmt.Fprintf(out, "ERC20 AssetHolder: %v (token %v)\n", erc20.AssetHolder.Hex(), erc20.Token.Hex())
+	}
+}
+
+func writeConfig(path string, d *ethchannel.Deployment) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "creating config file")
+	}
+	if err := ethchannel.WriteDeployment(file, d); err != nil {
+		file.Close() // nolint:errcheck,gosec
+		return err
+	}
+	return errors.Wrap(file.Close(), "closing config file")
+}
+
+func readConfig(path string) (*ethchannel.Deployment, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening config file")
+	}
+	defer file.Close() // nolint:errcheck
+	return ethchannel.ReadDeployment(file)
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ethchannel "perun.network/go-perun/backend/ethereum/channel"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+)
+
+var tokens = []common.Address{common.HexToAddress("0x1111"), common.HexToAddress("0x2222")}
+
+func TestRun(t *testing.T) {
+	var out bytes.Buffer
+	assert.Error(t, run(nil, &out), "missing command")
+	assert.Error(t, run([]string{"undeploy"}, &out), "unknown command")
+	assert.Error(t, run([]string{"validate"}, &out), "missing node")
+	assert.Error(t, run([]string{"deploy"}, &out), "missing node")
+	assert.Error(t, run([]string{"deploy", "-sim", "0xinvalid"}, &out), "invalid token")
+
+	config := filepath.Join(t.TempDir(), "deployment.json")
+	require.NoError(t, run([]string{"deploy", "-sim", "-config", config, tokens[0].Hex(), tokens[1].Hex()}, &out))
+	d, err := readConfig(config)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(simChainID), d.ChainID)
+	require.Len(t, d.ERC20, 2)
+	assert.Equal(t, tokens[1], d.ERC20[1].Token)
+}
+
+func TestDeployValidate(t *testing.T) {
+	ctx := context.Background()
+	sim, key, err := newSimBackend()
+	require.NoError(t, err)
+	tr := simple.NewTransactor(simple.NewWallet(key), types.NewEIP155Signer(big.NewInt(simChainID)))
+	cb := ethchannel.NewContractBackend(sim, tr)
+	deployer := accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+
+	config := filepath.Join(t.TempDir(), "deployment.json")
+	d, err := deploy(ctx, cb, deployer, tokens, config)
+	require.NoError(t, err)
+	validated, err := validate(ctx, sim, config)
+	require.NoError(t, err)
+	assert.Equal(t, d, validated)
+
+	t.Run("wrong_token", func(t *testing.T) {
+		wrong := *d
+		wrong.ERC20 = []ethchannel.ERC20Deployment{{Token: tokens[1], AssetHolder: d.ERC20[0].AssetHolder}}
+		require.NoError(t, writeConfig(config, &wrong))
+		_, err := validate(ctx, sim, config)
+		assert.True(t, ethchannel.IsErrInvalidContractCode(err))
+	})
+
+	t.Run("wrong_chain", func(t *testing.T) {
+		wrong := *d
+		wrong.ChainID = big.NewInt(1)
+		require.NoError(t, writeConfig(config, &wrong))
+		_, err := validate(ctx, sim, config)
+		assert.Error(t, err)
+	})
+}
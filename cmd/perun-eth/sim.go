@@ -0,0 +1,81 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+
+	ethchannel "perun.network/go-perun/backend/ethereum/channel"
+	"perun.network/go-perun/backend/ethereum/wallet/simple"
+)
+
+const (
+	// simChainID is the chain ID of the simulated backend.
+	simChainID = 1337
+	// simGasLimit is the block gas limit of the simulated backend.
+	simGasLimit = 8000000
+)
+
+// simBackend is a simulated chain that mines a block for every transaction.
+type simBackend struct {
+	*backends.SimulatedBackend
+}
+
+// SendTransaction sends the transaction and mines it.
+func (s simBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if err := s.SimulatedBackend.SendTransaction(ctx, tx); err != nil {
+		return errors.WithStack(err)
+	}
+	s.Commit()
+	return nil
+}
+
+// ChainID returns the chain ID of the simulated backend.
+func (s simBackend) ChainID(context.Context) (*big.Int, error) {
+	return big.NewInt(simChainID), nil
+}
+
+// newSimBackend creates a new simulated chain with a random, funded account
+// and returns the chain and the account's key.
+func newSimBackend() (simBackend, *ecdsa.PrivateKey, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return simBackend{}, nil, errors.Wrap(err, "generating key")
+	}
+	balance := new(big.Int).Lsh(big.NewInt(1), 128)
+	alloc := core.GenesisAlloc{crypto.PubkeyToAddress(key.PublicKey): {Balance: balance}}
+	return simBackend{backends.NewSimulatedBackend(alloc, simGasLimit)}, key, nil
+}
+
+// newSimContractBackend creates a ContractBackend on a new simulated chain and
+// returns it together with the funded deployer account.
+func newSimContractBackend() (ethchannel.ContractBackend, accounts.Account, error) {
+	sim, key, err := newSimBackend()
+	if err != nil {
+		return ethchannel.ContractBackend{}, accounts.Account{}, err
+	}
+	tr := simple.NewTransactor(simple.NewWallet(key), types.NewEIP155Signer(big.NewInt(simChainID)))
+	deployer := accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+	return ethchannel.NewContractBackend(sim, tr), deployer, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package badger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/pkg/sortedkv"
+	"perun.network/go-perun/pkg/sortedkv/test"
+)
+
+func TestBatch(t *testing.T) {
+	runTestOnTempDatabase(t, func(db *Database) {
+		test.GenericBatchTest(t, db)
+	})
+	runTestOnTempDatabase(t, func(db *Database) {
+		test.GenericBatchTest(t, sortedkv.NewTable(db, "table"))
+	})
+}
+
+func TestDatabase(t *testing.T) {
+	runTestOnTempDatabase(t, func(db *Database) {
+		test.GenericDatabaseTest(t, db)
+	})
+}
+
+func TestIterator(t *testing.T) {
+	runTestOnTempDatabase(t, func(db *Database) {
+		test.GenericIteratorTest(t, db)
+	})
+}
+
+func TestDatabase_Reopen(t *testing.T) {
+	path := t.TempDir()
+	db, err := LoadDatabase(path)
+	require.NoError(t, err)
+	require.NoError(t, db.Put("key", "value"))
+	require.NoError(t, db.Close())
+
+	db, err = LoadDatabase(path)
+	require.NoError(t, err)
+	defer db.Close() // nolint:errcheck
+	v, err := db.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+}
+
+func runTestOnTempDatabase(t *testing.T, tester func(*Database)) {
+	db, err := LoadDatabase(t.TempDir())
+	require.NoError(t, err, "Could not load database")
+	defer func() { assert.NoError(t, db.Close(), "Could not close database") }()
+
+	tester(db)
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package badger
+
+import (
+	"github.com/dgraph-io/badger/v2"
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/pkg/sortedkv"
+)
+
+// Database implements the Database interface and stores the values in a
+// Badger database.
+type Database struct {
+	*badger.DB
+}
+
+// LoadDatabase opens the Badger database in the directory path with default
+// options and creates it if it does not exist. Badger's logging is disabled.
+func LoadDatabase(path string) (*Database, error) {
+	return LoadDatabaseWithOptions(badger.DefaultOptions(path).WithLogger(nil))
+}
+
+// LoadDatabaseWithOptions opens a Badger database with the given options. For
+// example, writes are synced to disk before they return with
+//
+//	badger.DefaultOptions(path).WithSyncWrites(true)
+func LoadDatabaseWithOptions(opts badger.Options) (*Database, error) {
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening badger database")
+	}
+	return &Database{db}, nil
+}
+
+// interface Reader
+
+// Has returns true iff the database contains a key.
+func (d *Database) Has(key string) (bool, error) {
+	err := d.View(func(txn *badger.Txn) error {
+		_, err := txn.Get([]byte(key))
+		return err
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return false, nil
+	}
+	return err == nil, errors.Wrap(err, "Database.Has(key) error")
+}
+
+// Get returns the value as string for given key if it is present in the store.
+func (d *Database) Get(key string) (string, error) {
+	val, err := d.GetBytes(key)
+	return string(val), err
+}
+
+// GetBytes returns the value as []byte for given key if it is present in the store.
+func (d *Database) GetBytes(key string) ([]byte, error) {
+	var val []byte
+	err := d.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+		val, err = item.ValueCopy(nil)
+		return err
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, errors.WithMessage(&sortedkv.ErrNotFound{Key: key}, "Database.Get(key) error")
+	}
+	return val, errors.Wrap(err, "Database.Get(key) error")
+}
+
+// interface Writer
+
+// Put inserts the given value into the key-value store.
+// If the key is already present, it is overwritten and no error is returned.
+func (d *Database) Put(key string, value string) error {
+	return d.PutBytes(key, []byte(value))
+}
+
+// PutBytes inserts the given value into the key-value store.
+// If the key is already present, it is overwritten and no error is returned.
+func (d *Database) PutBytes(key string, value []byte) error {
+	err := d.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(key), append([]byte{}, value...))
+	})
+	return errors.Wrap(err, "Database.Put(key, value) error")
+}
+
+// Delete removes the key from the key-value store.
+// If the key is not present, an error is returned.
+func (d *Database) Delete(key string) error {
+	err := d.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get([]byte(key)); err != nil {
+			return err
+		}
+		return txn.Delete([]byte(key))
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return errors.WithMessage(&sortedkv.ErrNotFound{Key: key}, "Database.Delete(key) error")
+	}
+	return errors.Wrap(err, "Database.Delete(key) error")
+}
+
+// Batcher interface.
+
+// NewBatch creates a new batch.
+func (d *Database) NewBatch() sortedkv.Batch {
+	return &Batch{db: d.DB}
+}
+
+// Iterateable interface.
+
+// NewIterator creates a new iterator.
+func (d *Database) NewIterator() sortedkv.Iterator {
+	return d.NewIteratorWithRange("", "")
+}
+
+// NewIteratorWithRange creates a new iterator based on a given range.
+func (d *Database) NewIteratorWithRange(start string, end string) sortedkv.Iterator {
+	return newIterator(d.DB, badger.DefaultIteratorOptions, []byte(start), []byte(end))
+}
+
+// NewIteratorWithPrefix creates a new iterator for a given prefix.
+func (d *Database) NewIteratorWithPrefix(prefix string) sortedkv.Iterator {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = []byte(prefix)
+	return newIterator(d.DB, opts, []byte(prefix), nil)
+}
@@ -0,0 +1,19 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package badger implements the key-value database interface using Badger.
+//
+// Iterators run in their own read transaction and thus iterate over a snapshot
+// of the database. They do not block writes, but must be closed.
+package badger // import "perun.network/go-perun/pkg/sortedkv/badger"
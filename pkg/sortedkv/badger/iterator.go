@@ -0,0 +1,117 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package badger
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/log"
+)
+
+// Iterator provides an iterator over a key range.
+type Iterator struct {
+	mu      sync.Mutex
+	txn     *badger.Txn
+	it      *badger.Iterator
+	start   []byte
+	end     []byte // end is the exclusive end of the range, nil if unbounded.
+	started bool
+	err     error
+}
+
+func newIterator(db *badger.DB, opts badger.IteratorOptions, start, end []byte) *Iterator {
+	txn := db.NewTransaction(false)
+	return &Iterator{txn: txn, it: txn.NewIterator(opts), start: start, end: end}
+}
+
+// Next returns true if the iterator has a next element.
+func (i *Iterator) Next() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	// Was the iterator closed?
+	if i.it == nil {
+		return false
+	}
+
+	if i.started {
+		i.it.Next()
+	} else {
+		i.it.Seek(i.start)
+		i.started = true
+	}
+	return i.valid()
+}
+
+// valid returns whether the iterator is at an element.
+func (i *Iterator) valid() bool {
+	return i.it != nil && i.it.Valid() &&
+		(len(i.end) == 0 || bytes.Compare(i.it.Item().Key(), i.end) < 0)
+}
+
+// Key returns the key of the current element.
+func (i *Iterator) Key() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if !i.valid() {
+		log.Panic("Iterator.Key() called on invalid iterator")
+	}
+
+	return string(i.it.Item().Key())
+}
+
+// Value returns the value of the current element.
+func (i *Iterator) Value() string {
+	return string(i.ValueBytes())
+}
+
+// ValueBytes returns the value converted to bytes of the current element.
+func (i *Iterator) ValueBytes() []byte {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if !i.valid() {
+		log.Panic("Iterator.Value() called on invalid iterator")
+	}
+
+	val, err := i.it.Item().ValueCopy(nil)
+	if err != nil && i.err == nil {
+		i.err = errors.Wrap(err, "reading value")
+	}
+	return val
+}
+
+// Close closes this iterator.
+func (i *Iterator) Close() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.it == nil {
+		return nil
+	}
+
+	// The accumulated errors are only returned on the first call to Close()
+	i.it.Close()
+	i.txn.Discard()
+	i.it, i.txn = nil, nil
+	err := i.err
+	i.err = nil
+	return err
+}
@@ -0,0 +1,75 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbolt
+
+import (
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Batch represents a batch and implements the batch interface. All operations
+// of a batch are applied in a single bbolt transaction.
+type Batch struct {
+	db  *bolt.DB
+	ops []op
+}
+
+// op is a single operation of a batch. A nil value is a deletion.
+type op struct {
+	key   []byte
+	value []byte
+}
+
+// Put puts a new value in the batch.
+func (b *Batch) Put(key string, value string) error {
+	return b.PutBytes(key, []byte(value))
+}
+
+// PutBytes puts a new byte slice into the batch.
+func (b *Batch) PutBytes(key string, value []byte) error {
+	b.ops = append(b.ops, op{[]byte(key), append([]byte{}, value...)})
+	return nil
+}
+
+// Delete deletes a value from the batch.
+func (b *Batch) Delete(key string) error {
+	b.ops = append(b.ops, op{[]byte(key), nil})
+	return nil
+}
+
+// Apply applies the batch to the database.
+func (b *Batch) Apply() error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucket)
+		for _, o := range b.ops {
+			var err error
+			if o.value == nil {
+				err = bk.Delete(o.key)
+			} else {
+				err = bk.Put(o.key, o.value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.Wrap(err, "bbolt batch apply error")
+}
+
+// Reset resets the batch.
+func (b *Batch) Reset() {
+	b.ops = nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbolt
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+
+	"perun.network/go-perun/pkg/sortedkv"
+)
+
+// bucket is the name of the bucket that contains all keys.
+var bucket = []byte("sortedkv")
+
+// Database implements the Database interface and stores the values in a bbolt
+// file.
+type Database struct {
+	*bolt.DB
+}
+
+// LoadDatabase opens the bbolt file at path and creates it if it does not
+// exist.
+func LoadDatabase(path string) (*Database, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening bbolt database")
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	}); err != nil {
+		db.Close() // nolint:errcheck,gosec
+		return nil, errors.Wrap(err, "creating bucket")
+	}
+	return &Database{db}, nil
+}
+
+// get returns the value of key in b and whether it exists. The value is only
+// valid during the transaction.
+func get(b *bolt.Bucket, key string) ([]byte, bool) {
+	// Get cannot distinguish missing keys from empty values.
+	k, v := b.Cursor().Seek([]byte(key))
+	if k == nil || !bytes.Equal(k, []byte(key)) {
+		return nil, false
+	}
+	return v, true
+}
+
+// interface Reader
+
+// Has returns true iff the database contains a key.
+func (d *Database) Has(key string) (bool, error) {
+	var has bool
+	err := d.View(func(tx *bolt.Tx) error {
+		_, has = get(tx.Bucket(bucket), key)
+		return nil
+	})
+	return has, errors.Wrap(err, "Database.Has(key) error")
+}
+
+// Get returns the value as string for given key if it is present in the store.
+func (d *Database) Get(key string) (string, error) {
+	val, err := d.GetBytes(key)
+	return string(val), err
+}
+
+// GetBytes returns the value as []byte for given key if it is present in the store.
+func (d *Database) GetBytes(key string) ([]byte, error) {
+	var val []byte
+	err := d.View(func(tx *bolt.Tx) error {
+		v, ok := get(tx.Bucket(bucket), key)
+		if !ok {
+			return &sortedkv.ErrNotFound{Key: key}
+		}
+		val = append([]byte{}, v...)
+		return nil
+	})
+	return val, errors.WithMessage(err, "Database.Get(key) error")
+}
+
+// interface Writer
+
+// Put inserts the given value into the key-value store.
+// If the key is already present, it is overwritten and no error is returned.
+func (d *Database) Put(key string, value string) error {
+	return d.PutBytes(key, []byte(value))
+}
+
+// PutBytes inserts the given value into the key-value store.
+// If the key is already present, it is overwritten and no error is returned.
+func (d *Database) PutBytes(key string, value []byte) error {
+	err := d.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), value)
+	})
+	return errors.Wrap(err, "Database.Put(key, value) error")
+}
+
+// Delete removes the key from the key-value store.
+// If the key is not present, an error is returned.
+func (d *Database) Delete(key string) error {
+	err := d.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if _, ok := get(b, key); !ok {
+			return &sortedkv.ErrNotFound{Key: key}
+		}
+		return errors.WithStack(b.Delete([]byte(key)))
+	})
+	return errors.WithMessage(err, "Database.Delete(key) error")
+}
+
+// Batcher interface.
+
+// NewBatch creates a new batch.
+func (d *Database) NewBatch() sortedkv.Batch {
+	return &Batch{db: d.DB}
+}
+
+// Iterateable interface.
+
+// NewIterator creates a new iterator.
+func (d *Database) NewIterator() sortedkv.Iterator {
+	return d.NewIteratorWithRange("", "")
+}
+
+// NewIteratorWithRange creates a new iterator based on a given range.
+func (d *Database) NewIteratorWithRange(start string, end string) sortedkv.Iterator {
+	return newIterator(d.DB, []byte(start), func(key []byte) bool {
+		return end == "" || string(key) < end
+	})
+}
+
+// NewIteratorWithPrefix creates a new iterator for a given prefix.
+func (d *Database) NewIteratorWithPrefix(prefix string) sortedkv.Iterator {
+	return newIterator(d.DB, []byte(prefix), func(key []byte) bool {
+		return bytes.HasPrefix(key, []byte(prefix))
+	})
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbolt
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+
+	"perun.network/go-perun/log"
+)
+
+// chunkSize is the number of entries an iterator reads per transaction.
+const chunkSize = 64
+
+// Iterator provides an iterator over a key range.
+//
+// An open read transaction prevents bbolt from growing its file, so the
+// iterator does not keep one open. Instead, it reads the entries in chunks,
+// each in its own read transaction. Hence, unlike a snapshot, the iterator sees
+// writes to the part of the range that it has not read yet.
+type Iterator struct {
+	mu      sync.Mutex
+	db      *bolt.DB
+	start   []byte
+	inRange func([]byte) bool
+
+	keys, values [][]byte
+	pos          int  // pos is the index of the current entry in keys.
+	done         bool // done is whether the last chunk was read.
+	err          error
+}
+
+func newIterator(db *bolt.DB, start []byte, inRange func([]byte) bool) *Iterator {
+	return &Iterator{db: db, start: start, inRange: inRange, pos: -1}
+}
+
+// Next returns true if the iterator has a next element.
+func (i *Iterator) Next() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	// Was the iterator closed?
+	if i.db == nil {
+		return false
+	}
+
+	if i.pos+1 < len(i.keys) {
+		i.pos++
+		return true
+	}
+	if i.done {
+		i.pos = len(i.keys)
+		return false
+	}
+	i.readChunk()
+	if len(i.keys) == 0 {
+		return false
+	}
+	i.pos = 0
+	return true
+}
+
+// readChunk reads the next chunk of entries after the last read key.
+func (i *Iterator) readChunk() {
+	var last []byte
+	if len(i.keys) > 0 {
+		last = i.keys[len(i.keys)-1]
+	}
+	i.keys, i.values = i.keys[:0], i.values[:0]
+
+	i.err = errors.Wrap(i.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		var k, v []byte
+		if last == nil {
+			k, v = c.Seek(i.start)
+		} else if k, v = c.Seek(last); k != nil && bytes.Equal(k, last) {
+			k, v = c.Next()
+		}
+		for ; k != nil && i.inRange(k) && len(i.keys) < chunkSize; k, v = c.Next() {
+			i.keys = append(i.keys, append([]byte{}, k...))
+			i.values = append(i.values, append([]byte{}, v...))
+		}
+		i.done = len(i.keys) < chunkSize
+		return nil
+	}), "reading chunk")
+	if i.err != nil {
+		i.keys, i.values, i.done = nil, nil, true
+	}
+}
+
+// valid returns whether the iterator is at an element.
+func (i *Iterator) valid() bool {
+	return i.db != nil && i.pos >= 0 && i.pos < len(i.keys)
+}
+
+// Key returns the key of the current element.
+func (i *Iterator) Key() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if !i.valid() {
+		log.Panic("Iterator.Key() called on invalid iterator")
+	}
+
+	return string(i.keys[i.pos])
+}
+
+// Value returns the value of the current element.
+func (i *Iterator) Value() string {
+	return string(i.ValueBytes())
+}
+
+// ValueBytes returns the value converted to bytes of the current element.
+func (i *Iterator) ValueBytes() []byte {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if !i.valid() {
+		log.Panic("Iterator.Value() called on invalid iterator")
+	}
+
+	return append([]byte{}, i.values[i.pos]...)
+}
+
+// Close closes this iterator.
+func (i *Iterator) Close() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	// The error is only returned on the first call to Close().
+	err := i.err
+	i.db, i.keys, i.values, i.err = nil, nil, nil, nil
+	return err
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encrypted
+
+import (
+	"perun.network/go-perun/pkg/sortedkv"
+)
+
+// Batch is a batch that encrypts all values put into it and, if the Database
+// encrypts keys, all keys.
+type Batch struct {
+	sortedkv.Batch
+	db *Database
+}
+
+// Put encrypts a value and puts it into the batch.
+func (b *Batch) Put(key string, value string) error {
+	return b.PutBytes(key, []byte(value))
+}
+
+// PutBytes encrypts a value and puts it into the batch.
+func (b *Batch) PutBytes(key string, value []byte) error {
+	stored, ciphertext, err := b.db.seal(key, value)
+	if err != nil {
+		return err
+	}
+	return b.Batch.PutBytes(stored, ciphertext)
+}
+
+// Delete puts the deletion of a key into the batch.
+func (b *Batch) Delete(key string) error {
+	return b.db.notFound(key, b.Batch.Delete(b.db.storedKey(key)))
+}
@@ -0,0 +1,204 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encrypted
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/pkg/sortedkv"
+)
+
+// ErrDecrypt is returned when a stored value cannot be decrypted, e.g.,
+// because it was tampered with or because a wrong key was used.
+var ErrDecrypt = errors.New("decrypting value")
+
+// Database is a sortedkv.Database that encrypts all values before writing them
+// to the wrapped database. Optionally, keys are replaced by keyed tags.
+type Database struct {
+	db   sortedkv.Database
+	aead cipher.AEAD
+	keys *keyEncoder // nil if keys are stored in plaintext
+}
+
+// NewDatabase creates a Database that encrypts all values of db with aead.
+// Keys are stored in plaintext.
+func NewDatabase(db sortedkv.Database, aead cipher.AEAD) *Database {
+	return &Database{db: db, aead: aead}
+}
+
+// NewDatabaseWithKeyEncryption creates a Database that encrypts all values of
+// db with aead and replaces all keys by prefix-preserving tags derived from
+// keySecret. keySecret should be independent of the key of aead.
+//
+// Iterators of such a Database read all matching entries when Next is first
+// called, because the order of the tags differs from the order of the keys.
+func NewDatabaseWithKeyEncryption(db sortedkv.Database, aead cipher.AEAD, keySecret []byte) *Database {
+	return &Database{db: db, aead: aead, keys: newKeyEncoder(keySecret)}
+}
+
+// Has returns true if the database contains a key.
+func (d *Database) Has(key string) (bool, error) {
+	return d.db.Has(d.storedKey(key))
+}
+
+// Get returns the decrypted value of a key.
+func (d *Database) Get(key string) (string, error) {
+	value, err := d.GetBytes(key)
+	return string(value), err
+}
+
+// GetBytes returns the decrypted value of a key in bytes.
+func (d *Database) GetBytes(key string) ([]byte, error) {
+	stored := d.storedKey(key)
+	ciphertext, err := d.db.GetBytes(stored)
+	if err != nil {
+		return nil, d.notFound(key, err)
+	}
+	k, value, err := d.open(stored, ciphertext)
+	if err != nil {
+		return nil, err
+	} else if k != key {
+		return nil, errors.WithMessagef(ErrDecrypt, "key %q: stored under wrong key", key)
+	}
+	return value, nil
+}
+
+// Put encrypts a value and saves it under a key.
+func (d *Database) Put(key string, value string) error {
+	return d.PutBytes(key, []byte(value))
+}
+
+// PutBytes encrypts a value and saves it under a key.
+func (d *Database) PutBytes(key string, value []byte) error {
+	stored, ciphertext, err := d.seal(key, value)
+	if err != nil {
+		return err
+	}
+	return d.db.PutBytes(stored, ciphertext)
+}
+
+// Delete deletes a key.
+func (d *Database) Delete(key string) error {
+	return d.notFound(key, d.db.Delete(d.storedKey(key)))
+}
+
+// NewBatch creates a batch that encrypts all values put into it.
+func (d *Database) NewBatch() sortedkv.Batch {
+	return &Batch{Batch: d.db.NewBatch(), db: d}
+}
+
+// NewIterator creates an iterator over the entire keyspace that decrypts all
+// values.
+func (d *Database) NewIterator() sortedkv.Iterator {
+	return d.newIterator(d.db.NewIterator(), nil)
+}
+
+// NewIteratorWithRange creates an iterator over the key range [start, end)
+// that decrypts all values.
+func (d *Database) NewIteratorWithRange(start string, end string) sortedkv.Iterator {
+	if d.keys == nil {
+		return d.newIterator(d.db.NewIteratorWithRange(start, end), nil)
+	}
+	// All keys in the range share the common prefix of start and end.
+	it := d.db.NewIterator()
+	if end != "" {
+		it = d.db.NewIteratorWithPrefix(d.keys.encode(commonPrefix(start, end)))
+	}
+	return d.newIterator(it, func(key string) bool {
+		return key >= start && (end == "" || key < end)
+	})
+}
+
+// NewIteratorWithPrefix creates an iterator over all keys with the given
+// prefix that decrypts all values.
+func (d *Database) NewIteratorWithPrefix(prefix string) sortedkv.Iterator {
+	// The prefix filter excludes keys whose tags share the prefix's tag by
+	// collision.
+	return d.newIterator(d.db.NewIteratorWithPrefix(d.storedKey(prefix)), func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// Close closes the wrapped database.
+func (d *Database) Close() error {
+	return d.db.Close()
+}
+
+// storedKey returns the key under which key is stored in the wrapped database.
+func (d *Database) storedKey(key string) string {
+	if d.keys == nil {
+		return key
+	}
+	return d.keys.encode(key)
+}
+
+// notFound replaces the key of a sortedkv.ErrNotFound by the plaintext key.
+func (d *Database) notFound(key string, err error) error {
+	var nf *sortedkv.ErrNotFound
+	if d.keys != nil && errors.As(err, &nf) {
+		return &sortedkv.ErrNotFound{Key: key}
+	}
+	return err
+}
+
+// seal encrypts a value with a fresh random nonce, using the stored key as
+// additional data. The nonce is prepended to the ciphertext. If keys are
+// encrypted, the plaintext key is encrypted together with the value. seal
+// returns the stored key and the ciphertext.
+func (d *Database) seal(key string, value []byte) (string, []byte, error) {
+	stored := d.storedKey(key)
+	if d.keys != nil {
+		value = wrapKey(key, value)
+	}
+	nonce := make([]byte, d.aead.NonceSize(), d.aead.NonceSize()+len(value)+d.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, errors.Wrap(err, "generating nonce")
+	}
+	return stored, d.aead.Seal(nonce, nonce, value, []byte(stored)), nil
+}
+
+// open decrypts a ciphertext that was stored under the stored key by seal. It
+// returns the plaintext key and the value.
+func (d *Database) open(stored string, ciphertext []byte) (string, []byte, error) {
+	if len(ciphertext) < d.aead.NonceSize() {
+		return "", nil, errors.WithMessagef(ErrDecrypt, "key %q: value too short", stored)
+	}
+	nonce, sealed := ciphertext[:d.aead.NonceSize()], ciphertext[d.aead.NonceSize():]
+	plaintext, err := d.aead.Open(nil, nonce, sealed, []byte(stored))
+	if err != nil {
+		return "", nil, errors.WithMessagef(ErrDecrypt, "key %q", stored)
+	}
+	if d.keys == nil {
+		return stored, plaintext, nil
+	}
+	key, value, err := unwrapKey(plaintext)
+	if err != nil {
+		return "", nil, errors.WithMessagef(ErrDecrypt, "key %q: %v", stored, err)
+	}
+	return key, value, nil
+}
+
+// commonPrefix returns the longest common prefix of a and b.
+func commonPrefix(a, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return a[:i]
+}
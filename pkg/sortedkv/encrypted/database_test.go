@@ -0,0 +1,184 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encrypted
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "perun.network/go-perun/backend/sim"
+	"perun.network/go-perun/channel/persistence/keyvalue"
+	ptest "perun.network/go-perun/channel/persistence/test"
+	"perun.network/go-perun/pkg/sortedkv"
+	"perun.network/go-perun/pkg/sortedkv/leveldb"
+	"perun.network/go-perun/pkg/sortedkv/memorydb"
+	"perun.network/go-perun/pkg/sortedkv/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+func TestDatabase_Generic(t *testing.T) {
+	aead := newAEAD(t)
+	wrappers := []struct {
+		name string
+		wrap func(sortedkv.Database) sortedkv.Database
+	}{
+		{"plain keys", func(db sortedkv.Database) sortedkv.Database {
+			return NewDatabase(db, aead)
+		}},
+		{"encrypted keys", func(db sortedkv.Database) sortedkv.Database {
+			return NewDatabaseWithKeyEncryption(db, aead, []byte("key secret"))
+		}},
+	}
+	for _, w := range wrappers {
+		w := w
+		t.Run(w.name, func(t *testing.T) {
+			t.Run("memorydb", func(t *testing.T) {
+				testGeneric(t, func() sortedkv.Database {
+					return w.wrap(memorydb.NewDatabase())
+				})
+			})
+
+			t.Run("leveldb", func(t *testing.T) {
+				testGeneric(t, func() sortedkv.Database {
+					path, err := ioutil.TempDir("", "perun_testdb_")
+					require.NoError(t, err)
+					t.Cleanup(func() { require.NoError(t, os.RemoveAll(path)) })
+					db, err := leveldb.LoadDatabase(path)
+					require.NoError(t, err)
+					t.Cleanup(func() { assert.NoError(t, db.Close()) })
+					return w.wrap(db)
+				})
+			})
+		})
+	}
+}
+
+func testGeneric(t *testing.T, newDB func() sortedkv.Database) {
+	test.GenericDatabaseTest(t, newDB())
+	test.GenericBatchTest(t, newDB())
+	test.GenericBatchTest(t, sortedkv.NewTable(newDB(), "table"))
+	test.GenericIteratorTest(t, newDB())
+	test.GenericTableTest(t, newDB())
+}
+
+func TestDatabase_Encrypted(t *testing.T) {
+	plain := memorydb.NewDatabase()
+	db := NewDatabase(plain, newAEAD(t))
+	require.NoError(t, db.Put("k1", "secret value 1"))
+	b := db.NewBatch()
+	require.NoError(t, b.Put("k2", "secret value 2"))
+	require.NoError(t, b.Apply())
+
+	it := plain.NewIterator()
+	for it.Next() {
+		assert.False(t, strings.Contains(it.Value(), "secret"), "value stored in plaintext")
+	}
+	require.NoError(t, it.Close())
+
+	t.Run("moved value", func(t *testing.T) {
+		v1, err := plain.Get("k1")
+		require.NoError(t, err)
+		require.NoError(t, plain.Put("k3", v1))
+		_, err = db.Get("k3")
+		assert.True(t, errors.Is(err, ErrDecrypt))
+
+		it := db.NewIterator()
+		assert.True(t, it.Next())
+		assert.Equal(t, "k1", it.Key())
+		assert.Equal(t, "secret value 1", it.Value())
+		assert.True(t, it.Next())
+		assert.False(t, it.Next(), "iterator must stop at undecryptable value")
+		assert.Equal(t, "", it.Key())
+		assert.True(t, errors.Is(it.Close(), ErrDecrypt))
+		require.NoError(t, plain.Delete("k3"))
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		_, err := NewDatabase(plain, newAEAD(t)).Get("k1")
+		assert.True(t, errors.Is(err, ErrDecrypt))
+	})
+}
+
+func TestDatabase_EncryptedKeys(t *testing.T) {
+	plain := memorydb.NewDatabase()
+	db := NewDatabaseWithKeyEncryption(plain, newAEAD(t), []byte("key secret"))
+	require.NoError(t, db.Put("peer:alice:ch1", "v1"))
+	b := db.NewBatch()
+	require.NoError(t, b.Put("peer:alice:ch2", "v2"))
+	require.NoError(t, b.Put("peer:bob:ch3", "v3"))
+	require.NoError(t, b.Apply())
+
+	it := plain.NewIterator()
+	n := 0
+	for ; it.Next(); n++ {
+		for _, s := range []string{"peer", "alice", "bob", "ch"} {
+			assert.NotContains(t, it.Key(), s, "key stored in plaintext")
+			assert.NotContains(t, it.Value(), s, "key stored in plaintext")
+		}
+	}
+	require.NoError(t, it.Close())
+	assert.Equal(t, 3, n)
+
+	t.Run("prefix", func(t *testing.T) {
+		it := db.NewIteratorWithPrefix("peer:alice:")
+		for _, key := range []string{"peer:alice:ch1", "peer:alice:ch2"} {
+			require.True(t, it.Next())
+			assert.Equal(t, key, it.Key())
+		}
+		assert.False(t, it.Next())
+		require.NoError(t, it.Close())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := db.Get("peer:carol")
+		var nf *sortedkv.ErrNotFound
+		require.True(t, errors.As(err, &nf))
+		assert.Equal(t, "peer:carol", nf.Key)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		other := NewDatabaseWithKeyEncryption(plain, db.aead, []byte("other secret"))
+		ok, err := other.Has("peer:alice:ch1")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestDatabase_PersistRestorer(t *testing.T) {
+	db := NewDatabaseWithKeyEncryption(memorydb.NewDatabase(), newAEAD(t), []byte("key secret"))
+	pr, err := keyvalue.OpenPersistRestorer(db)
+	require.NoError(t, err)
+	ptest.GenericPersistRestorerTest(context.Background(), t, pkgtest.Prng(t), pr, 4, 16)
+}
+
+// newAEAD creates an AES-GCM AEAD with a random key.
+func newAEAD(t *testing.T) cipher.AEAD {
+	key := make([]byte, 32)
+	pkgtest.Prng(t).Read(key)
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	return aead
+}
@@ -0,0 +1,37 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package encrypted implements a sortedkv.Database decorator that encrypts all
+// values at rest with an AEAD cipher supplied by the application, e.g.,
+// AES-GCM from crypto/cipher.
+//
+// Each value is sealed with a fresh random nonce, which is stored in front of
+// the ciphertext. The value's key is used as additional data, so that values
+// cannot be moved to other keys unnoticed. Databases created with NewDatabase
+// store keys in plaintext to preserve the ordering and prefix iteration of the
+// wrapped database.
+//
+// Databases created with NewDatabaseWithKeyEncryption replace each byte of a
+// key by an 8-byte HMAC-SHA256 tag of the key up to that byte, so that prefix
+// iteration still works on the wrapped database. The plaintext key is
+// encrypted together with the value. An attacker with access to the wrapped
+// database still learns
+//   - the number of entries and the lengths of all keys and values,
+//   - which keys share a prefix and how long the shared prefix is, e.g., which
+//     entries belong to the same channel or peer, and
+//   - which keys are accessed and when, if the database is observed.
+//
+// Iterating such a database reads all entries in the iterated range at once
+// because the tags are not ordered like the keys.
+package encrypted // import "perun.network/go-perun/pkg/sortedkv/encrypted"
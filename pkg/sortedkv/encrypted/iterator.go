@@ -0,0 +1,124 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encrypted
+
+import (
+	"sort"
+
+	"perun.network/go-perun/pkg/sortedkv"
+)
+
+// Iterator is an iterator that decrypts the values of the wrapped iterator. If
+// a value cannot be decrypted, Next returns false and Close returns the error.
+//
+// If the Database encrypts keys, the Iterator decrypts and sorts all entries of
+// the wrapped iterator when Next is first called.
+type Iterator struct {
+	it     sortedkv.Iterator
+	db     *Database
+	filter func(key string) bool // filters decrypted keys, may be nil
+
+	entries []entry // decrypted and sorted entries if keys are encrypted
+	loaded  bool    // whether entries were loaded
+
+	key   string
+	value []byte
+	err   error
+}
+
+type entry struct {
+	key   string
+	value []byte
+}
+
+func (d *Database) newIterator(it sortedkv.Iterator, filter func(string) bool) *Iterator {
+	return &Iterator{it: it, db: d, filter: filter}
+}
+
+// Next moves the iterator to the next key/value pair and decrypts the value.
+func (i *Iterator) Next() bool {
+	i.key, i.value = "", nil
+	if i.err != nil {
+		return false
+	}
+	if i.db.keys != nil {
+		return i.nextSorted()
+	}
+	if !i.it.Next() {
+		return false
+	}
+	i.key, i.value, i.err = i.db.open(i.it.Key(), i.it.ValueBytes())
+	return i.err == nil
+}
+
+// nextSorted moves the iterator to the next of the sorted entries, loading
+// them first if necessary.
+func (i *Iterator) nextSorted() bool {
+	if !i.loaded {
+		i.loaded = true
+		if i.err = i.load(); i.err != nil {
+			return false
+		}
+	} else if len(i.entries) > 0 {
+		i.entries = i.entries[1:]
+	}
+	if len(i.entries) == 0 {
+		return false
+	}
+	i.key, i.value = i.entries[0].key, i.entries[0].value
+	return true
+}
+
+// load decrypts all entries of the wrapped iterator that pass the filter and
+// sorts them by key.
+func (i *Iterator) load() error {
+	for i.it.Next() {
+		key, value, err := i.db.open(i.it.Key(), i.it.ValueBytes())
+		if err != nil {
+			return err
+		}
+		if i.filter == nil || i.filter(key) {
+			i.entries = append(i.entries, entry{key: key, value: value})
+		}
+	}
+	sort.Slice(i.entries, func(a, b int) bool { return i.entries[a].key < i.entries[b].key })
+	return nil
+}
+
+// Key returns the key of the current key/value pair, or "" if done.
+func (i *Iterator) Key() string {
+	return i.key
+}
+
+// Value returns the decrypted value of the current key/value pair, or "" if
+// done.
+func (i *Iterator) Value() string {
+	return string(i.value)
+}
+
+// ValueBytes returns the decrypted value of the current key/value pair, or nil
+// if done.
+func (i *Iterator) ValueBytes() []byte {
+	return i.value
+}
+
+// Close closes the wrapped iterator and returns any decryption error.
+func (i *Iterator) Close() error {
+	i.entries, i.loaded = nil, true
+	if err := i.it.Close(); err != nil {
+		return err
+	}
+	return i.err
+}
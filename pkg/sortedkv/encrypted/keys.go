@@ -0,0 +1,71 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encrypted
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+
+	"github.com/pkg/errors"
+)
+
+// tagLen is the length of the tag that replaces each byte of an encrypted key.
+const tagLen = 8
+
+// keyEncoder maps keys to tags such that the tag of a prefix of a key is a
+// prefix of the tag of the key. Each byte of a key is replaced by the first
+// tagLen bytes of the HMAC-SHA256 of all bytes of the key up to and including
+// it. The mapping cannot be inverted, so the key is stored together with the
+// value, see Database.seal.
+type keyEncoder struct {
+	newMAC func() hash.Hash
+}
+
+func newKeyEncoder(secret []byte) *keyEncoder {
+	secret = append([]byte(nil), secret...)
+	return &keyEncoder{newMAC: func() hash.Hash { return hmac.New(sha256.New, secret) }}
+}
+
+// encode returns the tag of key.
+func (e *keyEncoder) encode(key string) string {
+	mac := e.newMAC()
+	tag := make([]byte, 0, tagLen*len(key))
+	var sum []byte
+	for i := 0; i < len(key); i++ {
+		mac.Write([]byte{key[i]}) // nolint: errcheck, gosec
+		sum = mac.Sum(sum[:0])
+		tag = append(tag, sum[:tagLen]...)
+	}
+	return string(tag)
+}
+
+// wrapKey prepends the length-prefixed key to value.
+func wrapKey(key string, value []byte) []byte {
+	buf := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(key)+len(value))
+	buf = buf[:binary.PutUvarint(buf, uint64(len(key)))]
+	return append(append(buf, key...), value...)
+}
+
+// unwrapKey splits a plaintext that was created with wrapKey into key and
+// value.
+func unwrapKey(plaintext []byte) (string, []byte, error) {
+	n, l := binary.Uvarint(plaintext)
+	if l <= 0 || uint64(len(plaintext)-l) < n {
+		return "", nil, errors.New("malformed key")
+	}
+	return string(plaintext[l : l+int(n)]), plaintext[l+int(n):], nil
+}
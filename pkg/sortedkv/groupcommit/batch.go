@@ -0,0 +1,56 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groupcommit
+
+// Batch collects operations that are applied atomically in a group commit.
+type Batch struct {
+	db  *Database
+	ops []op
+}
+
+// Put puts a value into the batch.
+func (b *Batch) Put(key string, value string) error {
+	return b.PutBytes(key, []byte(value))
+}
+
+// PutBytes puts a value into the batch. The value is copied.
+func (b *Batch) PutBytes(key string, value []byte) error {
+	b.ops = append(b.ops, op{key: key, value: append([]byte(nil), value...)})
+	return nil
+}
+
+// Delete adds the deletion of a key to the batch.
+func (b *Batch) Delete(key string) error {
+	b.ops = append(b.ops, op{key: key, delete: true})
+	return nil
+}
+
+// Apply adds the batch to the next group commit of the database and returns
+// before it is committed.
+func (b *Batch) Apply() error {
+	return b.db.enqueue(b.ops)
+}
+
+// Commit adds the batch to the next group commit and returns after it is
+// committed. Unlike Flush, it only returns the error of this batch, so that
+// the failed writes of other writers do not affect it.
+func (b *Batch) Commit() error {
+	return b.db.commit(b.ops)
+}
+
+// Reset resets the batch.
+func (b *Batch) Reset() {
+	b.ops = nil
+}
@@ -0,0 +1,327 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groupcommit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/pkg/sortedkv"
+)
+
+// ErrClosed is returned when writing to a closed Database.
+var ErrClosed = errors.New("database closed")
+
+// Database is a sortedkv.Database that coalesces writes into group commits.
+// Writes return before they are committed, Flush waits for them. Reads first
+// flush all pending writes and are then forwarded to the wrapped database.
+type Database struct {
+	sortedkv.Database
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending []*write
+	closed  bool
+	err     error // first error of a write without waiter since the last Flush
+
+	kick    chan struct{}
+	quit    chan struct{}
+	stopped chan struct{}
+}
+
+// write is a set of operations that is applied atomically. Its result is sent
+// on done if done is not nil. Otherwise, an error is recorded for the next
+// Flush.
+type write struct {
+	ops  []op
+	done chan error
+}
+
+type op struct {
+	key    string
+	value  []byte
+	delete bool
+}
+
+// NewDatabase creates a Database that writes to db in group commits. If
+// interval is zero, a group is committed as soon as the previous group commit
+// finished. Otherwise, writes are collected for the interval before they are
+// committed, which increases the group size at the expense of latency.
+func NewDatabase(db sortedkv.Database, interval time.Duration) *Database {
+	d := &Database{
+		Database: db,
+		interval: interval,
+		kick:     make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// Has waits for all pending writes and returns true if the database contains a
+// key.
+func (d *Database) Has(key string) (bool, error) {
+	if err := d.wait(); err != nil {
+		return false, err
+	}
+	return d.Database.Has(key)
+}
+
+// Get waits for all pending writes and returns the value of a key.
+func (d *Database) Get(key string) (string, error) {
+	if err := d.wait(); err != nil {
+		return "", err
+	}
+	return d.Database.Get(key)
+}
+
+// GetBytes waits for all pending writes and returns the value of a key in bytes.
+func (d *Database) GetBytes(key string) ([]byte, error) {
+	if err := d.wait(); err != nil {
+		return nil, err
+	}
+	return d.Database.GetBytes(key)
+}
+
+// NewIterator waits for all pending writes and creates an iterator over the
+// entire keyspace. If the database is closed, the iterator is empty and its
+// Close returns ErrClosed.
+func (d *Database) NewIterator() sortedkv.Iterator {
+	if err := d.wait(); err != nil {
+		return &errIterator{err: err}
+	}
+	return d.Database.NewIterator()
+}
+
+// NewIteratorWithRange waits for all pending writes and creates an iterator over
+// the key range [start, end).
+func (d *Database) NewIteratorWithRange(start string, end string) sortedkv.Iterator {
+	if err := d.wait(); err != nil {
+		return &errIterator{err: err}
+	}
+	return d.Database.NewIteratorWithRange(start, end)
+}
+
+// NewIteratorWithPrefix waits for all pending writes and creates an iterator
+// over all keys with the given prefix.
+func (d *Database) NewIteratorWithPrefix(prefix string) sortedkv.Iterator {
+	if err := d.wait(); err != nil {
+		return &errIterator{err: err}
+	}
+	return d.Database.NewIteratorWithPrefix(prefix)
+}
+
+// Put adds saving a value under a key to the next group commit. It returns
+// before the value is committed.
+func (d *Database) Put(key string, value string) error {
+	return d.enqueue([]op{{key: key, value: []byte(value)}})
+}
+
+// PutBytes adds saving a value under a key to the next group commit. It
+// returns before the value is committed.
+func (d *Database) PutBytes(key string, value []byte) error {
+	return d.enqueue([]op{{key: key, value: append([]byte(nil), value...)}})
+}
+
+// Delete deletes a key. It waits for all pending writes to be committed and
+// then deletes the key directly, so that an error is returned if the key does
+// not exist.
+func (d *Database) Delete(key string) error {
+	if err := d.wait(); err != nil {
+		return err
+	}
+	return d.Database.Delete(key)
+}
+
+// NewBatch creates a new batch. Applying it adds its operations to the next
+// group commit and returns before they are committed.
+func (d *Database) NewBatch() sortedkv.Batch {
+	return &Batch{db: d}
+}
+
+// Flush returns after all writes that were started before were committed. It
+// returns the first error of any write without waiter, i.e., of any Put or
+// Batch.Apply, since the last Flush or Close, which may have been started by
+// another writer. Reads wait for pending writes like Flush, but they leave
+// the errors to the next Flush.
+func (d *Database) Flush() error {
+	if err := d.wait(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err := d.err
+	d.err = nil
+	return err
+}
+
+// wait returns after all writes that were started before were committed.
+func (d *Database) wait() error {
+	return d.commit(nil)
+}
+
+// Close commits all pending writes and closes the wrapped database. It returns
+// the first error of any write without waiter since the last Flush.
+func (d *Database) Close() error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return ErrClosed
+	}
+	d.closed = true
+	close(d.quit)
+	d.mu.Unlock()
+
+	<-d.stopped
+	if err := d.Database.Close(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+// enqueue adds the operations to the next group commit without waiting for
+// their result.
+func (d *Database) enqueue(ops []op) error {
+	return d.add(&write{ops: ops})
+}
+
+// commit adds the operations to the next group commit and returns their result
+// after they are committed.
+func (d *Database) commit(ops []op) error {
+	w := &write{ops: ops, done: make(chan error, 1)}
+	if err := d.add(w); err != nil {
+		return err
+	}
+	return <-w.done
+}
+
+// add adds the write to the next group commit and notifies the committer.
+func (d *Database) add(w *write) error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return ErrClosed
+	}
+	d.pending = append(d.pending, w)
+	d.mu.Unlock()
+
+	select {
+	case d.kick <- struct{}{}:
+	default: // the committer is already notified
+	}
+	return nil
+}
+
+// run commits the pending writes whenever it is notified, until the Database
+// is closed.
+func (d *Database) run() {
+	defer close(d.stopped)
+	for {
+		select {
+		case <-d.kick:
+		case <-d.quit:
+			d.commitGroup(d.takePending())
+			return
+		}
+		if d.interval > 0 {
+			timer := time.NewTimer(d.interval)
+			select {
+			case <-timer.C:
+			case <-d.quit:
+				timer.Stop()
+			}
+		}
+		d.commitGroup(d.takePending())
+	}
+}
+
+func (d *Database) takePending() []*write {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	group := d.pending
+	d.pending = nil
+	return group
+}
+
+// commitGroup applies all writes of the group in a single batch. If that
+// fails, the writes are applied one by one, so that only the failing writes
+// are lost. Each write's result is sent to its waiter or recorded for the next
+// Flush.
+func (d *Database) commitGroup(group []*write) {
+	if len(group) == 0 {
+		return
+	}
+	if err := d.apply(group...); err == nil {
+		for _, w := range group {
+			d.done(w, nil)
+		}
+		return
+	}
+	for _, w := range group {
+		d.done(w, d.apply(w))
+	}
+}
+
+// done reports the result of a write.
+func (d *Database) done(w *write, err error) {
+	if w.done != nil {
+		w.done <- err
+	} else if err != nil {
+		d.setErr(err)
+	}
+}
+
+func (d *Database) setErr(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+// apply applies the writes to the wrapped database in one batch.
+func (d *Database) apply(writes ...*write) error {
+	b := d.Database.NewBatch()
+	for _, w := range writes {
+		for _, o := range w.ops {
+			var err error
+			if o.delete {
+				err = b.Delete(o.key)
+			} else {
+				err = b.PutBytes(o.key, o.value)
+			}
+			if err != nil {
+				return errors.WithMessage(err, "adding to batch")
+			}
+		}
+	}
+	return b.Apply()
+}
+
+// errIterator is an empty iterator that returns an error on Close.
+type errIterator struct {
+	err error
+}
+
+func (i *errIterator) Next() bool         { return false }
+func (i *errIterator) Key() string        { return "" }
+func (i *errIterator) Value() string      { return "" }
+func (i *errIterator) ValueBytes() []byte { return nil }
+func (i *errIterator) Close() error       { return i.err }
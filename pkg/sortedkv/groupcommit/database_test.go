@@ -0,0 +1,169 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groupcommit
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/pkg/sortedkv"
+	"perun.network/go-perun/pkg/sortedkv/memorydb"
+	"perun.network/go-perun/pkg/sortedkv/test"
+)
+
+func TestDatabase_Generic(t *testing.T) {
+	for _, interval := range []time.Duration{0, time.Millisecond} {
+		t.Run(fmt.Sprint(interval), func(t *testing.T) {
+			test.GenericDatabaseTest(t, NewDatabase(memorydb.NewDatabase(), interval))
+			test.GenericBatchTest(t, NewDatabase(memorydb.NewDatabase(), interval))
+			test.GenericBatchTest(t, sortedkv.NewTable(NewDatabase(memorydb.NewDatabase(), interval), "table"))
+			test.GenericIteratorTest(t, NewDatabase(memorydb.NewDatabase(), interval))
+			test.GenericTableTest(t, NewDatabase(memorydb.NewDatabase(), interval))
+		})
+	}
+}
+
+func TestDatabase_GroupCommit(t *testing.T) {
+	const numWriters = 50
+	counter := &countingDatabase{Database: memorydb.NewDatabase()}
+	db := NewDatabase(counter, 10*time.Millisecond)
+
+	var wg sync.WaitGroup
+	wg.Add(numWriters)
+	for i := 0; i < numWriters; i++ {
+		go func(i int) {
+			defer wg.Done()
+			b := db.NewBatch()
+			assert.NoError(t, b.Put(fmt.Sprintf("k%02d", i), "v"))
+			assert.NoError(t, b.Put(fmt.Sprintf("l%02d", i), "v"))
+			assert.NoError(t, b.Apply())
+			assert.NoError(t, db.Flush())
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Less(t, int(atomic.LoadInt32(&counter.applies)), numWriters)
+	dbtest := test.DatabaseTest{T: t, Database: db}
+	for i := 0; i < numWriters; i++ {
+		dbtest.MustGetEqual(fmt.Sprintf("k%02d", i), "v")
+		dbtest.MustGetEqual(fmt.Sprintf("l%02d", i), "v")
+	}
+	require.NoError(t, db.Close())
+}
+
+func TestDatabase_Async(t *testing.T) {
+	// Block the committer, so that all writes are pending.
+	counter := &countingDatabase{Database: memorydb.NewDatabase(), block: make(chan struct{})}
+	db := NewDatabase(counter, 0)
+	require.NoError(t, db.Put("first", "v"))
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&counter.applies) == 1 },
+		time.Second, time.Millisecond)
+
+	b := db.NewBatch()
+	require.NoError(t, b.Put("k1", "v1"))
+	require.NoError(t, b.Delete("missing"))
+	require.NoError(t, b.Apply(), "writes must not wait for the commit")
+	require.NoError(t, db.Put("k2", "v2"))
+
+	flushed := make(chan error, 1)
+	go func() { flushed <- db.Flush() }()
+	select {
+	case <-flushed:
+		t.Fatal("Flush returned before the writes were committed")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(counter.block)
+	assert.Error(t, <-flushed, "Flush must return the error of the failed batch")
+	require.NoError(t, db.Flush())
+
+	dbtest := test.DatabaseTest{T: t, Database: db}
+	dbtest.MustNotHave("k1")
+	dbtest.MustGetEqual("k2", "v2")
+	require.NoError(t, db.Close())
+
+	assert.True(t, errors.Is(db.Put("k3", "v3"), ErrClosed))
+	assert.True(t, errors.Is(db.Close(), ErrClosed))
+}
+
+func TestDatabase_Commit(t *testing.T) {
+	// Block the committer, so that all writes are committed in one group.
+	counter := &countingDatabase{Database: memorydb.NewDatabase(), block: make(chan struct{})}
+	db := NewDatabase(counter, 0)
+	require.NoError(t, db.Put("first", "v"))
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&counter.applies) == 1 },
+		time.Second, time.Millisecond)
+
+	failing := db.NewBatch()
+	require.NoError(t, failing.Delete("missing"))
+	require.NoError(t, failing.Apply())
+	commit := func(b sortedkv.Batch) <-chan error {
+		res := make(chan error, 1)
+		go func() { res <- b.(*Batch).Commit() }()
+		return res
+	}
+	ok := db.NewBatch()
+	require.NoError(t, ok.Put("k", "v"))
+	okRes := commit(ok)
+	bad := db.NewBatch()
+	require.NoError(t, bad.Delete("missing2"))
+	badRes := commit(bad)
+	require.Eventually(t, func() bool {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		return len(db.pending) == 3
+	}, time.Second, time.Millisecond)
+	close(counter.block)
+
+	assert.NoError(t, <-okRes, "Commit must not return errors of other writes")
+	assert.Error(t, <-badRes, "Commit must return the error of its batch")
+	has, err := db.Has("k")
+	require.NoError(t, err)
+	assert.True(t, has)
+	assert.Error(t, db.Flush(), "reads must not clear the errors of queued writes")
+	assert.NoError(t, db.Flush())
+	require.NoError(t, db.Close())
+}
+
+// countingDatabase counts the applied batches. If block is not nil, batches
+// are only applied after it is closed.
+type countingDatabase struct {
+	sortedkv.Database
+	applies int32
+	block   chan struct{}
+}
+
+func (d *countingDatabase) NewBatch() sortedkv.Batch {
+	return &countingBatch{Batch: d.Database.NewBatch(), db: d}
+}
+
+type countingBatch struct {
+	sortedkv.Batch
+	db *countingDatabase
+}
+
+func (b *countingBatch) Apply() error {
+	atomic.AddInt32(&b.db.applies, 1)
+	if b.db.block != nil {
+		<-b.db.block
+	}
+	return b.Batch.Apply()
+}
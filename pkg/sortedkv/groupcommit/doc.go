@@ -0,0 +1,30 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package groupcommit implements a sortedkv.Database decorator that coalesces
+// writes into group commits.
+//
+// Writes and batches are queued and return immediately. A background committer
+// writes all queued writes to the wrapped database in a single batch, either
+// as soon as the previous group commit finished or after the commit interval.
+// Flush waits until all previous writes are committed, and reads flush before
+// they are forwarded to the wrapped database. Durability is therefore only
+// guaranteed after Flush returned and if the wrapped database syncs its
+// batches, like a leveldb.Database with Sync set. Batch.Commit waits for a
+// single batch and returns its own result. Errors of other queued writes are
+// returned by the next Flush.
+//
+// The keyvalue.AsyncPersistRestorer uses a Database to only wait for the sync
+// when a channel update is enabled.
+package groupcommit // import "perun.network/go-perun/pkg/sortedkv/groupcommit"
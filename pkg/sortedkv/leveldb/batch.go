@@ -17,12 +17,14 @@ package leveldb
 import (
 	"github.com/pkg/errors"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
 )
 
 // Batch represents a batch and implements the batch interface.
 type Batch struct {
 	*leveldb.Batch
-	db *leveldb.DB
+	db   *leveldb.DB
+	opts *opt.WriteOptions
 }
 
 // Put puts a new value in the batch.
@@ -44,7 +46,7 @@ func (b *Batch) Delete(key string) error {
 
 // Apply applies the batch to the database.
 func (b *Batch) Apply() error {
-	err := b.db.Write(b.Batch, nil)
+	err := b.db.Write(b.Batch, b.opts)
 	return errors.Wrap(err, "leveldb batch apply error")
 }
 
@@ -19,6 +19,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
 	"github.com/syndtr/goleveldb/leveldb/util"
 
 	"perun.network/go-perun/pkg/sortedkv"
@@ -28,6 +29,11 @@ import (
 type Database struct {
 	*leveldb.DB
 	path string
+
+	// Sync makes all writes flush the operating system's buffers to disk before
+	// they return, so that they survive a machine crash. Writes are much slower
+	// if it is set.
+	Sync bool
 }
 
 // LoadDatabase creates a new, empty Database.
@@ -39,8 +45,8 @@ func LoadDatabase(path string) (*Database, error) {
 	}
 
 	return &Database{
-		db,
-		path,
+		DB:   db,
+		path: path,
 	}, nil
 }
 
@@ -75,7 +81,7 @@ func (d *Database) Put(key string, value string) error {
 // PutBytes inserts the given value into the key-value store.
 // If the key is already present, it is overwritten and no error is returned.
 func (d *Database) PutBytes(key string, value []byte) error {
-	err := d.DB.Put([]byte(key), value, nil)
+	err := d.DB.Put([]byte(key), value, d.writeOptions())
 	return errors.Wrap(err, "Database.Put(key, value) error")
 }
 
@@ -92,7 +98,7 @@ func (d *Database) Delete(key string) error {
 		return errors.New("Database.Delete(key) error")
 	}
 
-	err = d.DB.Delete([]byte(key), nil)
+	err = d.DB.Delete([]byte(key), d.writeOptions())
 	return errors.Wrap(err, "Database.Delete(key) error")
 }
 
@@ -100,7 +106,12 @@ func (d *Database) Delete(key string) error {
 
 // NewBatch creates a new batch.
 func (d *Database) NewBatch() sortedkv.Batch {
-	return &Batch{&leveldb.Batch{}, d.DB}
+	return &Batch{&leveldb.Batch{}, d.DB, d.writeOptions()}
+}
+
+// writeOptions returns the write options for the Sync setting.
+func (d *Database) writeOptions() *opt.WriteOptions {
+	return &opt.WriteOptions{Sync: d.Sync}
 }
 
 // Iterateable interface.
@@ -16,6 +16,8 @@ package memorydb
 
 import (
 	"github.com/pkg/errors"
+
+	"perun.network/go-perun/pkg/sortedkv"
 )
 
 // Batch represents a batch and implements the batch interface.
@@ -44,20 +46,23 @@ func (b *Batch) Delete(key string) error {
 	return nil
 }
 
-// Apply applies the batch to the database.
+// Apply applies the batch to the database atomically. If a deleted key is not
+// in the database, nothing is applied.
 func (b *Batch) Apply() error {
-	for key, value := range b.writes {
-		err := b.db.Put(key, value)
-		if err != nil {
-			return errors.Wrap(err, "failed to put entry")
+	b.db.mutex.Lock()
+	defer b.db.mutex.Unlock()
+
+	for key := range b.deletes {
+		if _, has := b.db.data[key]; !has {
+			return errors.Wrap(&sortedkv.ErrNotFound{Key: key}, "failed to delete entry")
 		}
 	}
 
+	for key, value := range b.writes {
+		b.db.data[key] = value
+	}
 	for key := range b.deletes {
-		err := b.db.Delete(key)
-		if err != nil {
-			return errors.Wrap(err, "failed to delete entry")
-		}
+		delete(b.db.data, key)
 	}
 	return nil
 }
@@ -0,0 +1,135 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memorydb
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/pkg/sortedkv"
+)
+
+// ErrCrashed is returned by all writes to a CrashingDatabase from the crash
+// point on.
+var ErrCrashed = errors.New("simulated crash")
+
+// CrashingDatabase is an in-memory Database that simulates a crash at a given
+// write. Each Put, PutBytes, Delete and batch Apply counts as one write. The
+// crashing write and all following writes fail with ErrCrashed without
+// touching the data. It is meant for testing the crash consistency of users
+// of a Database.
+type CrashingDatabase struct {
+	*Database
+
+	mutex   sync.Mutex
+	crashAt int // write at which to crash, never if <= 0
+	writes  int // number of attempted writes
+}
+
+// NewCrashingDatabase creates a new, empty CrashingDatabase that crashes at the
+// crashAt-th write. If crashAt is not positive, it never crashes.
+func NewCrashingDatabase(crashAt int) *CrashingDatabase {
+	return &CrashingDatabase{
+		Database: NewDatabase().(*Database),
+		crashAt:  crashAt,
+	}
+}
+
+// Writes returns the number of attempted writes, including failed ones.
+func (d *CrashingDatabase) Writes() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.writes
+}
+
+// Crashed returns whether the database crashed.
+func (d *CrashingDatabase) Crashed() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.crashed()
+}
+
+func (d *CrashingDatabase) crashed() bool {
+	return d.crashAt > 0 && d.writes >= d.crashAt
+}
+
+// Recover returns a new Database containing a copy of the data that was
+// written before the crash.
+func (d *CrashingDatabase) Recover() sortedkv.Database {
+	d.Database.mutex.RLock()
+	defer d.Database.mutex.RUnlock()
+
+	data := make(map[string]string, len(d.Database.data))
+	for k, v := range d.Database.data {
+		data[k] = v
+	}
+	return FromData(data)
+}
+
+// write counts a write and returns ErrCrashed if it is the crashing write or
+// a later one.
+func (d *CrashingDatabase) write() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.writes++
+	if d.crashed() {
+		return errors.WithStack(ErrCrashed)
+	}
+	return nil
+}
+
+// Put saves a value under a key, unless the database crashed.
+func (d *CrashingDatabase) Put(key, value string) error {
+	if err := d.write(); err != nil {
+		return err
+	}
+	return d.Database.Put(key, value)
+}
+
+// PutBytes saves a value under a key, unless the database crashed.
+func (d *CrashingDatabase) PutBytes(key string, value []byte) error {
+	if err := d.write(); err != nil {
+		return err
+	}
+	return d.Database.PutBytes(key, value)
+}
+
+// Delete deletes a key, unless the database crashed.
+func (d *CrashingDatabase) Delete(key string) error {
+	if err := d.write(); err != nil {
+		return err
+	}
+	return d.Database.Delete(key)
+}
+
+// NewBatch creates a new batch whose Apply counts as a single write.
+func (d *CrashingDatabase) NewBatch() sortedkv.Batch {
+	return &crashingBatch{Batch: d.Database.NewBatch(), db: d}
+}
+
+type crashingBatch struct {
+	sortedkv.Batch
+	db *CrashingDatabase
+}
+
+// Apply applies the batch atomically, unless the database crashed.
+func (b *crashingBatch) Apply() error {
+	if err := b.db.write(); err != nil {
+		return err
+	}
+	return b.Batch.Apply()
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memorydb
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"perun.network/go-perun/pkg/sortedkv"
+	"perun.network/go-perun/pkg/sortedkv/test"
+)
+
+func TestCrashingDatabase(t *testing.T) {
+	t.Run("Generic Database test", func(t *testing.T) {
+		test.GenericDatabaseTest(t, NewCrashingDatabase(0))
+	})
+
+	t.Run("Generic Batch test", func(t *testing.T) {
+		test.GenericBatchTest(t, NewCrashingDatabase(0))
+	})
+
+	t.Run("crash", func(t *testing.T) {
+		db := NewCrashingDatabase(3)
+		require.NoError(t, db.Put("k1", "v1"))
+		b := db.NewBatch()
+		require.NoError(t, b.Put("k2", "v2"))
+		require.NoError(t, b.Put("k3", "v3"))
+		require.NoError(t, b.Apply())
+		assert.False(t, db.Crashed())
+
+		assert.True(t, errors.Is(db.Put("k4", "v4"), ErrCrashed))
+		assert.True(t, db.Crashed())
+		assert.True(t, errors.Is(db.Delete("k1"), ErrCrashed))
+		b = db.NewBatch()
+		require.NoError(t, b.Put("k5", "v5"))
+		assert.True(t, errors.Is(b.Apply(), ErrCrashed))
+		assert.Equal(t, 5, db.Writes())
+
+		dbtest := test.DatabaseTest{T: t, Database: db.Recover()}
+		dbtest.MustGetEqual("k1", "v1")
+		dbtest.MustGetEqual("k2", "v2")
+		dbtest.MustGetEqual("k3", "v3")
+		for _, key := range []string{"k4", "k5"} {
+			has, err := dbtest.Database.Has(key)
+			require.NoError(t, err)
+			assert.False(t, has)
+		}
+	})
+}
+
+func TestBatch_Apply_Atomic(t *testing.T) {
+	db := NewDatabase()
+	b := db.NewBatch()
+	require.NoError(t, b.Put("k1", "v1"))
+	require.NoError(t, b.Delete("missing"))
+
+	var notFound *sortedkv.ErrNotFound
+	assert.True(t, errors.As(b.Apply(), &notFound))
+	has, err := db.Has("k1")
+	require.NoError(t, err)
+	assert.False(t, has, "failed batch must not be applied partially")
+}
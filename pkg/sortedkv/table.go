@@ -68,7 +68,7 @@ func (t *table) Delete(key string) error {
 
 // NewBatch creates a new batch.
 func (t *table) NewBatch() Batch {
-	return &tableBatch{t.Database.NewBatch(), t.prefix}
+	return NewTableBatch(t.Database.NewBatch(), t.prefix)
 }
 
 // NewIterator creates a new table iterator.
@@ -21,6 +21,14 @@ type tableBatch struct {
 	prefix string
 }
 
+// NewTableBatch creates a view on the given batch that prefixes all keys, like
+// a Table does. Several table batches can be created on the same batch to
+// write to several tables in one batch, which is applied atomically if the
+// underlying batch is. Apply and Reset act on the whole underlying batch.
+func NewTableBatch(b Batch, prefix string) Batch {
+	return &tableBatch{b, prefix}
+}
+
 func (b *tableBatch) pkey(key string) string {
 	return b.prefix + key
 }
@@ -0,0 +1,38 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchtower
+
+import "time"
+
+const (
+	// defaultRetryDelay is the delay before the first retry of a failed
+	// replication or subscription.
+	defaultRetryDelay = time.Second
+	// maxRetryDelay is the maximal delay between retries.
+	maxRetryDelay = time.Minute
+)
+
+// backoff returns the delay before the next retry after the given number of
+// consecutive failures. The delay starts at base and doubles with every
+// failure, up to maxRetryDelay.
+func backoff(base time.Duration, failures int) time.Duration {
+	for i := 0; i < failures && base < maxRetryDelay; i++ {
+		base *= 2
+	}
+	if base > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return base
+}
@@ -0,0 +1,33 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package watchtower replicates channel states to remote watchers that refute
+// outdated registrations on behalf of a client.
+//
+// A Replicator is a persistence.PersistRestorer decorator that forwards every
+// fully signed current state of a channel to a set of watchers over a
+// wire.Bus. The replication is asynchronous and retried until it succeeds, so
+// an unreachable watcher does not stall the client; Flush waits for it. States
+// that are still queued when the client stops are queued again by Resync.
+//
+// A Watcher receives these states, stores the latest state of every channel
+// and watches the Adjudicator. If an older state is registered, it registers
+// the latest state, so that a client's channels stay safe while the client is
+// offline or its machine is lost. Failed Adjudicator subscriptions are retried.
+//
+// The watchers only accept states that are signed by all channel participants
+// and sent by one of their configured replicators. Use a wire.Bus that
+// authenticates peers, like the net.Bus, so that neither the senders nor the
+// acknowledgements can be forged.
+package watchtower // import "perun.network/go-perun/watchtower"
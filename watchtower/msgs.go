@@ -0,0 +1,93 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchtower
+
+import (
+	"io"
+
+	"perun.network/go-perun/channel"
+	perunio "perun.network/go-perun/pkg/io"
+	"perun.network/go-perun/wire"
+)
+
+func init() {
+	wire.RegisterDecoder(wire.WatchUpdate,
+		func(r io.Reader) (wire.Msg, error) {
+			var m msgWatchUpdate
+			return &m, m.Decode(r)
+		})
+	wire.RegisterDecoder(wire.WatchUpdateAck,
+		func(r io.Reader) (wire.Msg, error) {
+			var m msgWatchUpdateAck
+			return &m, m.Decode(r)
+		})
+}
+
+type (
+	// msgWatchUpdate is the wire message that forwards a fully signed state of
+	// a channel to a watcher.
+	msgWatchUpdate struct {
+		Params channel.Params
+		Tx     channel.Transaction
+	}
+
+	// msgWatchUpdateAck is the wire message sent as a reply to a WatchUpdate.
+	// It references the channel ID and version of the update.
+	msgWatchUpdateAck struct {
+		// ChannelID is the channel ID.
+		ChannelID channel.ID
+		// Version is the version of the acknowledged state.
+		Version uint64
+		// Reason states why the watcher rejected the state. It is empty if the
+		// state was accepted.
+		Reason string
+	}
+)
+
+// Type returns this message's type: WatchUpdate.
+func (*msgWatchUpdate) Type() wire.Type {
+	return wire.WatchUpdate
+}
+
+// Type returns this message's type: WatchUpdateAck.
+func (*msgWatchUpdateAck) Type() wire.Type {
+	return wire.WatchUpdateAck
+}
+
+func (m msgWatchUpdate) Encode(w io.Writer) error {
+	return perunio.Encode(w, &m.Params, m.Tx)
+}
+
+func (m *msgWatchUpdate) Decode(r io.Reader) error {
+	return perunio.Decode(r, &m.Params, &m.Tx)
+}
+
+func (m msgWatchUpdateAck) Encode(w io.Writer) error {
+	return perunio.Encode(w, m.ChannelID, m.Version, m.Reason)
+}
+
+func (m *msgWatchUpdateAck) Decode(r io.Reader) error {
+	return perunio.Decode(r, &m.ChannelID, &m.Version, &m.Reason)
+}
+
+// ID returns the id of the channel this update refers to.
+func (m *msgWatchUpdate) ID() channel.ID {
+	return m.Tx.ID
+}
+
+// ID returns the id of the channel this acknowledgement refers to.
+func (m *msgWatchUpdateAck) ID() channel.ID {
+	return m.ChannelID
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchtower
+
+import (
+	"testing"
+
+	"perun.network/go-perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+	"perun.network/go-perun/wire"
+)
+
+func TestWatchUpdateSerialization(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	for i := 0; i < 4; i++ {
+		m := &msgWatchUpdate{
+			Params: *test.NewRandomParams(rng),
+			Tx:     *test.NewRandomTransaction(rng, []bool{true, i%2 == 0}),
+		}
+		wire.TestMsg(t, m)
+	}
+}
+
+func TestWatchUpdateAckSerialization(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	for i := 0; i < 4; i++ {
+		m := &msgWatchUpdateAck{
+			ChannelID: test.NewRandomChannelID(rng),
+			Version:   rng.Uint64(),
+		}
+		if i%2 == 1 {
+			m.Reason = "rejected"
+		}
+		wire.TestMsg(t, m)
+	}
+}
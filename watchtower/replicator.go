@@ -0,0 +1,310 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchtower
+
+import (
+	"context"
+	stdsync "sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	"perun.network/go-perun/log"
+	"perun.network/go-perun/pkg/sync"
+	"perun.network/go-perun/wire"
+)
+
+type (
+	// Replicator is a persistence.PersistRestorer that forwards all calls to
+	// the wrapped PersistRestorer and additionally replicates all fully signed
+	// current states to a set of watchers.
+	//
+	// The replication is asynchronous, so that an unreachable watcher does not
+	// stall the channels. Every watcher has a queue that holds the latest
+	// unreplicated state of every channel. Failed replications are logged and
+	// retried with an exponential backoff until they succeed or the Replicator
+	// is closed. States that a watcher rejects are logged and dropped.
+	Replicator struct {
+		persistence.PersistRestorer
+
+		closer     sync.Closer
+		bus        wire.Bus
+		relay      *wire.Relay // relay receives the watchers' acknowledgements.
+		addr       wire.Address
+		timeout    time.Duration
+		retryDelay time.Duration
+		queues     []*updateQueue
+	}
+
+	// updateQueue holds the updates that are not yet replicated to a watcher.
+	updateQueue struct {
+		watcher wire.Address
+		wake    chan struct{} // wake signals new updates.
+
+		mu      stdsync.Mutex // mu protects pending and empty.
+		pending map[channel.ID]*msgWatchUpdate
+		empty   chan struct{} // empty is closed while pending is empty.
+	}
+)
+
+// NewReplicator creates a Replicator that wraps pr and replicates the states
+// to the watchers. It subscribes to the bus as addr, which must not be the
+// address of a client on the same bus. Every replication attempt waits for the
+// watcher's acknowledgement for at most the given timeout.
+func NewReplicator(pr persistence.PersistRestorer, bus wire.Bus, addr wire.Address, watchers []wire.Address, timeout time.Duration) (*Replicator, error) {
+	relay := wire.NewRelay()
+	if err := bus.SubscribeClient(relay, addr); err != nil {
+		relay.Close() // nolint:errcheck,gosec
+		return nil, errors.WithMessage(err, "subscribing to bus")
+	}
+	r := &Replicator{
+		PersistRestorer: pr,
+		bus:             bus,
+		relay:           relay,
+		addr:            addr,
+		timeout:         timeout,
+		retryDelay:      defaultRetryDelay,
+		queues:          make([]*updateQueue, len(watchers)),
+	}
+	for i, watcher := range watchers {
+		r.queues[i] = newUpdateQueue(watcher)
+		go r.replicateTo(r.queues[i])
+	}
+	return r, nil
+}
+
+// ChannelCreated persists the channel with the wrapped PersistRestorer and
+// queues its initial state for replication, so that the watchers start
+// watching the channel.
+func (r *Replicator) ChannelCreated(ctx context.Context, s channel.Source, peers []wire.Address, parent *channel.ID) error {
+	if err := r.PersistRestorer.ChannelCreated(ctx, s, peers, parent); err != nil {
+		return err
+	}
+	r.replicate(s)
+	return nil
+}
+
+// Enabled persists the channel with the wrapped PersistRestorer and queues the
+// new current state for replication.
+func (r *Replicator) Enabled(ctx context.Context, s channel.Source) error {
+	if err := r.PersistRestorer.Enabled(ctx, s); err != nil {
+		return err
+	}
+	r.replicate(s)
+	return nil
+}
+
+// Flush waits until all queued states are replicated or dropped. It returns an
+// error if ctx is done before.
+func (r *Replicator) Flush(ctx context.Context) error {
+	for _, q := range r.queues {
+		q.mu.Lock()
+		empty := q.empty
+		q.mu.Unlock()
+		select {
+		case <-empty:
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "replicating to watcher %v", q.watcher)
+		}
+	}
+	return nil
+}
+
+// Resync queues the current transaction of every channel of the wrapped
+// PersistRestorer for replication if it is fully signed. Queued states are
+// lost when the Replicator is closed or the process crashes, so Resync should
+// be called after creating a Replicator on a restored PersistRestorer.
+//
+// Only channels with at least one peer are found, see
+// persistence.Restorer.ActivePeers.
+func (r *Replicator) Resync(ctx context.Context) error {
+	peers, err := r.ActivePeers(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "restoring active peers")
+	}
+	for _, peer := range peers {
+		it, err := r.RestorePeer(peer)
+		if err != nil {
+			return errors.WithMessagef(err, "restoring channels of peer %v", peer)
+		}
+		for it.Next(ctx) {
+			r.replicate(it.Channel())
+		}
+		if err := it.Close(); err != nil {
+			return errors.WithMessagef(err, "restoring channels of peer %v", peer)
+		}
+	}
+	return errors.WithMessage(ctx.Err(), "restoring channels")
+}
+
+// Close stops the replication, closes the wrapped PersistRestorer and
+// unsubscribes from the bus. Queued states that are not yet replicated are
+// lost, see Resync.
+func (r *Replicator) Close() error {
+	if err := r.closer.Close(); err != nil {
+		return err
+	}
+	if err := r.relay.Close(); err != nil {
+		return errors.WithMessage(err, "closing relay")
+	}
+	return r.PersistRestorer.Close()
+}
+
+// replicate queues the current transaction of the channel for all watchers if
+// it is fully signed.
+func (r *Replicator) replicate(s channel.Source) {
+	tx := s.CurrentTX()
+	if !tx.IsFullySigned() {
+		return
+	}
+	msg := &msgWatchUpdate{Params: *s.Params(), Tx: tx.Clone()}
+	for _, q := range r.queues {
+		q.push(msg)
+	}
+}
+
+// replicateTo replicates the queued updates to the queue's watcher until the
+// Replicator is closed.
+func (r *Replicator) replicateTo(q *updateQueue) {
+	log := log.WithField("watcher", q.watcher)
+	failures := 0
+	for {
+		msgs := q.peek()
+		if len(msgs) == 0 {
+			select {
+			case <-q.wake:
+				continue
+			case <-r.closer.Closed():
+				return
+			}
+		}
+
+		failed := false
+		for _, msg := range msgs {
+			ack, err := r.send(q.watcher, msg)
+			if r.closer.IsClosed() {
+				return
+			}
+			log := log.WithField("channel", msg.Tx.ID)
+			switch {
+			case err != nil:
+				log.WithError(err).Warnf("Replicator: replicating version %d", msg.Tx.Version)
+				failed = true
+				continue
+			case ack.Reason != "":
+				log.Errorf("Replicator: version %d rejected: %s", msg.Tx.Version, ack.Reason)
+			default:
+				log.Debugf("Replicator: replicated version %d", msg.Tx.Version)
+			}
+			q.remove(msg)
+		}
+		if !failed {
+			failures = 0
+			continue
+		}
+
+		select {
+		case <-time.After(backoff(r.retryDelay, failures)):
+			failures++
+		case <-r.closer.Closed():
+			return
+		}
+	}
+}
+
+// send sends the update to a watcher and waits for its acknowledgement.
+func (r *Replicator) send(watcher wire.Address, msg *msgWatchUpdate) (*msgWatchUpdateAck, error) {
+	ctx, cancel := context.WithTimeout(r.closer.Ctx(), r.timeout)
+	defer cancel()
+
+	recv := wire.NewReceiver()
+	defer recv.Close() // nolint:errcheck
+	isAck := func(e *wire.Envelope) bool {
+		ack, ok := e.Msg.(*msgWatchUpdateAck)
+		return ok && e.Sender.Equals(watcher) &&
+			ack.ChannelID == msg.Tx.ID && ack.Version == msg.Tx.Version
+	}
+	if err := r.relay.Subscribe(recv, isAck); err != nil {
+		return nil, errors.WithMessage(err, "subscribing receiver")
+	}
+
+	if err := r.bus.Publish(ctx, &wire.Envelope{
+		Sender:    r.addr,
+		Recipient: watcher,
+		Msg:       msg,
+	}); err != nil {
+		return nil, errors.WithMessage(err, "publishing update")
+	}
+	e, err := recv.Next(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "receiving acknowledgement")
+	}
+	return e.Msg.(*msgWatchUpdateAck), nil
+}
+
+func newUpdateQueue(watcher wire.Address) *updateQueue {
+	empty := make(chan struct{})
+	close(empty)
+	return &updateQueue{
+		watcher: watcher,
+		wake:    make(chan struct{}, 1),
+		pending: make(map[channel.ID]*msgWatchUpdate),
+		empty:   empty,
+	}
+}
+
+// push queues the update unless a newer update of the channel is queued.
+func (q *updateQueue) push(msg *msgWatchUpdate) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if p, ok := q.pending[msg.Tx.ID]; ok && p.Tx.Version >= msg.Tx.Version {
+		return
+	}
+	if len(q.pending) == 0 {
+		q.empty = make(chan struct{})
+	}
+	q.pending[msg.Tx.ID] = msg
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// peek returns all queued updates without removing them.
+func (q *updateQueue) peek() []*msgWatchUpdate {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msgs := make([]*msgWatchUpdate, 0, len(q.pending))
+	for _, msg := range q.pending {
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// remove removes the update from the queue unless it was superseded by a newer
+// update in the meantime.
+func (q *updateQueue) remove(msg *msgWatchUpdate) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending[msg.Tx.ID] != msg {
+		return
+	}
+	delete(q.pending, msg.Tx.ID)
+	if len(q.pending) == 0 {
+		close(q.empty)
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchtower
+
+import (
+	"bytes"
+	"context"
+	stdsync "sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/log"
+	perunio "perun.network/go-perun/pkg/io"
+	"perun.network/go-perun/pkg/sortedkv"
+	"perun.network/go-perun/pkg/sync"
+	"perun.network/go-perun/wire"
+)
+
+// ackTimeout is the timeout for sending an acknowledgement.
+const ackTimeout = 10 * time.Second
+
+// channelPrefix is the prefix of the watched channels in the database.
+const channelPrefix = "Chan:"
+
+type (
+	// Watcher is a watchtower service. It receives fully signed states from
+	// Replicators, stores the latest state of every channel and watches the
+	// channels on the Adjudicator. If a state older than the latest state is
+	// registered, the Watcher registers the latest state to refute it. A channel
+	// is no longer watched after it is concluded. Failed subscriptions to the
+	// Adjudicator are retried with an exponential backoff.
+	Watcher struct {
+		sync.Closer
+
+		adj         channel.Adjudicator
+		bus         wire.Bus
+		addr        wire.Address
+		replicators []wire.Address // replicators are the accepted senders.
+		db          sortedkv.Database
+		recv        *wire.Receiver
+
+		resubscribeDelay time.Duration // initial delay before resubscribing.
+
+		mu    stdsync.Mutex // mu protects chans.
+		chans map[channel.ID]*watchedChannel
+	}
+
+	// watchedChannel is a channel that is watched by a Watcher.
+	watchedChannel struct {
+		params *channel.Params
+		tx     channel.Transaction
+		sub    channel.AdjudicatorSubscription
+	}
+)
+
+// NewWatcher creates a Watcher that receives states on the bus as addr,
+// stores them in db and refutes outdated registrations on adj. Transactions
+// are sent by the adjudicator's own account, so it must not be one of the
+// client's accounts. Only states sent by one of the given replicator
+// addresses are accepted, states of other senders are rejected.
+//
+// The Watcher resumes watching all channels that are stored in db. Call Serve
+// to start receiving states.
+func NewWatcher(adj channel.Adjudicator, bus wire.Bus, addr wire.Address, db sortedkv.Database, replicators []wire.Address) (*Watcher, error) {
+	w := &Watcher{
+		adj:         adj,
+		bus:         bus,
+		addr:        addr,
+		replicators: replicators,
+		db:          db,
+		recv:        wire.NewReceiver(),
+		chans:       make(map[channel.ID]*watchedChannel),
+
+		resubscribeDelay: defaultRetryDelay,
+	}
+	if err := w.load(); err != nil {
+		return nil, err
+	}
+	if err := bus.SubscribeClient(w.recv, addr); err != nil {
+		w.recv.Close() // nolint:errcheck,gosec
+		return nil, errors.WithMessage(err, "subscribing to bus")
+	}
+	for id, ch := range w.chans {
+		go w.watch(id, ch.params)
+	}
+	return w, nil
+}
+
+// load loads all stored channels from the database.
+func (w *Watcher) load() error {
+	it := sortedkv.NewTable(w.db, channelPrefix).NewIterator()
+	for it.Next() {
+		ch := watchedChannel{params: new(channel.Params)}
+		if err := perunio.Decode(bytes.NewReader(it.ValueBytes()), ch.params, &ch.tx); err != nil {
+			it.Close() // nolint:errcheck
+			return errors.WithMessagef(err, "decoding channel %x", it.Key())
+		}
+		w.chans[ch.tx.ID] = &ch
+	}
+	return errors.WithMessage(it.Close(), "loading channels")
+}
+
+// Serve receives states until the Watcher is closed. It always returns nil.
+func (w *Watcher) Serve() error {
+	for {
+		e, err := w.recv.Next(w.Ctx())
+		if err != nil {
+			return nil
+		}
+		msg, ok := e.Msg.(*msgWatchUpdate)
+		if !ok {
+			log.WithField("sender", e.Sender).Warnf("Watcher: ignoring %v message", e.Msg.Type())
+			continue
+		}
+
+		ack := &msgWatchUpdateAck{ChannelID: msg.Tx.ID, Version: msg.Tx.Version}
+		if !w.isReplicator(e.Sender) {
+			log.WithField("sender", e.Sender).Warn("Watcher: rejecting update of unknown replicator")
+			ack.Reason = "unknown replicator"
+		} else if err := w.update(msg); err != nil {
+			log.WithField("sender", e.Sender).WithError(err).Warn("Watcher: rejecting update")
+			ack.Reason = err.Error()
+		}
+		ctx, cancel := context.WithTimeout(w.Ctx(), ackTimeout)
+		if err := w.bus.Publish(ctx, &wire.Envelope{Sender: w.addr, Recipient: e.Sender, Msg: ack}); err != nil {
+			log.WithField("recipient", e.Sender).WithError(err).Warn("Watcher: sending acknowledgement")
+		}
+		cancel()
+	}
+}
+
+// isReplicator returns whether addr is one of the accepted replicators.
+func (w *Watcher) isReplicator(addr wire.Address) bool {
+	for _, r := range w.replicators {
+		if r.Equals(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Latest returns the latest transaction of the channel and whether the channel
+// is watched.
+func (w *Watcher) Latest(id channel.ID) (channel.Transaction, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ch, ok := w.chans[id]
+	if !ok {
+		return channel.Transaction{}, false
+	}
+	return ch.tx.Clone(), true
+}
+
+// Close stops the Watcher and all its subscriptions. It does not close the
+// database.
+func (w *Watcher) Close() error {
+	if err := w.Closer.Close(); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	for _, ch := range w.chans {
+		if ch.sub != nil {
+			ch.sub.Close() // nolint:errcheck,gosec
+		}
+	}
+	w.mu.Unlock()
+	return w.recv.Close()
+}
+
+// update validates the update and stores it if it is newer than the latest
+// state of the channel. An unknown channel is watched from then on.
+func (w *Watcher) update(msg *msgWatchUpdate) error {
+	params, tx := &msg.Params, msg.Tx
+	if tx.State == nil {
+		return errors.New("missing state")
+	}
+	if params.ID() != tx.ID || channel.CalcID(params) != tx.ID {
+		return errors.New("state does not belong to params")
+	}
+	if len(tx.Sigs) != len(params.Parts) {
+		return errors.Errorf("expected %d signatures, got %d", len(params.Parts), len(tx.Sigs))
+	}
+	for i, sig := range tx.Sigs {
+		if ok, err := channel.Verify(params.Parts[i], params, tx.State, sig); err != nil {
+			return errors.WithMessagef(err, "verifying signature %d", i)
+		} else if !ok {
+			return errors.Errorf("invalid signature %d", i)
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ch, ok := w.chans[tx.ID]
+	if ok && ch.tx.Version >= tx.Version {
+		return nil // We already have this or a newer state.
+	}
+
+	var buf bytes.Buffer
+	if err := perunio.Encode(&buf, params, tx); err != nil {
+		return errors.WithMessage(err, "encoding channel")
+	}
+	if err := sortedkv.NewTable(w.db, channelPrefix).PutBytes(string(tx.ID[:]), buf.Bytes()); err != nil {
+		return errors.WithMessage(err, "storing channel")
+	}
+
+	if ok {
+		ch.tx = tx
+		return nil
+	}
+	w.chans[tx.ID] = &watchedChannel{params: params, tx: tx}
+	if !w.IsClosed() {
+		go w.watch(tx.ID, params)
+	}
+	return nil
+}
+
+// watch watches the channel on the adjudicator until it is concluded or the
+// Watcher is closed. If subscribing fails or the subscription ends with an
+// error, it resubscribes with an exponential backoff.
+func (w *Watcher) watch(id channel.ID, params *channel.Params) {
+	log := log.WithField("channel", id)
+	failures := 0
+	for {
+		sub, err := w.adj.Subscribe(w.Ctx(), params)
+		if err == nil {
+			failures = 0
+			var concluded bool
+			if concluded, err = w.handleEvents(id, sub); concluded {
+				return
+			}
+		}
+		if w.IsClosed() {
+			return
+		}
+		delay := backoff(w.resubscribeDelay, failures)
+		log.WithError(err).Errorf("Watcher: subscription failed, resubscribing in %v", delay)
+		select {
+		case <-time.After(delay):
+			failures++
+		case <-w.Closed():
+			return
+		}
+	}
+}
+
+// handleEvents handles the events of the subscription until the channel is
+// concluded or the subscription ends. It returns whether the channel was
+// concluded and the subscription's error.
+func (w *Watcher) handleEvents(id channel.ID, sub channel.AdjudicatorSubscription) (bool, error) {
+	defer sub.Close() // nolint:errcheck
+	w.mu.Lock()
+	if w.IsClosed() {
+		w.mu.Unlock()
+		return false, nil
+	}
+	w.chans[id].sub = sub
+	w.mu.Unlock()
+
+	log := log.WithField("channel", id)
+	for e := sub.Next(); e != nil; e = sub.Next() {
+		log.Debugf("Watcher: event %v", e)
+		switch e := e.(type) {
+		case *channel.RegisteredEvent:
+			if err := w.refute(id, e.Version()); err != nil {
+				log.WithError(err).Error("Watcher: refuting")
+			}
+		case *channel.ConcludedEvent:
+			log.Info("Watcher: channel concluded")
+			w.remove(id)
+			return true, nil
+		}
+	}
+	if err := sub.Err(); err != nil {
+		return false, err
+	}
+	return false, errors.New("subscription closed")
+}
+
+// refute registers the latest state of the channel if the registered version
+// is older.
+func (w *Watcher) refute(id channel.ID, registered uint64) error {
+	w.mu.Lock()
+	ch := w.chans[id]
+	params, tx := ch.params, ch.tx
+	w.mu.Unlock()
+
+	if registered >= tx.Version {
+		return nil
+	}
+	log.WithField("channel", id).Infof("Watcher: refuting version %d with version %d", registered, tx.Version)
+	return w.adj.Register(w.Ctx(), channel.AdjudicatorReq{Params: params, Tx: tx})
+}
+
+// remove stops watching the channel and deletes it from the database.
+func (w *Watcher) remove(id channel.ID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.chans, id)
+	if err := sortedkv.NewTable(w.db, channelPrefix).Delete(string(id[:])); err != nil {
+		log.WithField("channel", id).WithError(err).Error("Watcher: deleting channel")
+	}
+}
@@ -0,0 +1,396 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchtower
+
+import (
+	"context"
+	"errors"
+	stdsync "sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ethchannel "perun.network/go-perun/backend/ethereum/channel"
+	ethchanneltest "perun.network/go-perun/backend/ethereum/channel/test"
+	"perun.network/go-perun/backend/ethereum/wallet/keystore"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence"
+	"perun.network/go-perun/channel/persistence/keyvalue"
+	channeltest "perun.network/go-perun/channel/test"
+	"perun.network/go-perun/pkg/sortedkv/memorydb"
+	pkgtest "perun.network/go-perun/pkg/test"
+	"perun.network/go-perun/wallet"
+	wtest "perun.network/go-perun/wallet/test"
+	"perun.network/go-perun/wire"
+)
+
+const testTimeout = 20 * time.Second
+
+func TestWatcher_Refute(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	s := ethchanneltest.NewSetup(t, rng, 2)
+	params, state := channeltest.NewRandomParamsAndState(rng,
+		channeltest.WithChallengeDuration(uint64(100*time.Second)),
+		channeltest.WithParts(s.Parts...),
+		channeltest.WithAssets((*ethchannel.Asset)(&s.Asset)),
+		channeltest.WithIsFinal(false))
+
+	bus := wire.NewLocalBus()
+	watcherAddr, replicatorAddr := wtest.NewRandomAddress(rng), wtest.NewRandomAddress(rng)
+	replicators := []wire.Address{replicatorAddr}
+	db := memorydb.NewDatabase()
+	w, err := NewWatcher(s.Adjs[0], bus, watcherAddr, db, replicators)
+	require.NoError(t, err)
+	go w.Serve() // nolint:errcheck
+
+	r, err := NewReplicator(persistence.NonPersistRestorer, bus, replicatorAddr,
+		[]wire.Address{watcherAddr}, testTimeout)
+	require.NoError(t, err)
+	defer r.Close() // nolint:errcheck
+
+	// Replicate version 0 and 1 of the channel.
+	ch := persistence.NewChannel()
+	ch.ParamsV = params
+	ch.CurrentTXV = signState(t, s.Accs, params, state)
+	old := ch.CurrentTXV
+	require.NoError(t, r.ChannelCreated(ctx, ch, nil, nil))
+	state = state.Clone()
+	state.Version++
+	ch.CurrentTXV = signState(t, s.Accs, params, state)
+	require.NoError(t, r.Enabled(ctx, ch))
+	require.NoError(t, r.Flush(ctx))
+	latest, ok := w.Latest(params.ID())
+	require.True(t, ok)
+	assert.Equal(t, state.Version, latest.Version)
+
+	// Restart the watcher, it must resume watching the stored channel.
+	require.NoError(t, w.Close())
+	w, err = NewWatcher(s.Adjs[0], bus, watcherAddr, db, replicators)
+	require.NoError(t, err)
+	defer w.Close() // nolint:errcheck
+	go w.Serve()    // nolint:errcheck
+	latest, ok = w.Latest(params.ID())
+	require.True(t, ok)
+	assert.Equal(t, state.Version, latest.Version)
+
+	// The peer registers the old state, which the watcher must refute.
+	sub, err := s.Adjs[1].Subscribe(ctx, params)
+	require.NoError(t, err)
+	defer sub.Close()
+	require.NoError(t, s.Adjs[1].Register(ctx, channel.AdjudicatorReq{
+		Params: params,
+		Acc:    s.Accs[1],
+		Idx:    1,
+		Tx:     old,
+	}))
+	for e := sub.Next(); ; e = sub.Next() {
+		if e == nil {
+			t.Fatalf("subscription closed: %v", sub.Err())
+		}
+		if _, ok := e.(*channel.RegisteredEvent); ok && e.Version() == state.Version {
+			break
+		}
+	}
+}
+
+func TestReplicator_Rejected(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	s := ethchanneltest.NewSetup(t, rng, 2)
+	params, state := channeltest.NewRandomParamsAndState(rng,
+		channeltest.WithParts(s.Parts...),
+		channeltest.WithAssets((*ethchannel.Asset)(&s.Asset)))
+
+	bus := wire.NewLocalBus()
+	watcherAddr, replicatorAddr := wtest.NewRandomAddress(rng), wtest.NewRandomAddress(rng)
+	w, err := NewWatcher(s.Adjs[0], bus, watcherAddr, memorydb.NewDatabase(), []wire.Address{replicatorAddr})
+	require.NoError(t, err)
+	defer w.Close() // nolint:errcheck
+	go w.Serve()    // nolint:errcheck
+
+	r, err := NewReplicator(persistence.NonPersistRestorer, bus, replicatorAddr,
+		[]wire.Address{watcherAddr}, testTimeout)
+	require.NoError(t, err)
+	defer r.Close() // nolint:errcheck
+
+	ch := persistence.NewChannel()
+	ch.ParamsV = params
+	ch.CurrentTXV = signState(t, s.Accs, params, state)
+	ch.CurrentTXV.Sigs[0], ch.CurrentTXV.Sigs[1] = ch.CurrentTXV.Sigs[1], ch.CurrentTXV.Sigs[0]
+	require.NoError(t, r.ChannelCreated(ctx, ch, nil, nil))
+	require.NoError(t, r.Flush(ctx), "rejected states should be dropped")
+	_, ok := w.Latest(params.ID())
+	assert.False(t, ok, "invalid signatures")
+
+	// Partially signed states are not replicated.
+	ch.CurrentTXV.Sigs[0] = nil
+	assert.NoError(t, r.Enabled(ctx, ch))
+	require.NoError(t, r.Flush(ctx))
+	_, ok = w.Latest(params.ID())
+	assert.False(t, ok)
+}
+
+func TestReplicator_Retry(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	s := ethchanneltest.NewSetup(t, rng, 2)
+	params, state := channeltest.NewRandomParamsAndState(rng,
+		channeltest.WithParts(s.Parts...),
+		channeltest.WithAssets((*ethchannel.Asset)(&s.Asset)))
+
+	bus := wire.NewLocalBus()
+	watcherAddr, replicatorAddr := wtest.NewRandomAddress(rng), wtest.NewRandomAddress(rng)
+	r, err := NewReplicator(persistence.NonPersistRestorer, bus, replicatorAddr,
+		[]wire.Address{watcherAddr}, 50*time.Millisecond)
+	require.NoError(t, err)
+	defer r.Close() // nolint:errcheck
+	r.retryDelay = 10 * time.Millisecond
+
+	// The watcher is offline, which must not fail the persister calls.
+	ch := persistence.NewChannel()
+	ch.ParamsV = params
+	ch.CurrentTXV = signState(t, s.Accs, params, state)
+	require.NoError(t, r.ChannelCreated(ctx, ch, nil, nil))
+	state = state.Clone()
+	state.Version++
+	ch.CurrentTXV = signState(t, s.Accs, params, state)
+	require.NoError(t, r.Enabled(ctx, ch))
+	flushCtx, flushCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer flushCancel()
+	assert.Error(t, r.Flush(flushCtx), "watcher offline")
+
+	// The replicator retries until the watcher is online.
+	w, err := NewWatcher(s.Adjs[0], bus, watcherAddr, memorydb.NewDatabase(), []wire.Address{replicatorAddr})
+	require.NoError(t, err)
+	defer w.Close() // nolint:errcheck
+	go w.Serve()    // nolint:errcheck
+	require.NoError(t, r.Flush(ctx))
+	latest, ok := w.Latest(params.ID())
+	require.True(t, ok)
+	assert.Equal(t, state.Version, latest.Version, "only the latest state should be replicated")
+}
+
+func TestWatcher_UnknownReplicator(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	s := ethchanneltest.NewSetup(t, rng, 2)
+	params, state := channeltest.NewRandomParamsAndState(rng,
+		channeltest.WithParts(s.Parts...),
+		channeltest.WithAssets((*ethchannel.Asset)(&s.Asset)))
+
+	bus := wire.NewLocalBus()
+	watcherAddr := wtest.NewRandomAddress(rng)
+	w, err := NewWatcher(s.Adjs[0], bus, watcherAddr, memorydb.NewDatabase(),
+		[]wire.Address{wtest.NewRandomAddress(rng)})
+	require.NoError(t, err)
+	defer w.Close() // nolint:errcheck
+	go w.Serve()    // nolint:errcheck
+
+	r, err := NewReplicator(persistence.NonPersistRestorer, bus, wtest.NewRandomAddress(rng),
+		[]wire.Address{watcherAddr}, testTimeout)
+	require.NoError(t, err)
+	defer r.Close() // nolint:errcheck
+
+	ch := persistence.NewChannel()
+	ch.ParamsV = params
+	ch.CurrentTXV = signState(t, s.Accs, params, state)
+	require.NoError(t, r.ChannelCreated(ctx, ch, nil, nil))
+	require.NoError(t, r.Flush(ctx), "rejected states should be dropped")
+	_, ok := w.Latest(params.ID())
+	assert.False(t, ok, "state of unknown replicator accepted")
+}
+
+func TestReplicator_Resync(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	s := ethchanneltest.NewSetup(t, rng, 2)
+	params, state := channeltest.NewRandomParamsAndState(rng,
+		channeltest.WithParts(s.Parts...),
+		channeltest.WithAssets((*ethchannel.Asset)(&s.Asset)),
+		channeltest.WithoutApp())
+
+	// The channel was persisted, but its state was not replicated before the
+	// client stopped.
+	pr, err := keyvalue.OpenPersistRestorer(memorydb.NewDatabase())
+	require.NoError(t, err)
+	ch := persistence.NewChannel()
+	ch.ParamsV = params
+	ch.CurrentTXV = signState(t, s.Accs, params, state)
+	require.NoError(t, pr.ChannelCreated(ctx, ch, []wire.Address{wtest.NewRandomAddress(rng)}, nil))
+
+	bus := wire.NewLocalBus()
+	watcherAddr, replicatorAddr := wtest.NewRandomAddress(rng), wtest.NewRandomAddress(rng)
+	w, err := NewWatcher(s.Adjs[0], bus, watcherAddr, memorydb.NewDatabase(), []wire.Address{replicatorAddr})
+	require.NoError(t, err)
+	defer w.Close() // nolint:errcheck
+	go w.Serve()    // nolint:errcheck
+
+	r, err := NewReplicator(pr, bus, replicatorAddr, []wire.Address{watcherAddr}, testTimeout)
+	require.NoError(t, err)
+	defer r.Close() // nolint:errcheck
+	require.NoError(t, r.Resync(ctx))
+	require.NoError(t, r.Flush(ctx))
+	latest, ok := w.Latest(params.ID())
+	require.True(t, ok, "restored channel not replicated")
+	assert.Equal(t, state.Version, latest.Version)
+}
+
+func TestWatcher_Resubscribe(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	s := ethchanneltest.NewSetup(t, rng, 2)
+	params, state := channeltest.NewRandomParamsAndState(rng,
+		channeltest.WithParts(s.Parts...),
+		channeltest.WithAssets((*ethchannel.Asset)(&s.Asset)),
+		channeltest.WithIsFinal(false))
+	state.Version = 1
+
+	adj := newFlakyAdjudicator()
+	w, err := NewWatcher(adj, wire.NewLocalBus(), wtest.NewRandomAddress(rng), memorydb.NewDatabase(), nil)
+	require.NoError(t, err)
+	defer w.Close() // nolint:errcheck
+	w.resubscribeDelay = time.Millisecond
+
+	// The first subscription fails, the second one ends with an error.
+	require.NoError(t, w.update(&msgWatchUpdate{Params: *params, Tx: signState(t, s.Accs, params, state)}))
+	select {
+	case adj.fail <- errors.New("connection lost"):
+	case <-ctx.Done():
+		t.Fatal("not resubscribed after failed subscription")
+	}
+
+	// The third subscription must still refute and observe the conclusion.
+	timeout := &channel.ElapsedTimeout{}
+	select {
+	case adj.events <- channel.NewRegisteredEvent(params.ID(), timeout, 0):
+	case <-ctx.Done():
+		t.Fatal("not resubscribed after subscription error")
+	}
+	select {
+	case req := <-adj.registered:
+		assert.Equal(t, state.Version, req.Tx.Version)
+	case <-ctx.Done():
+		t.Fatal("old state not refuted")
+	}
+	select {
+	case adj.events <- &channel.ConcludedEvent{AdjudicatorEventBase: *channel.NewAdjudicatorEventBase(params.ID(), timeout, 1)}:
+	case <-ctx.Done():
+		t.Fatal("conclusion not received")
+	}
+	for {
+		if _, ok := w.Latest(params.ID()); !ok {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("concluded channel still watched")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	assert.Equal(t, 3, adj.subscribes())
+}
+
+// flakyAdjudicator is an Adjudicator whose first subscription fails. Its
+// subscriptions emit the events sent on events and end with the errors sent
+// on fail. Registrations are sent on registered.
+type flakyAdjudicator struct {
+	channel.Adjudicator
+
+	events     chan channel.AdjudicatorEvent
+	fail       chan error
+	registered chan channel.AdjudicatorReq
+
+	mu   stdsync.Mutex
+	subs int
+}
+
+func newFlakyAdjudicator() *flakyAdjudicator {
+	return &flakyAdjudicator{
+		events:     make(chan channel.AdjudicatorEvent),
+		fail:       make(chan error),
+		registered: make(chan channel.AdjudicatorReq, 1),
+	}
+}
+
+func (a *flakyAdjudicator) Subscribe(context.Context, *channel.Params) (channel.AdjudicatorSubscription, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.subs++
+	if a.subs == 1 {
+		return nil, errors.New("node unreachable")
+	}
+	return &flakySub{adj: a, closed: make(chan struct{})}, nil
+}
+
+func (a *flakyAdjudicator) Register(_ context.Context, req channel.AdjudicatorReq) error {
+	a.registered <- req
+	return nil
+}
+
+func (a *flakyAdjudicator) subscribes() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.subs
+}
+
+type flakySub struct {
+	adj       *flakyAdjudicator
+	err       error
+	closed    chan struct{}
+	closeOnce stdsync.Once
+}
+
+func (s *flakySub) Next() channel.AdjudicatorEvent {
+	select {
+	case e := <-s.adj.events:
+		return e
+	case s.err = <-s.adj.fail:
+		return nil
+	case <-s.closed:
+		return nil
+	}
+}
+
+func (s *flakySub) Err() error { return s.err }
+
+func (s *flakySub) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	return nil
+}
+
+func signState(t *testing.T, accs []*keystore.Account, params *channel.Params, state *channel.State) channel.Transaction {
+	tx := channel.Transaction{State: state, Sigs: make([]wallet.Sig, len(accs))}
+	for i, acc := range accs {
+		sig, err := channel.Sign(acc, params, state)
+		require.NoError(t, err)
+		tx.Sigs[i] = sig
+	}
+	return tx
+}
@@ -130,6 +130,11 @@ const (
 	ChannelUpdateAcc
 	ChannelUpdateRej
 	ChannelSync
+	PaymentRequest
+	PaymentRequestRej
+	InvoicePayment
+	WatchUpdate
+	WatchUpdateAck
 	LastType // upper bound on the message types of the Perun wire protocol
 )
 
@@ -147,6 +152,11 @@ var typeNames = map[Type]string{
 	ChannelUpdateAcc:         "ChannelUpdateAcc",
 	ChannelUpdateRej:         "ChannelUpdateRej",
 	ChannelSync:              "ChannelSync",
+	PaymentRequest:           "PaymentRequest",
+	PaymentRequestRej:        "PaymentRequestRej",
+	InvoicePayment:           "InvoicePayment",
+	WatchUpdate:              "WatchUpdate",
+	WatchUpdateAck:           "WatchUpdateAck",
 }
 
 // String returns the name of a message type if it is valid and name known
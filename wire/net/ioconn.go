@@ -25,17 +25,33 @@ import (
 
 var _ Conn = (*ioConn)(nil)
 
+// A ReaderScope wraps the reader from which a connection decodes incoming
+// messages. It allows to decode messages in a scope other than the global one,
+// e.g., to resolve the apps in incoming messages with a specific
+// channel.AppRegistry using its Scope method.
+type ReaderScope func(io.Reader) io.Reader
+
 // ioConn is a connection that communicates its messages over an io stream.
 type ioConn struct {
 	closed atomic.Bool
 	conn   io.ReadWriteCloser
+	r      io.Reader // conn, wrapped by the connection's ReaderScope.
 }
 
 // NewIoConn creates a peer message connection from an io stream.
 func NewIoConn(conn io.ReadWriteCloser) Conn {
-	return &ioConn{
-		conn: conn,
+	return NewScopedIoConn(conn, nil)
+}
+
+// NewScopedIoConn creates a peer message connection from an io stream that
+// decodes incoming messages from the reader returned by scope. If scope is
+// nil, messages are decoded from the stream directly.
+func NewScopedIoConn(conn io.ReadWriteCloser, scope ReaderScope) Conn {
+	c := &ioConn{conn: conn, r: conn}
+	if scope != nil {
+		c.r = scope(conn)
 	}
+	return c
 }
 
 func (c *ioConn) Send(e *wire.Envelope) error {
@@ -49,7 +65,7 @@ func (c *ioConn) Send(e *wire.Envelope) error {
 
 func (c *ioConn) Recv() (*wire.Envelope, error) {
 	var e wire.Envelope
-	if err := e.Decode(c.conn); err != nil {
+	if err := e.Decode(c.r); err != nil {
 		// nolint:errcheck,gosec
 		c.conn.Close()
 		return nil, err
@@ -34,6 +34,7 @@ type Dialer struct {
 	peers   map[wallet.AddrKey]string // Known peer addresses.
 	dialer  net.Dialer                // Used to dial connections.
 	network string                    // The socket type.
+	opts    connOpts                  // Options for dialed connections.
 
 	pkgsync.Closer
 }
@@ -44,22 +45,23 @@ var _ wirenet.Dialer = (*Dialer)(nil)
 // attempts. Leaving the timeout as 0 will result in no timeouts. Standard OS
 // timeouts may still apply even when no timeout is selected. The network string
 // controls the type of connection that the dialer can dial.
-func NewNetDialer(network string, defaultTimeout time.Duration) *Dialer {
+func NewNetDialer(network string, defaultTimeout time.Duration, opts ...Option) *Dialer {
 	return &Dialer{
 		peers:   make(map[wallet.AddrKey]string),
 		dialer:  net.Dialer{Timeout: defaultTimeout},
 		network: network,
+		opts:    makeConnOpts(opts),
 	}
 }
 
 // NewTCPDialer is a short-hand version of NewNetDialer for creating TCP dialers.
-func NewTCPDialer(defaultTimeout time.Duration) *Dialer {
-	return NewNetDialer("tcp", defaultTimeout)
+func NewTCPDialer(defaultTimeout time.Duration, opts ...Option) *Dialer {
+	return NewNetDialer("tcp", defaultTimeout, opts...)
 }
 
 // NewUnixDialer is a short-hand version of NewNetDialer for creating Unix dialers.
-func NewUnixDialer(defaultTimeout time.Duration) *Dialer {
-	return NewNetDialer("unix", defaultTimeout)
+func NewUnixDialer(defaultTimeout time.Duration, opts ...Option) *Dialer {
+	return NewNetDialer("unix", defaultTimeout, opts...)
 }
 
 func (d *Dialer) get(key wallet.AddrKey) (string, bool) {
@@ -97,7 +99,7 @@ func (d *Dialer) Dial(ctx context.Context, addr wire.Address) (wirenet.Conn, err
 		return nil, errors.Wrap(err, "failed to dial peer")
 	}
 
-	return wirenet.NewIoConn(conn), nil
+	return wirenet.NewScopedIoConn(conn, d.opts.scope), nil
 }
 
 // Register registers a network address for a peer address.
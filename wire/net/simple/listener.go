@@ -24,29 +24,30 @@ import (
 // Listener is a TCP Listener.
 type Listener struct {
 	net.Listener
+	opts connOpts // Options for accepted connections.
 }
 
 var _ wirenet.Listener = (*Listener)(nil)
 
 // NewNetListener creates a listener reachable under the requested address.
-func NewNetListener(network string, address string) (*Listener, error) {
+func NewNetListener(network string, address string, opts ...Option) (*Listener, error) {
 	l, err := net.Listen(network, address)
 	if err != nil {
 		return nil, errors.Wrapf(err,
 			"failed to create listener for '%s'", address)
 	}
 
-	return &Listener{Listener: l}, nil
+	return &Listener{Listener: l, opts: makeConnOpts(opts)}, nil
 }
 
 // NewTCPListener is a short-hand version of NewNetListener for TCP listeners.
-func NewTCPListener(address string) (*Listener, error) {
-	return NewNetListener("tcp", address)
+func NewTCPListener(address string, opts ...Option) (*Listener, error) {
+	return NewNetListener("tcp", address, opts...)
 }
 
 // NewUnixListener is a short-hand version of NewNetListener for Unix listeners.
-func NewUnixListener(address string) (*Listener, error) {
-	return NewNetListener("unix", address)
+func NewUnixListener(address string, opts ...Option) (*Listener, error) {
+	return NewNetListener("unix", address, opts...)
 }
 
 // Accept implements peer.Dialer.Accept().
@@ -56,5 +57,5 @@ func (l *Listener) Accept() (wirenet.Conn, error) {
 		return nil, errors.Wrap(err, "accept failed")
 	}
 
-	return wirenet.NewIoConn(conn), nil
+	return wirenet.NewScopedIoConn(conn, l.opts.scope), nil
 }
@@ -0,0 +1,42 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import wirenet "perun.network/go-perun/wire/net"
+
+type (
+	// An Option configures a Dialer or Listener on creation.
+	Option func(*connOpts)
+
+	// connOpts are the options for the connections of a Dialer or Listener.
+	connOpts struct {
+		scope wirenet.ReaderScope
+	}
+)
+
+// WithReaderScope makes the connections of a Dialer or Listener decode
+// incoming messages in the given scope, see wirenet.ReaderScope. For example,
+// passing the Scope method of a channel.AppRegistry resolves the apps in
+// incoming messages with that registry instead of the global one.
+func WithReaderScope(scope wirenet.ReaderScope) Option {
+	return func(o *connOpts) { o.scope = scope }
+}
+
+func makeConnOpts(opts []Option) (o connOpts) {
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	simwallet "perun.network/go-perun/backend/sim/wallet"
+	"perun.network/go-perun/pkg/sync/atomic"
+	"perun.network/go-perun/pkg/test"
+	"perun.network/go-perun/wire"
+)
+
+// scopedReader marks the readers that were wrapped by a ReaderScope.
+type scopedReader struct {
+	io.Reader
+	used *atomic.Bool
+}
+
+func (r scopedReader) Read(p []byte) (int, error) {
+	r.used.Set()
+	return r.Reader.Read(p)
+}
+
+func TestWithReaderScope(t *testing.T) {
+	rng := test.Prng(t)
+	const host = "127.0.0.1:7358"
+	var listenerScoped, dialerScoped atomic.Bool
+	scope := func(used *atomic.Bool) Option {
+		return WithReaderScope(func(r io.Reader) io.Reader { return scopedReader{r, used} })
+	}
+
+	l, err := NewTCPListener(host, scope(&listenerScoped))
+	require.NoError(t, err)
+	defer l.Close()
+	d := NewTCPDialer(time.Second, scope(&dialerScoped))
+	defer d.Close()
+	laddr, daddr := simwallet.NewRandomAddress(rng), simwallet.NewRandomAddress(rng)
+	d.Register(laddr, host)
+
+	ping := &wire.Envelope{Sender: daddr, Recipient: laddr, Msg: wire.NewPingMsg()}
+	pong := &wire.Envelope{Sender: laddr, Recipient: daddr, Msg: wire.NewPongMsg()}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn, err := l.Accept()
+		if !assert.NoError(t, err) {
+			return
+		}
+		e, err := conn.Recv()
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, ping, e)
+		assert.NoError(t, conn.Send(pong))
+	}()
+
+	conn, err := d.Dial(context.Background(), laddr)
+	require.NoError(t, err)
+	require.NoError(t, conn.Send(ping))
+	e, err := conn.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, pong, e)
+	<-done
+
+	assert.True(t, listenerScoped.IsSet(), "listener connection should decode in scope")
+	assert.True(t, dialerScoped.IsSet(), "dialer connection should decode in scope")
+}